@@ -0,0 +1,26 @@
+package htm
+
+/*
+ Computes the raw anomaly score, the fraction of the currently active
+columns that were not predicted on the previous time step. Returns 0 when
+no columns are active. Both slices must hold unique column indices.
+*/
+func ComputeRawAnomalyScore(activeColumns []int, prevPredictedColumns []int) float64 {
+	if len(activeColumns) == 0 {
+		return 0
+	}
+
+	predicted := make(map[int]bool, len(prevPredictedColumns))
+	for _, col := range prevPredictedColumns {
+		predicted[col] = true
+	}
+
+	predictedActive := 0
+	for _, col := range activeColumns {
+		if predicted[col] {
+			predictedActive++
+		}
+	}
+
+	return float64(len(activeColumns)-predictedActive) / float64(len(activeColumns))
+}
@@ -0,0 +1,13 @@
+package htm
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestComputeRawAnomalyScore(t *testing.T) {
+	assert.Equal(t, 0.0, ComputeRawAnomalyScore(nil, []int{1, 2}))
+	assert.Equal(t, 1.0, ComputeRawAnomalyScore([]int{1, 2}, nil))
+	assert.Equal(t, 0.0, ComputeRawAnomalyScore([]int{3, 5, 7}, []int{7, 5, 3, 9}))
+	assert.Equal(t, 0.5, ComputeRawAnomalyScore([]int{2, 3, 6, 7}, []int{3, 7, 8}))
+}
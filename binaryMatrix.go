@@ -0,0 +1,140 @@
+package htm
+
+import (
+	"bytes"
+	"sort"
+)
+
+/*
+ BinaryMatrix is the method set shared by the binary matrix
+implementations, allowing callers to swap representations depending on
+their access pattern:
+
+SparseBinaryMatrix: unordered list of entries, cheap to build
+DenseBinaryMatrix: one bool per entry, constant time access
+PackedBinaryMatrix: one bit per entry, word at a time row operations
+CSRBinaryMatrix: sorted column indices per row, fast row queries
+CSCBinaryMatrix: sorted row indices per column, fast column queries and
+RowAndSum for sparse inputs
+*/
+type BinaryMatrix interface {
+	//Returns the number of rows (height)
+	Rows() int
+	//Returns the number of columns (width)
+	Cols() int
+	Get(row int, col int) bool
+	Set(row int, col int, value bool)
+	Entries() []SparseEntry
+	Flatten() []bool
+	ReplaceRow(row int, values []bool)
+	ReplaceRowByIndices(row int, indices []int)
+	GetDenseRow(row int) []bool
+	GetRowIndices(row int) []int
+	GetColIndices(col int) []int
+	SetRowFromDense(row int, denseRow []bool)
+	RowAndSum(row []bool) []int
+	NonZeroRows() []int
+	TotalTrueRows() int
+	TotalTrueCols() int
+	TotalNonZeroCount() int
+	Clear()
+	FillRow(row int, val bool)
+	ToString() string
+}
+
+//Inserts value into sorted slice if it is not already present
+func sortedInsert(values []int, value int) []int {
+	idx := sort.SearchInts(values, value)
+	if idx < len(values) && values[idx] == value {
+		return values
+	}
+	values = append(values, 0)
+	copy(values[idx+1:], values[idx:])
+	values[idx] = value
+	return values
+}
+
+//Removes value from sorted slice if present
+func sortedDelete(values []int, value int) []int {
+	idx := sort.SearchInts(values, value)
+	if idx < len(values) && values[idx] == value {
+		return append(values[:idx], values[idx+1:]...)
+	}
+	return values
+}
+
+//Returns true if sorted slice contains value
+func sortedContains(values []int, value int) bool {
+	idx := sort.SearchInts(values, value)
+	return idx < len(values) && values[idx] == value
+}
+
+//Renders any binary matrix as rows of 1's and 0's
+func binaryMatrixString(m BinaryMatrix) string {
+	var buffer bytes.Buffer
+
+	for r := 0; r < m.Rows(); r++ {
+		for _, val := range m.GetDenseRow(r) {
+			if val {
+				buffer.WriteByte('1')
+			} else {
+				buffer.WriteByte('0')
+			}
+		}
+		buffer.WriteByte('\n')
+	}
+
+	return buffer.String()
+}
+
+//Panics if matrices are not the same size
+func validateSameSize(a, b BinaryMatrix) {
+	if a.Rows() != b.Rows() || a.Cols() != b.Cols() {
+		panic("Matrix sizes do not match")
+	}
+}
+
+//Returns set of true entries for constant time lookups
+func entrySet(m BinaryMatrix) map[SparseEntry]bool {
+	entries := m.Entries()
+	result := make(map[SparseEntry]bool, len(entries))
+	for _, val := range entries {
+		result[val] = true
+	}
+	return result
+}
+
+//Returns the number of true entries in each row
+func RowSums(m BinaryMatrix) []int {
+	result := make([]int, m.Rows())
+	for _, val := range m.Entries() {
+		result[val.Row]++
+	}
+	return result
+}
+
+//Returns the number of true entries in each column
+func ColSums(m BinaryMatrix) []int {
+	result := make([]int, m.Cols())
+	for _, val := range m.Entries() {
+		result[val.Col]++
+	}
+	return result
+}
+
+//Returns true if both matrices are the same size and have the same
+//true entries, regardless of representation
+func BinaryMatrixEqual(a, b BinaryMatrix) bool {
+	if a.Rows() != b.Rows() || a.Cols() != b.Cols() {
+		return false
+	}
+	if a.TotalNonZeroCount() != b.TotalNonZeroCount() {
+		return false
+	}
+	for _, val := range a.Entries() {
+		if !b.Get(val.Row, val.Col) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,410 @@
+package htm
+
+import (
+	"github.com/nupic-community/htm/utils"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+type binaryMatrixCtor struct {
+	name string
+	make func(height, width int) BinaryMatrix
+}
+
+var binaryMatrixCtors = []binaryMatrixCtor{
+	{"sparse", func(h, w int) BinaryMatrix { return NewSparseBinaryMatrix(h, w) }},
+	{"dense", func(h, w int) BinaryMatrix { return NewDenseBinaryMatrix(h, w) }},
+	{"csr", func(h, w int) BinaryMatrix { return NewCSRBinaryMatrix(h, w) }},
+	{"csc", func(h, w int) BinaryMatrix { return NewCSCBinaryMatrix(h, w) }},
+	{"packed", func(h, w int) BinaryMatrix { return NewPackedBinaryMatrix(h, w) }},
+}
+
+func sortedEntries(entries []SparseEntry) []SparseEntry {
+	result := make([]SparseEntry, len(entries))
+	copy(result, entries)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Row != result[j].Row {
+			return result[i].Row < result[j].Row
+		}
+		return result[i].Col < result[j].Col
+	})
+	return result
+}
+
+//checks every query of m against a dense reference
+func assertMatchesReference(t *testing.T, name string, m BinaryMatrix, ref [][]bool) {
+	height, width := len(ref), len(ref[0])
+	assert.Equal(t, height, m.Rows(), name)
+	assert.Equal(t, width, m.Cols(), name)
+
+	var entries []SparseEntry
+	var nonZeroRows []int
+	flat := make([]bool, 0, height*width)
+	trueCols := make(map[int]bool)
+	for r := 0; r < height; r++ {
+		assert.Equal(t, ref[r], m.GetDenseRow(r), name)
+		rowIndices := m.GetRowIndices(r)
+		sort.Ints(rowIndices)
+		assert.Equal(t, utils.OnIndices(ref[r]), nilIfEmpty(rowIndices), name)
+		for c := 0; c < width; c++ {
+			assert.Equal(t, ref[r][c], m.Get(r, c), name)
+			if ref[r][c] {
+				entries = append(entries, SparseEntry{r, c})
+				trueCols[c] = true
+			}
+		}
+		if utils.AnyTrue(ref[r]) {
+			nonZeroRows = append(nonZeroRows, r)
+		}
+		flat = append(flat, ref[r]...)
+	}
+
+	for c := 0; c < width; c++ {
+		var expected []int
+		for r := 0; r < height; r++ {
+			if ref[r][c] {
+				expected = append(expected, r)
+			}
+		}
+		actual := m.GetColIndices(c)
+		sort.Ints(actual)
+		assert.Equal(t, expected, nilIfEmpty(actual), name)
+	}
+
+	assert.Equal(t, nilIfEmptyEntries(sortedEntries(entries)), nilIfEmptyEntries(sortedEntries(m.Entries())), name)
+	assert.Equal(t, flat, m.Flatten(), name)
+	rows := m.NonZeroRows()
+	sort.Ints(rows)
+	assert.Equal(t, nonZeroRows, nilIfEmpty(rows), name)
+	assert.Equal(t, len(nonZeroRows), m.TotalTrueRows(), name)
+	assert.Equal(t, len(trueCols), m.TotalTrueCols(), name)
+	assert.Equal(t, len(entries), m.TotalNonZeroCount(), name)
+
+	input := make([]bool, width)
+	for i := range input {
+		input[i] = rand.Intn(2) == 1
+	}
+	expectedSums := make([]int, height)
+	for r := 0; r < height; r++ {
+		for c := 0; c < width; c++ {
+			if ref[r][c] && input[c] {
+				expectedSums[r]++
+			}
+		}
+	}
+	assert.Equal(t, expectedSums, m.RowAndSum(input), name)
+}
+
+func nilIfEmpty(values []int) []int {
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+func nilIfEmptyEntries(values []SparseEntry) []SparseEntry {
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+func TestBinaryMatrixImplementations(t *testing.T) {
+	height, width := 12, 9
+
+	for _, ctor := range binaryMatrixCtors {
+		m := ctor.make(height, width)
+		ref := make([][]bool, height)
+		for r := range ref {
+			ref[r] = make([]bool, width)
+		}
+		assertMatchesReference(t, ctor.name, m, ref)
+
+		//random sets and clears
+		for i := 0; i < 300; i++ {
+			r, c, val := rand.Intn(height), rand.Intn(width), rand.Intn(3) > 0
+			m.Set(r, c, val)
+			ref[r][c] = val
+		}
+		assertMatchesReference(t, ctor.name, m, ref)
+
+		//row replacement
+		newRow := make([]bool, width)
+		newRow[1], newRow[7] = true, true
+		m.ReplaceRow(3, newRow)
+		copy(ref[3], newRow)
+		m.ReplaceRowByIndices(5, []int{8, 0, 4})
+		ref[5] = utils.Make1DBool([]int{1, 0, 0, 0, 1, 0, 0, 0, 1})
+		m.SetRowFromDense(6, make([]bool, width))
+		ref[6] = make([]bool, width)
+		assertMatchesReference(t, ctor.name, m, ref)
+
+		m.FillRow(2, true)
+		utils.FillSliceBool(ref[2], true)
+		m.FillRow(4, false)
+		utils.FillSliceBool(ref[4], false)
+		assertMatchesReference(t, ctor.name, m, ref)
+
+		assert.Equal(t, NewDenseBinaryMatrixFromDense(ref).ToString(), m.ToString(), ctor.name)
+
+		m.Clear()
+		for r := range ref {
+			ref[r] = make([]bool, width)
+		}
+		assertMatchesReference(t, ctor.name, m, ref)
+	}
+}
+
+func TestCSRBinaryMatrixConstructors(t *testing.T) {
+	ints := [][]int{{0, 1, 0, 1}, {0, 0, 0, 0}, {1, 0, 0, 1}}
+	ref := NewDenseBinaryMatrixFromInts(ints)
+
+	assertMatchesReference(t, "ints", NewCSRBinaryMatrixFromInts(ints), utils.Make2DBool(ints))
+	assertMatchesReference(t, "dense", NewCSRBinaryMatrixFromDense(utils.Make2DBool(ints)), utils.Make2DBool(ints))
+	assertMatchesReference(t, "dense1d", NewCSRBinaryMatrixFromDense1D(ref.Flatten(), 3, 4), utils.Make2DBool(ints))
+	assertMatchesReference(t, "matrix", NewCSRBinaryMatrixFromMatrix(ref), utils.Make2DBool(ints))
+
+	m := NewCSRBinaryMatrixFromInts(ints)
+	c := m.Copy()
+	c.Set(1, 1, true)
+	assert.False(t, m.Get(1, 1))
+
+	or := m.Or(NewCSRBinaryMatrixFromInts([][]int{{1, 0, 0, 0}, {0, 0, 0, 0}, {1, 0, 0, 0}}))
+	assert.Equal(t, []int{0, 1, 3}, or.GetRowIndices(0))
+	assert.Equal(t, []int{0, 3}, or.GetRowIndices(2))
+
+	assert.Panics(t, func() { m.Set(3, 0, true) })
+	assert.Panics(t, func() { m.Set(0, 4, true) })
+}
+
+func TestCSCBinaryMatrixConstructors(t *testing.T) {
+	ints := [][]int{{0, 1, 0, 1}, {0, 0, 0, 0}, {1, 0, 0, 1}}
+	ref := NewSparseBinaryMatrixFromInts(ints)
+
+	assertMatchesReference(t, "ints", NewCSCBinaryMatrixFromInts(ints), utils.Make2DBool(ints))
+	assertMatchesReference(t, "dense", NewCSCBinaryMatrixFromDense(utils.Make2DBool(ints)), utils.Make2DBool(ints))
+	assertMatchesReference(t, "dense1d", NewCSCBinaryMatrixFromDense1D(ref.Flatten(), 3, 4), utils.Make2DBool(ints))
+	assertMatchesReference(t, "matrix", NewCSCBinaryMatrixFromMatrix(ref), utils.Make2DBool(ints))
+
+	m := NewCSCBinaryMatrixFromInts(ints)
+	c := m.Copy()
+	c.Set(1, 1, true)
+	assert.False(t, m.Get(1, 1))
+
+	or := m.Or(NewCSCBinaryMatrixFromInts([][]int{{1, 0, 0, 0}, {0, 0, 0, 0}, {1, 0, 0, 0}}))
+	assert.Equal(t, []int{0, 2}, or.GetColIndices(0))
+	assert.Equal(t, []int{0, 2}, or.GetColIndices(3))
+
+	assert.Panics(t, func() { m.Set(3, 0, true) })
+	assert.Panics(t, func() { m.Set(0, 4, true) })
+}
+
+func TestPackedBinaryMatrix(t *testing.T) {
+	ints := [][]int{{0, 1, 0, 1}, {0, 0, 0, 0}, {1, 0, 0, 1}}
+	ref := NewDenseBinaryMatrixFromInts(ints)
+
+	assertMatchesReference(t, "ints", NewPackedBinaryMatrixFromInts(ints), utils.Make2DBool(ints))
+	assertMatchesReference(t, "dense", NewPackedBinaryMatrixFromDense(utils.Make2DBool(ints)), utils.Make2DBool(ints))
+	assertMatchesReference(t, "dense1d", NewPackedBinaryMatrixFromDense1D(ref.Flatten(), 3, 4), utils.Make2DBool(ints))
+
+	m := NewPackedBinaryMatrixFromInts(ints)
+	c := m.Copy()
+	c.Set(1, 1, true)
+	assert.False(t, m.Get(1, 1))
+
+	other := NewPackedBinaryMatrixFromInts([][]int{{1, 1, 0, 0}, {0, 0, 0, 0}, {1, 0, 0, 0}})
+	assert.Equal(t, []int{0, 1, 3}, m.Or(other).GetRowIndices(0))
+	assert.Equal(t, []int{1}, m.And(other).GetRowIndices(0))
+	assert.Equal(t, []int{0, 3}, m.Xor(other).GetRowIndices(0))
+	assert.Equal(t, []int{3}, m.Xor(other).GetRowIndices(2))
+
+	assert.Panics(t, func() { m.Set(3, 0, true) })
+	assert.Panics(t, func() { m.Set(0, 4, true) })
+	assert.Panics(t, func() { m.Or(NewPackedBinaryMatrix(3, 5)) })
+
+	//rows spanning several words
+	height, width := 5, 150
+	wide := NewPackedBinaryMatrix(height, width)
+	dense := NewDenseBinaryMatrix(height, width)
+	for i := 0; i < 200; i++ {
+		r, col := rand.Intn(height), rand.Intn(width)
+		wide.Set(r, col, true)
+		dense.Set(r, col, true)
+	}
+	wide.FillRow(4, true)
+	dense.FillRow(4, true)
+	input := make([]bool, width)
+	for i := range input {
+		input[i] = rand.Intn(3) == 0
+	}
+	assert.Equal(t, dense.RowAndSum(input), wide.RowAndSum(input))
+	assert.Equal(t, dense.Flatten(), wide.Flatten())
+	assert.Equal(t, dense.TotalNonZeroCount(), wide.TotalNonZeroCount())
+	assert.Equal(t, dense.TotalTrueCols(), wide.TotalTrueCols())
+	assert.Equal(t, width, len(wide.GetRowIndices(4)))
+}
+
+func benchmarkRowAndSum(b *testing.B, m BinaryMatrix) {
+	for i := 0; i < m.Rows(); i++ {
+		for j := 0; j < m.Cols(); j++ {
+			if rand.Intn(20) == 0 {
+				m.Set(i, j, true)
+			}
+		}
+	}
+	input := make([]bool, m.Cols())
+	for i := range input {
+		input[i] = rand.Intn(50) == 0
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.RowAndSum(input)
+	}
+}
+
+func BenchmarkRowAndSumSparse(b *testing.B) {
+	benchmarkRowAndSum(b, NewSparseBinaryMatrix(256, 1024))
+}
+
+func BenchmarkRowAndSumDense(b *testing.B) {
+	benchmarkRowAndSum(b, NewDenseBinaryMatrix(256, 1024))
+}
+
+func BenchmarkRowAndSumCSR(b *testing.B) {
+	benchmarkRowAndSum(b, NewCSRBinaryMatrix(256, 1024))
+}
+
+func BenchmarkRowAndSumCSC(b *testing.B) {
+	benchmarkRowAndSum(b, NewCSCBinaryMatrix(256, 1024))
+}
+
+func BenchmarkRowAndSumPacked(b *testing.B) {
+	benchmarkRowAndSum(b, NewPackedBinaryMatrix(256, 1024))
+}
+
+func benchmarkGetRowIndices(b *testing.B, m BinaryMatrix) {
+	for i := 0; i < m.Rows(); i++ {
+		for j := 0; j < m.Cols(); j++ {
+			if rand.Intn(20) == 0 {
+				m.Set(i, j, true)
+			}
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.GetRowIndices(i % m.Rows())
+	}
+}
+
+func BenchmarkGetRowIndicesSparse(b *testing.B) {
+	benchmarkGetRowIndices(b, NewSparseBinaryMatrix(256, 1024))
+}
+
+func BenchmarkGetRowIndicesCSR(b *testing.B) {
+	benchmarkGetRowIndices(b, NewCSRBinaryMatrix(256, 1024))
+}
+
+func randomBinaryMatrix(height, width int) [][]bool {
+	result := make([][]bool, height)
+	for r := range result {
+		result[r] = make([]bool, width)
+		for c := range result[r] {
+			result[r][c] = rand.Intn(3) == 0
+		}
+	}
+	return result
+}
+
+func TestBinaryMatrixAlgebra(t *testing.T) {
+	height, width := 7, 11
+	a := randomBinaryMatrix(height, width)
+	b := randomBinaryMatrix(height, width)
+
+	ops := []struct {
+		name string
+		op   func(x, y bool) bool
+	}{
+		{"or", func(x, y bool) bool { return x || y }},
+		{"and", func(x, y bool) bool { return x && y }},
+		{"andnot", func(x, y bool) bool { return x && !y }},
+		{"xor", func(x, y bool) bool { return x != y }},
+	}
+
+	expected := make(map[string][][]bool)
+	for _, op := range ops {
+		ref := make([][]bool, height)
+		for r := range ref {
+			ref[r] = make([]bool, width)
+			for c := range ref[r] {
+				ref[r][c] = op.op(a[r][c], b[r][c])
+			}
+		}
+		expected[op.name] = ref
+	}
+
+	others := []BinaryMatrix{NewSparseBinaryMatrixFromDense(b), NewDenseBinaryMatrixFromDense(b)}
+	for _, other := range others {
+		sm := NewSparseBinaryMatrixFromDense(a)
+		assertMatchesReference(t, "sparse or", sm.Or(other), expected["or"])
+		assertMatchesReference(t, "sparse and", sm.And(other), expected["and"])
+		assertMatchesReference(t, "sparse andnot", sm.AndNot(other), expected["andnot"])
+		assertMatchesReference(t, "sparse xor", sm.Xor(other), expected["xor"])
+		//allocating forms leave receiver untouched
+		assertMatchesReference(t, "sparse", sm, a)
+
+		dm := NewDenseBinaryMatrixFromDense(a)
+		assertMatchesReference(t, "dense or", dm.Or(other), expected["or"])
+		assertMatchesReference(t, "dense and", dm.And(other), expected["and"])
+		assertMatchesReference(t, "dense andnot", dm.AndNot(other), expected["andnot"])
+		assertMatchesReference(t, "dense xor", dm.Xor(other), expected["xor"])
+		assertMatchesReference(t, "dense", dm, a)
+
+		sm.XorInPlace(other)
+		assertMatchesReference(t, "sparse xor in place", sm, expected["xor"])
+		dm.AndNotInPlace(other)
+		assertMatchesReference(t, "dense andnot in place", dm, expected["andnot"])
+		sm = NewSparseBinaryMatrixFromDense(a)
+		sm.AndInPlace(other)
+		assertMatchesReference(t, "sparse and in place", sm, expected["and"])
+		dm = NewDenseBinaryMatrixFromDense(a)
+		dm.OrInPlace(other)
+		assertMatchesReference(t, "dense or in place", dm, expected["or"])
+	}
+
+	assert.Panics(t, func() { NewSparseBinaryMatrix(2, 3).And(NewDenseBinaryMatrix(3, 2)) })
+	assert.Panics(t, func() { NewDenseBinaryMatrix(2, 3).Or(NewSparseBinaryMatrix(3, 2)) })
+}
+
+func TestBinaryMatrixReductions(t *testing.T) {
+	ints := [][]int{{0, 1, 0, 1}, {0, 0, 0, 0}, {1, 1, 0, 1}}
+	sm := NewSparseBinaryMatrixFromInts(ints)
+	dm := NewDenseBinaryMatrixFromInts(ints)
+
+	assert.Equal(t, []int{2, 0, 3}, sm.RowSums())
+	assert.Equal(t, []int{2, 0, 3}, dm.RowSums())
+	assert.Equal(t, []int{1, 2, 0, 2}, sm.ColSums())
+	assert.Equal(t, []int{1, 2, 0, 2}, dm.ColSums())
+	assert.Equal(t, []int{1, 2, 0, 2}, ColSums(NewCSRBinaryMatrixFromInts(ints)))
+
+	transposed := [][]int{{0, 0, 1}, {1, 0, 1}, {0, 0, 0}, {1, 0, 1}}
+	assertMatchesReference(t, "sparse transpose", sm.Transpose(), utils.Make2DBool(transposed))
+	assertMatchesReference(t, "dense transpose", dm.Transpose(), utils.Make2DBool(transposed))
+
+	sub := [][]int{{0, 0}, {1, 0}}
+	assertMatchesReference(t, "sparse sub", sm.SubMatrix(1, 3, 1, 3), utils.Make2DBool(sub))
+	assertMatchesReference(t, "dense sub", dm.SubMatrix(1, 3, 1, 3), utils.Make2DBool(sub))
+	assert.Panics(t, func() { sm.SubMatrix(0, 4, 0, 1) })
+	assert.Panics(t, func() { dm.SubMatrix(2, 1, 0, 1) })
+
+	assert.True(t, sm.Equals(dm))
+	assert.True(t, dm.Equals(sm))
+	assert.True(t, BinaryMatrixEqual(sm, NewCSCBinaryMatrixFromInts(ints)))
+	dm.Set(1, 1, true)
+	assert.False(t, sm.Equals(dm))
+	assert.False(t, dm.Equals(sm))
+	assert.False(t, sm.Equals(sm.Transpose()))
+}
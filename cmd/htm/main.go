@@ -0,0 +1,95 @@
+/*
+ Command htm runs a spatial pooler and temporal memory over a CSV file.
+
+Usage:
+
+	htm -config model.json [-input data.csv] [-output results.csv] [-learn=false] [-nupic]
+
+The input must start with a header row naming its columns. With -nupic
+the input is a NuPIC data file whose three header rows hold the field
+names, types and flags, the model is reset whenever the reset field or
+sequence id starts a new sequence. The config,
+JSON or YAML (.yaml or .yml), describes how each column is encoded along
+with the spatial pooler and temporal memory params, see the model package.
+For example:
+
+	{
+		"Fields": [
+			{"Name": "timestamp", "Type": "date", "Format": "2006-01-02 15:04:05",
+			 "Params": {"TimeOfDayWidth": 21, "TimeOfDayRadius": 1}},
+			{"Name": "consumption", "Type": "scalar",
+			 "Params": {"Width": 21, "MinVal": 0, "MaxVal": 100, "N": 200, "ClipInput": true}},
+			{"Name": "kind", "Type": "category",
+			 "Params": {"Width": 21, "Categories": ["gas", "power"]}}
+		],
+		"SP": {"ColumnDimensions": [2048], "GlobalInhibition": true, "NumActiveColumnsPerInhArea": 40},
+		"TM": {"CellsPerColumn": 32}
+	}
+
+Params left out keep the library defaults. A row is written per input
+record holding the active columns, the columns predicted for the next
+record and the raw anomaly score. When the config sets PredictedField a
+bestPrediction<n> column per classifier step follows, holding the most
+likely value of the predicted field n records ahead.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/nupic-community/htm/model"
+	"io"
+	"os"
+)
+
+func main() {
+	configPath := flag.String("config", "", "model config file (JSON or YAML)")
+	inputPath := flag.String("input", "", "input CSV file, defaults to stdin")
+	outputPath := flag.String("output", "", "output CSV file, defaults to stdout")
+	learn := flag.Bool("learn", true, "enable learning")
+	nupic := flag.Bool("nupic", false, "input is a NuPIC data file with names, types and flags header rows")
+	flag.Parse()
+
+	if len(*configPath) == 0 {
+		fmt.Fprintln(os.Stderr, "htm: -config is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := runFiles(*configPath, *inputPath, *outputPath, *learn, *nupic); err != nil {
+		fmt.Fprintln(os.Stderr, "htm:", err)
+		os.Exit(1)
+	}
+}
+
+func runFiles(configPath, inputPath, outputPath string, learn, nupic bool) error {
+	cfg, err := model.LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if len(inputPath) > 0 {
+		f, err := os.Open(inputPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var out io.Writer = os.Stdout
+	if len(outputPath) > 0 {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if nupic {
+		return runNupic(cfg, in, out, learn)
+	}
+	return run(cfg, in, out, learn)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/nupic-community/htm/model"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+ Feeds CSV records through the pipeline described by a config.
+*/
+type runner struct {
+	pipeline *model.Pipeline
+	fields   []string
+	//Index of the CSV column of each field
+	columns []int
+}
+
+//Creates a runner for CSV records with the specified header
+func newRunner(cfg *model.Config, header []string) (*runner, error) {
+	pipeline, err := model.NewPipeline(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &runner{pipeline: pipeline, fields: pipeline.FieldNames()}
+	for _, field := range r.fields {
+		col := -1
+		for idx, name := range header {
+			if strings.TrimSpace(name) == field {
+				col = idx
+				break
+			}
+		}
+		if col == -1 {
+			return nil, fmt.Errorf("field %v not found in input header", field)
+		}
+		r.columns = append(r.columns, col)
+	}
+
+	return r, nil
+}
+
+//Runs a single record through the model
+func (r *runner) step(record []string, learn bool) (model.Result, error) {
+	values := make(model.Record, len(r.fields))
+	for idx, field := range r.fields {
+		col := r.columns[idx]
+		if col >= len(record) {
+			return model.Result{}, fmt.Errorf("missing column %v", col)
+		}
+		values[field] = strings.TrimSpace(record[col])
+	}
+
+	return r.pipeline.Compute(values, learn)
+}
+
+func formatColumns(columns []int) string {
+	strs := make([]string, len(columns))
+	for idx, col := range columns {
+		strs[idx] = strconv.Itoa(col)
+	}
+	return strings.Join(strs, " ")
+}
+
+//Returns the classifier steps to write best predictions for, none if no
+//field is predicted
+func predictionSteps(cfg *model.Config) []int {
+	if len(cfg.PredictedField) == 0 {
+		return nil
+	}
+	return cfg.Classifier.Steps
+}
+
+//Writes the results header row, with a best prediction column per step
+func writeHeader(writer *csv.Writer, steps []int) {
+	header := []string{"row", "activeColumns", "predictedColumns", "anomalyScore"}
+	for _, step := range steps {
+		header = append(header, fmt.Sprintf("bestPrediction%v", step))
+	}
+	writer.Write(header)
+}
+
+//Writes a row of results, best predictions are left empty until the
+//classifier has one
+func writeResult(writer *csv.Writer, row int, result model.Result, steps []int) {
+	values := []string{
+		strconv.Itoa(row),
+		formatColumns(result.ActiveColumns),
+		formatColumns(result.PredictedColumns),
+		strconv.FormatFloat(result.AnomalyScore, 'f', -1, 64),
+	}
+	for _, step := range steps {
+		prediction := ""
+		if best, ok := result.Classification.BestPrediction(step); ok {
+			prediction = strconv.FormatFloat(best, 'f', -1, 64)
+		}
+		values = append(values, prediction)
+	}
+	writer.Write(values)
+}
+
+/*
+ Reads CSV records, with a header row, from in and writes a row of
+results per record to out.
+*/
+func run(cfg *model.Config, in io.Reader, out io.Writer, learn bool) error {
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("reading header: %v", err)
+	}
+
+	r, err := newRunner(cfg, header)
+	if err != nil {
+		return err
+	}
+
+	steps := predictionSteps(cfg)
+	writer := csv.NewWriter(out)
+	writeHeader(writer, steps)
+
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("row %v: %v", row, err)
+		}
+
+		result, err := r.step(record, learn)
+		if err != nil {
+			return fmt.Errorf("row %v: %v", row, err)
+		}
+		writeResult(writer, row, result, steps)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+/*
+ Reads records from a NuPIC data file, with names, types and flags header
+rows, and writes a row of results per record to out. The model is reset
+whenever the reset or sequence id field starts a new sequence.
+*/
+func runNupic(cfg *model.Config, in io.Reader, out io.Writer, learn bool) error {
+	reader, err := model.NewFileReader(in)
+	if err != nil {
+		return err
+	}
+	pipeline, err := model.NewPipeline(cfg)
+	if err != nil {
+		return err
+	}
+
+	steps := predictionSteps(cfg)
+	writer := csv.NewWriter(out)
+	writeHeader(writer, steps)
+
+	row := 0
+	err = model.Replay(pipeline, reader, learn, func(record model.Record, result model.Result) error {
+		row++
+		writeResult(writer, row, result, steps)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/nupic-community/htm/model"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testConfig = `{
+	"Fields": [
+		{"Name": "timestamp", "Type": "date", "Format": "2006-01-02 15:04",
+		 "Params": {"SeasonWidth": 0, "WeekendWidth": 0, "TimeOfDayWidth": 21, "TimeOfDayRadius": 1}},
+		{"Name": "value", "Type": "scalar",
+		 "Params": {"Width": 21, "MinVal": 0, "MaxVal": 100, "N": 120, "ClipInput": true}},
+		{"Name": "kind", "Type": "category",
+		 "Params": {"Width": 11, "Categories": ["a", "b"]}}
+	],
+	"SP": {"ColumnDimensions": [256], "PotentialRadius": 500, "GlobalInhibition": true,
+		"NumActiveColumnsPerInhArea": 10, "Seed": 1},
+	"TM": {"CellsPerColumn": 4, "ActivationThreshold": 6, "MinThreshold": 4,
+		"MaxNewSynapseCount": 10, "InitialPermanence": 0.51}
+}`
+
+func TestRun(t *testing.T) {
+	cfg, err := model.ParseJSONConfig([]byte(testConfig))
+	assert.Nil(t, err)
+
+	var in bytes.Buffer
+	in.WriteString("timestamp,value,kind\n")
+	for rep := 0; rep < 30; rep++ {
+		for i := 0; i < 4; i++ {
+			in.WriteString(fmt.Sprintf("2015-06-01 %02d:00,%v,%v\n", i*6, i*25, []string{"a", "b"}[i%2]))
+		}
+	}
+
+	var out bytes.Buffer
+	assert.Nil(t, run(cfg, &in, &out, true))
+
+	records, err := csv.NewReader(&out).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"row", "activeColumns", "predictedColumns", "anomalyScore"}, records[0])
+	assert.Equal(t, 121, len(records))
+
+	first := records[1]
+	assert.Equal(t, "1", first[0])
+	assert.Equal(t, 10, len(strings.Fields(first[1])))
+	assert.Equal(t, "1", first[3])
+
+	//the repeating sequence is learned
+	total := 0.0
+	for _, record := range records[len(records)-8:] {
+		score, err := strconv.ParseFloat(record[3], 64)
+		assert.Nil(t, err)
+		total += score
+	}
+	assert.True(t, total/8 < 0.5)
+}
+
+func TestRunPredictions(t *testing.T) {
+	cfg, err := model.ParseJSONConfig([]byte(testConfig))
+	assert.Nil(t, err)
+	cfg.PredictedField = "value"
+	cfg.Classifier.Steps = []int{1, 2}
+
+	var in bytes.Buffer
+	in.WriteString("timestamp,value,kind\n")
+	for rep := 0; rep < 30; rep++ {
+		for i := 0; i < 4; i++ {
+			in.WriteString(fmt.Sprintf("2015-06-01 %02d:00,%v,%v\n", i*6, i*25, []string{"a", "b"}[i%2]))
+		}
+	}
+
+	var out bytes.Buffer
+	assert.Nil(t, run(cfg, &in, &out, true))
+
+	records, err := csv.NewReader(&out).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"row", "activeColumns", "predictedColumns", "anomalyScore",
+		"bestPrediction1", "bestPrediction2"}, records[0])
+
+	//the last record has value 75, the sequence continues with 0 and 25
+	last := records[len(records)-1]
+	next, err := strconv.ParseFloat(last[4], 64)
+	assert.Nil(t, err)
+	assert.InDelta(t, 0, next, 5)
+	next, err = strconv.ParseFloat(last[5], 64)
+	assert.Nil(t, err)
+	assert.InDelta(t, 25, next, 5)
+}
+
+func TestRunErrors(t *testing.T) {
+	cfg, err := model.ParseJSONConfig([]byte(testConfig))
+	assert.Nil(t, err)
+
+	var out bytes.Buffer
+	err = run(cfg, strings.NewReader("timestamp,value\n"), &out, true)
+	assert.Equal(t, "field kind not found in input header", err.Error())
+
+	err = run(cfg, strings.NewReader("timestamp,value,kind\n2015-06-01 00:00,abc,a\n"), &out, true)
+	assert.True(t, strings.HasPrefix(err.Error(), "row 1: "))
+}
+
+func TestRunNupic(t *testing.T) {
+	cfg, err := model.ParseJSONConfig([]byte(testConfig))
+	assert.Nil(t, err)
+
+	var in bytes.Buffer
+	in.WriteString("timestamp,value,kind,sequence\ndatetime,float,string,int\nT,,,S\n")
+	for rep := 0; rep < 30; rep++ {
+		for i := 0; i < 4; i++ {
+			in.WriteString(fmt.Sprintf("2015-06-01 %02d:00:00,%v,%v,%v\n", i*6, i*25, []string{"a", "b"}[i%2], rep))
+		}
+	}
+
+	var out bytes.Buffer
+	assert.Nil(t, runNupic(cfg, &in, &out, true))
+
+	records, err := csv.NewReader(&out).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, 121, len(records))
+	//every sequence starts unpredicted
+	assert.Equal(t, "1", records[len(records)-4][3])
+	assert.Equal(t, "0", records[len(records)-1][3])
+
+	err = runNupic(cfg, strings.NewReader("timestamp,value\ndatetime,double\n,\n"), &out, true)
+	assert.Equal(t, `field value: unknown type "double"`, err.Error())
+}
@@ -0,0 +1,323 @@
+package htm
+
+import (
+	"sort"
+)
+
+//Compressed sparse column binary matrix, stores the sorted "on" row
+//indices of each column. Column queries are proportional to the number of
+//entries in the column, and RowAndSum only visits the columns that are on
+//in the input, which makes it fast for sparse inputs.
+type CSCBinaryMatrix struct {
+	Width  int
+	Height int
+	cols   [][]int
+}
+
+//Create new CSC binary matrix of specified size
+func NewCSCBinaryMatrix(height, width int) *CSCBinaryMatrix {
+	m := &CSCBinaryMatrix{}
+	m.Height = height
+	m.Width = width
+	m.cols = make([][]int, width)
+	return m
+}
+
+//Create CSC binary matrix from specified dense matrix
+func NewCSCBinaryMatrixFromDense(values [][]bool) *CSCBinaryMatrix {
+	if len(values) < 1 {
+		panic("No values specified.")
+	}
+
+	m := NewCSCBinaryMatrix(len(values), len(values[0]))
+	for r := 0; r < m.Height; r++ {
+		for c, val := range values[r] {
+			if val {
+				m.cols[c] = append(m.cols[c], r)
+			}
+		}
+	}
+	return m
+}
+
+//Create CSC binary matrix from specified flattened dense matrix
+func NewCSCBinaryMatrixFromDense1D(values []bool, rows, cols int) *CSCBinaryMatrix {
+	if len(values) < 1 {
+		panic("No values specified.")
+	}
+	if len(values) != rows*cols {
+		panic("Invalid size")
+	}
+
+	m := NewCSCBinaryMatrix(rows, cols)
+	for idx, val := range values {
+		if val {
+			m.cols[idx%cols] = append(m.cols[idx%cols], idx/cols)
+		}
+	}
+	return m
+}
+
+// Creates a CSC binary matrix from specified integer array
+// (any values greater than 0 are true)
+func NewCSCBinaryMatrixFromInts(values [][]int) *CSCBinaryMatrix {
+	if len(values) < 1 {
+		panic("No values specified.")
+	}
+
+	m := NewCSCBinaryMatrix(len(values), len(values[0]))
+	for r := 0; r < m.Height; r++ {
+		for c := 0; c < m.Width; c++ {
+			if values[r][c] > 0 {
+				m.cols[c] = append(m.cols[c], r)
+			}
+		}
+	}
+	return m
+}
+
+//Create CSC binary matrix with the same entries as any other binary matrix
+func NewCSCBinaryMatrixFromMatrix(src BinaryMatrix) *CSCBinaryMatrix {
+	m := NewCSCBinaryMatrix(src.Rows(), src.Cols())
+	for _, val := range src.Entries() {
+		m.cols[val.Col] = append(m.cols[val.Col], val.Row)
+	}
+	for c := range m.cols {
+		sort.Ints(m.cols[c])
+	}
+	return m
+}
+
+//Returns number of rows
+func (sm *CSCBinaryMatrix) Rows() int {
+	return sm.Height
+}
+
+//Returns number of columns
+func (sm *CSCBinaryMatrix) Cols() int {
+	return sm.Width
+}
+
+//Returns all true/on indices in column major order
+func (sm *CSCBinaryMatrix) Entries() []SparseEntry {
+	result := make([]SparseEntry, 0, sm.TotalNonZeroCount())
+	for c, rows := range sm.cols {
+		for _, r := range rows {
+			result = append(result, SparseEntry{r, c})
+		}
+	}
+	return result
+}
+
+//Returns flattend dense represenation
+func (sm *CSCBinaryMatrix) Flatten() []bool {
+	result := make([]bool, sm.Height*sm.Width)
+	for c, rows := range sm.cols {
+		for _, r := range rows {
+			result[(r*sm.Width)+c] = true
+		}
+	}
+	return result
+}
+
+//Get value at col,row position
+func (sm *CSCBinaryMatrix) Get(row int, col int) bool {
+	return sortedContains(sm.cols[col], row)
+}
+
+//Set value at row,col position
+func (sm *CSCBinaryMatrix) Set(row int, col int, value bool) {
+	sm.validateRowCol(row, col)
+	if value {
+		sm.cols[col] = sortedInsert(sm.cols[col], row)
+	} else {
+		sm.cols[col] = sortedDelete(sm.cols[col], row)
+	}
+}
+
+//Replaces specified row with values, assumes values is ordered
+//correctly
+func (sm *CSCBinaryMatrix) ReplaceRow(row int, values []bool) {
+	sm.SetRowFromDense(row, values)
+}
+
+//Replaces row with true values at specified indices
+func (sm *CSCBinaryMatrix) ReplaceRowByIndices(row int, indices []int) {
+	sm.validateRow(row)
+	dense := make([]bool, sm.Width)
+	for _, val := range indices {
+		dense[val] = true
+	}
+	sm.SetRowFromDense(row, dense)
+}
+
+//Returns dense row
+func (sm *CSCBinaryMatrix) GetDenseRow(row int) []bool {
+	sm.validateRow(row)
+	result := make([]bool, sm.Width)
+	for c, rows := range sm.cols {
+		result[c] = sortedContains(rows, row)
+	}
+	return result
+}
+
+//Returns a rows "on" indices
+func (sm *CSCBinaryMatrix) GetRowIndices(row int) []int {
+	result := []int{}
+	for c, rows := range sm.cols {
+		if sortedContains(rows, row) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+//Returns a columns "on" indices
+func (sm *CSCBinaryMatrix) GetColIndices(col int) []int {
+	result := make([]int, len(sm.cols[col]))
+	copy(result, sm.cols[col])
+	return result
+}
+
+//Sets a sparse row from dense representation
+func (sm *CSCBinaryMatrix) SetRowFromDense(row int, denseRow []bool) {
+	sm.validateRow(row)
+	if len(denseRow) != sm.Width {
+		panic("Row width does not match matrix")
+	}
+	for c, val := range denseRow {
+		if val {
+			sm.cols[c] = sortedInsert(sm.cols[c], row)
+		} else {
+			sm.cols[c] = sortedDelete(sm.cols[c], row)
+		}
+	}
+}
+
+//In a normal matrix this would be multiplication in binary terms
+//we just and then sum the true entries
+func (sm *CSCBinaryMatrix) RowAndSum(row []bool) []int {
+	sm.validateCol(len(row))
+	result := make([]int, sm.Height)
+	for c, val := range row {
+		if !val {
+			continue
+		}
+		for _, r := range sm.cols[c] {
+			result[r]++
+		}
+	}
+	return result
+}
+
+//Returns row indexes with at least 1 true column, in ascending order
+func (sm *CSCBinaryMatrix) NonZeroRows() []int {
+	hit := make([]bool, sm.Height)
+	for _, rows := range sm.cols {
+		for _, r := range rows {
+			hit[r] = true
+		}
+	}
+
+	var result []int
+	for r, val := range hit {
+		if val {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+//Returns # of rows with at least 1 true value
+func (sm *CSCBinaryMatrix) TotalTrueRows() int {
+	return len(sm.NonZeroRows())
+}
+
+//Returns # of cols with at least 1 true value
+func (sm *CSCBinaryMatrix) TotalTrueCols() int {
+	count := 0
+	for _, rows := range sm.cols {
+		if len(rows) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+//Returns total true entries
+func (sm *CSCBinaryMatrix) TotalNonZeroCount() int {
+	count := 0
+	for _, rows := range sm.cols {
+		count += len(rows)
+	}
+	return count
+}
+
+// Ors 2 matrices
+func (sm *CSCBinaryMatrix) Or(sm2 *CSCBinaryMatrix) *CSCBinaryMatrix {
+	result := sm.Copy()
+	for c, rows := range sm2.cols {
+		for _, r := range rows {
+			result.cols[c] = sortedInsert(result.cols[c], r)
+		}
+	}
+	return result
+}
+
+//Clears  all entries
+func (sm *CSCBinaryMatrix) Clear() {
+	for c := range sm.cols {
+		sm.cols[c] = sm.cols[c][:0]
+	}
+}
+
+//Fills specified row with specified value
+func (sm *CSCBinaryMatrix) FillRow(row int, val bool) {
+	sm.validateRow(row)
+	for c := range sm.cols {
+		if val {
+			sm.cols[c] = sortedInsert(sm.cols[c], row)
+		} else {
+			sm.cols[c] = sortedDelete(sm.cols[c], row)
+		}
+	}
+}
+
+//Copys a matrix
+func (sm *CSCBinaryMatrix) Copy() *CSCBinaryMatrix {
+	if sm == nil {
+		return nil
+	}
+
+	result := NewCSCBinaryMatrix(sm.Height, sm.Width)
+	for c, rows := range sm.cols {
+		if len(rows) > 0 {
+			result.cols[c] = make([]int, len(rows))
+			copy(result.cols[c], rows)
+		}
+	}
+	return result
+}
+
+func (sm *CSCBinaryMatrix) ToString() string {
+	return binaryMatrixString(sm)
+}
+
+func (sm *CSCBinaryMatrix) validateCol(col int) {
+	if col > sm.Width {
+		panic("Specified row is wider than matrix.")
+	}
+}
+
+func (sm *CSCBinaryMatrix) validateRow(row int) {
+	if row < 0 || row >= sm.Height {
+		panic("Specified row is out of bounds.")
+	}
+}
+
+func (sm *CSCBinaryMatrix) validateRowCol(row int, col int) {
+	sm.validateRow(row)
+	if col < 0 || col >= sm.Width {
+		panic("Specified col is out of bounds.")
+	}
+}
@@ -0,0 +1,309 @@
+package htm
+
+import (
+	"sort"
+)
+
+//Compressed sparse row binary matrix, stores the sorted "on" column
+//indices of each row. Row queries are proportional to the number of
+//entries in the row, point lookups are a binary search.
+type CSRBinaryMatrix struct {
+	Width  int
+	Height int
+	rows   [][]int
+}
+
+//Create new CSR binary matrix of specified size
+func NewCSRBinaryMatrix(height, width int) *CSRBinaryMatrix {
+	m := &CSRBinaryMatrix{}
+	m.Height = height
+	m.Width = width
+	m.rows = make([][]int, height)
+	return m
+}
+
+//Create CSR binary matrix from specified dense matrix
+func NewCSRBinaryMatrixFromDense(values [][]bool) *CSRBinaryMatrix {
+	if len(values) < 1 {
+		panic("No values specified.")
+	}
+
+	m := NewCSRBinaryMatrix(len(values), len(values[0]))
+	for r := 0; r < m.Height; r++ {
+		m.SetRowFromDense(r, values[r])
+	}
+	return m
+}
+
+//Create CSR binary matrix from specified flattened dense matrix
+func NewCSRBinaryMatrixFromDense1D(values []bool, rows, cols int) *CSRBinaryMatrix {
+	if len(values) < 1 {
+		panic("No values specified.")
+	}
+	if len(values) != rows*cols {
+		panic("Invalid size")
+	}
+
+	m := NewCSRBinaryMatrix(rows, cols)
+	for r := 0; r < m.Height; r++ {
+		m.SetRowFromDense(r, values[r*cols:(r*cols)+cols])
+	}
+	return m
+}
+
+// Creates a CSR binary matrix from specified integer array
+// (any values greater than 0 are true)
+func NewCSRBinaryMatrixFromInts(values [][]int) *CSRBinaryMatrix {
+	if len(values) < 1 {
+		panic("No values specified.")
+	}
+
+	m := NewCSRBinaryMatrix(len(values), len(values[0]))
+	for r := 0; r < m.Height; r++ {
+		for c := 0; c < m.Width; c++ {
+			if values[r][c] > 0 {
+				m.rows[r] = append(m.rows[r], c)
+			}
+		}
+	}
+	return m
+}
+
+//Create CSR binary matrix with the same entries as any other binary matrix
+func NewCSRBinaryMatrixFromMatrix(src BinaryMatrix) *CSRBinaryMatrix {
+	m := NewCSRBinaryMatrix(src.Rows(), src.Cols())
+	for r := 0; r < m.Height; r++ {
+		m.rows[r] = src.GetRowIndices(r)
+		sort.Ints(m.rows[r])
+	}
+	return m
+}
+
+//Returns number of rows
+func (sm *CSRBinaryMatrix) Rows() int {
+	return sm.Height
+}
+
+//Returns number of columns
+func (sm *CSRBinaryMatrix) Cols() int {
+	return sm.Width
+}
+
+//Returns all true/on indices in row major order
+func (sm *CSRBinaryMatrix) Entries() []SparseEntry {
+	result := make([]SparseEntry, 0, sm.TotalNonZeroCount())
+	for r, cols := range sm.rows {
+		for _, c := range cols {
+			result = append(result, SparseEntry{r, c})
+		}
+	}
+	return result
+}
+
+//Returns flattend dense represenation
+func (sm *CSRBinaryMatrix) Flatten() []bool {
+	result := make([]bool, sm.Height*sm.Width)
+	for r, cols := range sm.rows {
+		for _, c := range cols {
+			result[(r*sm.Width)+c] = true
+		}
+	}
+	return result
+}
+
+//Get value at col,row position
+func (sm *CSRBinaryMatrix) Get(row int, col int) bool {
+	return sortedContains(sm.rows[row], col)
+}
+
+//Set value at row,col position
+func (sm *CSRBinaryMatrix) Set(row int, col int, value bool) {
+	sm.validateRowCol(row, col)
+	if value {
+		sm.rows[row] = sortedInsert(sm.rows[row], col)
+	} else {
+		sm.rows[row] = sortedDelete(sm.rows[row], col)
+	}
+}
+
+//Replaces specified row with values, assumes values is ordered
+//correctly
+func (sm *CSRBinaryMatrix) ReplaceRow(row int, values []bool) {
+	sm.SetRowFromDense(row, values)
+}
+
+//Replaces row with true values at specified indices
+func (sm *CSRBinaryMatrix) ReplaceRowByIndices(row int, indices []int) {
+	sm.validateRow(row)
+	newRow := make([]int, 0, len(indices))
+	for _, val := range indices {
+		newRow = sortedInsert(newRow, val)
+	}
+	sm.rows[row] = newRow
+}
+
+//Returns dense row
+func (sm *CSRBinaryMatrix) GetDenseRow(row int) []bool {
+	sm.validateRow(row)
+	result := make([]bool, sm.Width)
+	for _, c := range sm.rows[row] {
+		result[c] = true
+	}
+	return result
+}
+
+//Returns a rows "on" indices
+func (sm *CSRBinaryMatrix) GetRowIndices(row int) []int {
+	result := make([]int, len(sm.rows[row]))
+	copy(result, sm.rows[row])
+	return result
+}
+
+//Returns a columns "on" indices
+func (sm *CSRBinaryMatrix) GetColIndices(col int) []int {
+	result := []int{}
+	for r, cols := range sm.rows {
+		if sortedContains(cols, col) {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+//Sets a sparse row from dense representation
+func (sm *CSRBinaryMatrix) SetRowFromDense(row int, denseRow []bool) {
+	sm.validateRow(row)
+	if len(denseRow) != sm.Width {
+		panic("Row width does not match matrix")
+	}
+	newRow := sm.rows[row][:0]
+	for c, val := range denseRow {
+		if val {
+			newRow = append(newRow, c)
+		}
+	}
+	sm.rows[row] = newRow
+}
+
+//In a normal matrix this would be multiplication in binary terms
+//we just and then sum the true entries
+func (sm *CSRBinaryMatrix) RowAndSum(row []bool) []int {
+	sm.validateCol(len(row))
+	result := make([]int, sm.Height)
+	for r, cols := range sm.rows {
+		for _, c := range cols {
+			if row[c] {
+				result[r]++
+			}
+		}
+	}
+	return result
+}
+
+//Returns row indexes with at least 1 true column, in ascending order
+func (sm *CSRBinaryMatrix) NonZeroRows() []int {
+	var result []int
+	for r, cols := range sm.rows {
+		if len(cols) > 0 {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+//Returns # of rows with at least 1 true value
+func (sm *CSRBinaryMatrix) TotalTrueRows() int {
+	return len(sm.NonZeroRows())
+}
+
+//Returns # of cols with at least 1 true value
+func (sm *CSRBinaryMatrix) TotalTrueCols() int {
+	hit := make([]bool, sm.Width)
+	count := 0
+	for _, cols := range sm.rows {
+		for _, c := range cols {
+			if !hit[c] {
+				hit[c] = true
+				count++
+			}
+		}
+	}
+	return count
+}
+
+//Returns total true entries
+func (sm *CSRBinaryMatrix) TotalNonZeroCount() int {
+	count := 0
+	for _, cols := range sm.rows {
+		count += len(cols)
+	}
+	return count
+}
+
+// Ors 2 matrices
+func (sm *CSRBinaryMatrix) Or(sm2 *CSRBinaryMatrix) *CSRBinaryMatrix {
+	result := sm.Copy()
+	for r, cols := range sm2.rows {
+		for _, c := range cols {
+			result.rows[r] = sortedInsert(result.rows[r], c)
+		}
+	}
+	return result
+}
+
+//Clears  all entries
+func (sm *CSRBinaryMatrix) Clear() {
+	for r := range sm.rows {
+		sm.rows[r] = sm.rows[r][:0]
+	}
+}
+
+//Fills specified row with specified value
+func (sm *CSRBinaryMatrix) FillRow(row int, val bool) {
+	sm.validateRow(row)
+	sm.rows[row] = sm.rows[row][:0]
+	if val {
+		for c := 0; c < sm.Width; c++ {
+			sm.rows[row] = append(sm.rows[row], c)
+		}
+	}
+}
+
+//Copys a matrix
+func (sm *CSRBinaryMatrix) Copy() *CSRBinaryMatrix {
+	if sm == nil {
+		return nil
+	}
+
+	result := NewCSRBinaryMatrix(sm.Height, sm.Width)
+	for r, cols := range sm.rows {
+		if len(cols) > 0 {
+			result.rows[r] = make([]int, len(cols))
+			copy(result.rows[r], cols)
+		}
+	}
+	return result
+}
+
+func (sm *CSRBinaryMatrix) ToString() string {
+	return binaryMatrixString(sm)
+}
+
+func (sm *CSRBinaryMatrix) validateCol(col int) {
+	if col > sm.Width {
+		panic("Specified row is wider than matrix.")
+	}
+}
+
+func (sm *CSRBinaryMatrix) validateRow(row int) {
+	if row < 0 || row >= sm.Height {
+		panic("Specified row is out of bounds.")
+	}
+}
+
+func (sm *CSRBinaryMatrix) validateRowCol(row int, col int) {
+	sm.validateRow(row)
+	if col < 0 || col >= sm.Width {
+		panic("Specified col is out of bounds.")
+	}
+}
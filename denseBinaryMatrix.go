@@ -81,6 +81,16 @@ func (sm *DenseBinaryMatrix) toIndex(index int) (row int, col int) {
 	return
 }
 
+//Returns number of rows
+func (sm *DenseBinaryMatrix) Rows() int {
+	return sm.Height
+}
+
+//Returns number of columns
+func (sm *DenseBinaryMatrix) Cols() int {
+	return sm.Width
+}
+
 //Returns all true/on indices
 func (sm *DenseBinaryMatrix) Entries() []SparseEntry {
 	result := make([]SparseEntry, 0, int(float64(len(sm.entries))*0.3))
@@ -173,6 +183,17 @@ func (sm *DenseBinaryMatrix) GetRowIndices(row int) []int {
 	return result
 }
 
+//Returns a columns "on" indices
+func (sm *DenseBinaryMatrix) GetColIndices(col int) []int {
+	result := make([]int, 0, sm.Height)
+	for r := 0; r < sm.Height; r++ {
+		if sm.entries[r*sm.Width+col] {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
 //Sets a sparse row from dense representation
 func (sm *DenseBinaryMatrix) SetRowFromDense(row int, denseRow []bool) {
 	//TODO: speed this up
@@ -226,26 +247,150 @@ func (sm *DenseBinaryMatrix) TotalTrueRows() int {
 	return len(sm.NonZeroRows())
 }
 
+//Returns # of cols with at least 1 true value
+func (sm *DenseBinaryMatrix) TotalTrueCols() int {
+	count := 0
+	for c := 0; c < sm.Width; c++ {
+		for r := 0; r < sm.Height; r++ {
+			if sm.entries[r*sm.Width+c] {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
 //Returns total true entries
 func (sm *DenseBinaryMatrix) TotalNonZeroCount() int {
 	return len(sm.Entries())
 }
 
 // Ors 2 matrices
-func (sm *DenseBinaryMatrix) Or(sm2 *DenseBinaryMatrix) *DenseBinaryMatrix {
-	result := NewDenseBinaryMatrix(sm.Height, sm.Width)
+func (sm *DenseBinaryMatrix) Or(sm2 BinaryMatrix) *DenseBinaryMatrix {
+	result := sm.Copy()
+	result.OrInPlace(sm2)
+	return result
+}
 
-	for _, val := range sm.Entries() {
-		result.Set(val.Row, val.Col, true)
+// Ands 2 matrices
+func (sm *DenseBinaryMatrix) And(sm2 BinaryMatrix) *DenseBinaryMatrix {
+	result := sm.Copy()
+	result.AndInPlace(sm2)
+	return result
+}
+
+// Returns entries that are true in this matrix and false in sm2
+func (sm *DenseBinaryMatrix) AndNot(sm2 BinaryMatrix) *DenseBinaryMatrix {
+	result := sm.Copy()
+	result.AndNotInPlace(sm2)
+	return result
+}
+
+// Xors 2 matrices
+func (sm *DenseBinaryMatrix) Xor(sm2 BinaryMatrix) *DenseBinaryMatrix {
+	result := sm.Copy()
+	result.XorInPlace(sm2)
+	return result
+}
+
+//Sets all entries that are true in sm2
+func (sm *DenseBinaryMatrix) OrInPlace(sm2 BinaryMatrix) {
+	for idx, val := range sm.others(sm2) {
+		sm.entries[idx] = sm.entries[idx] || val
+	}
+}
+
+//Keeps only entries that are also true in sm2
+func (sm *DenseBinaryMatrix) AndInPlace(sm2 BinaryMatrix) {
+	for idx, val := range sm.others(sm2) {
+		sm.entries[idx] = sm.entries[idx] && val
 	}
+}
 
-	for _, val := range sm2.Entries() {
-		result.Set(val.Row, val.Col, true)
+//Clears all entries that are true in sm2
+func (sm *DenseBinaryMatrix) AndNotInPlace(sm2 BinaryMatrix) {
+	for idx, val := range sm.others(sm2) {
+		sm.entries[idx] = sm.entries[idx] && !val
 	}
+}
 
+//Flips all entries that are true in sm2
+func (sm *DenseBinaryMatrix) XorInPlace(sm2 BinaryMatrix) {
+	for idx, val := range sm.others(sm2) {
+		sm.entries[idx] = sm.entries[idx] != val
+	}
+}
+
+//Returns flattened entries of sm2, avoiding a copy when it is dense
+func (sm *DenseBinaryMatrix) others(sm2 BinaryMatrix) []bool {
+	validateSameSize(sm, sm2)
+	if dense, ok := sm2.(*DenseBinaryMatrix); ok {
+		return dense.entries
+	}
+	return sm2.Flatten()
+}
+
+//Returns transposed copy of matrix
+func (sm *DenseBinaryMatrix) Transpose() *DenseBinaryMatrix {
+	result := NewDenseBinaryMatrix(sm.Width, sm.Height)
+	for r := 0; r < sm.Height; r++ {
+		for c := 0; c < sm.Width; c++ {
+			result.entries[c*sm.Height+r] = sm.entries[r*sm.Width+c]
+		}
+	}
+	return result
+}
+
+//Returns the number of true entries in each row
+func (sm *DenseBinaryMatrix) RowSums() []int {
+	result := make([]int, sm.Height)
+	for idx, val := range sm.entries {
+		if val {
+			result[idx/sm.Width]++
+		}
+	}
+	return result
+}
+
+//Returns the number of true entries in each column
+func (sm *DenseBinaryMatrix) ColSums() []int {
+	result := make([]int, sm.Width)
+	for idx, val := range sm.entries {
+		if val {
+			result[idx%sm.Width]++
+		}
+	}
 	return result
 }
 
+//Returns copy of the rows [rowStart,rowEnd) and cols [colStart,colEnd)
+func (sm *DenseBinaryMatrix) SubMatrix(rowStart, rowEnd, colStart, colEnd int) *DenseBinaryMatrix {
+	if rowStart < 0 || rowEnd > sm.Height || rowStart > rowEnd ||
+		colStart < 0 || colEnd > sm.Width || colStart > colEnd {
+		panic("Sub matrix bounds out of range")
+	}
+
+	result := NewDenseBinaryMatrix(rowEnd-rowStart, colEnd-colStart)
+	for r := rowStart; r < rowEnd; r++ {
+		copy(result.entries[(r-rowStart)*result.Width:], sm.entries[r*sm.Width+colStart:r*sm.Width+colEnd])
+	}
+	return result
+}
+
+//Returns true if sm2 is the same size with the same true entries
+func (sm *DenseBinaryMatrix) Equals(sm2 BinaryMatrix) bool {
+	if sm.Height != sm2.Rows() || sm.Width != sm2.Cols() {
+		return false
+	}
+	for idx, val := range sm.others(sm2) {
+		if sm.entries[idx] != val {
+			return false
+		}
+	}
+	return true
+}
+
 //Clears  all entries
 func (sm *DenseBinaryMatrix) Clear() {
 	utils.FillSliceBool(sm.entries, false)
@@ -0,0 +1,108 @@
+package encoders
+
+import (
+	"fmt"
+)
+
+/*
+	Params for the category encoder
+*/
+type CategoryEncoderParams struct {
+	//Number of bits set for each category
+	Width int
+	//Known categories, any other value is encoded as unknown
+	Categories []string
+	Name       string
+}
+
+func NewCategoryEncoderParams(width int, categories []string) *CategoryEncoderParams {
+	p := new(CategoryEncoderParams)
+	p.Width = width
+	p.Categories = categories
+	return p
+}
+
+/*
+ Encodes a string from a fixed list of categories. Every category, plus
+one for unknown values, gets its own block of Width bits so encodings of
+different categories never overlap.
+*/
+type CategoryEncoder struct {
+	CategoryEncoderParams
+	N       int
+	indices map[string]int
+}
+
+func NewCategoryEncoder(p *CategoryEncoderParams) *CategoryEncoder {
+	if p.Width <= 0 {
+		panic("Width must be greater than 0")
+	}
+	if len(p.Categories) == 0 {
+		panic("No categories specified")
+	}
+
+	ce := new(CategoryEncoder)
+	ce.CategoryEncoderParams = *p
+	ce.indices = make(map[string]int, len(p.Categories))
+	for idx, category := range p.Categories {
+		if _, ok := ce.indices[category]; ok {
+			panic(fmt.Sprintf("Duplicate category %v", category))
+		}
+		// index 0 is reserved for unknown categories
+		ce.indices[category] = idx + 1
+	}
+	ce.N = ce.Width * (len(ce.Categories) + 1)
+
+	if len(ce.Name) == 0 {
+		ce.Name = "category"
+	}
+
+	return ce
+}
+
+//Returns the index of a category, 0 if unknown
+func (ce *CategoryEncoder) CategoryIndex(category string) int {
+	return ce.indices[category]
+}
+
+/*
+	Encodes input to specifed slice
+*/
+func (ce *CategoryEncoder) EncodeToSlice(category string, output []bool) {
+	start := ce.CategoryIndex(category) * ce.Width
+	for i := 0; i < ce.N; i++ {
+		output[i] = i >= start && i < start+ce.Width
+	}
+}
+
+/*
+	Returns encoded category
+*/
+func (ce *CategoryEncoder) Encode(category string) []bool {
+	output := make([]bool, ce.N)
+	ce.EncodeToSlice(category, output)
+	return output
+}
+
+/*
+ Returns the category with the most bits set in encoded, an empty string
+for unknown
+*/
+func (ce *CategoryEncoder) Decode(encoded []bool) string {
+	best, bestCount := 0, 0
+	for idx := 0; idx <= len(ce.Categories); idx++ {
+		count := 0
+		for _, val := range encoded[idx*ce.Width : (idx+1)*ce.Width] {
+			if val {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = idx, count
+		}
+	}
+	if best == 0 {
+		return ""
+	}
+	return ce.Categories[best-1]
+}
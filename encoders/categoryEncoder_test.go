@@ -0,0 +1,25 @@
+package encoders
+
+import (
+	"github.com/nupic-community/htm/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCategoryEncoding(t *testing.T) {
+	ce := NewCategoryEncoder(NewCategoryEncoderParams(3, []string{"ES", "GB", "US"}))
+	assert.Equal(t, 12, ce.N)
+
+	assert.Equal(t, []int{6, 7, 8}, utils.OnIndices(ce.Encode("GB")))
+	assert.Equal(t, []int{9, 10, 11}, utils.OnIndices(ce.Encode("US")))
+	//unknown values share the first block
+	assert.Equal(t, []int{0, 1, 2}, utils.OnIndices(ce.Encode("NA")))
+
+	assert.Equal(t, "GB", ce.Decode(ce.Encode("GB")))
+	assert.Equal(t, "", ce.Decode(ce.Encode("NA")))
+
+	output := make([]bool, ce.N)
+	utils.FillSliceBool(output, true)
+	ce.EncodeToSlice("ES", output)
+	assert.Equal(t, []int{3, 4, 5}, utils.OnIndices(output))
+}
@@ -301,3 +301,10 @@ func (de *DateEncoder) Description(date time.Time) string {
 		fmt.Sprintf(" holiday %v", de.holidayOffset) +
 		fmt.Sprintf(" time of day: %v ", de.timeOfDayOffset)
 }
+
+/*
+	Returns the number of bits in the encoding
+*/
+func (de *DateEncoder) Width() int {
+	return de.width
+}
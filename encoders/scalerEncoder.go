@@ -42,6 +42,8 @@ type ScalerEncoderParams struct {
 	ClipInput  bool
 	Verbosity  int
 	N          int
+	//Receives diagnostic messages, nil discards them
+	Logger utils.Logger
 }
 
 func NewScalerEncoderParams(width int, minVal float64, maxVal float64) *ScalerEncoderParams {
@@ -84,6 +86,7 @@ type ScalerEncoder struct {
 	bucketValues    []float64
 	//nInternal represents the output area excluding the possible padding on each
 	nInternal int
+	logger    utils.Logger
 }
 
 func NewScalerEncoder(p *ScalerEncoderParams) *ScalerEncoder {
@@ -124,8 +127,12 @@ func NewScalerEncoder(p *ScalerEncoderParams) *ScalerEncoder {
 		se.Name = fmt.Sprintf("[%v:%v]", se.MinVal, se.MaxVal)
 	}
 
+	se.logger = utils.WithFields(se.Logger, utils.Field("component", "scalerEncoder"),
+		utils.Field("name", se.Name))
+
 	if se.Width < 21 {
-		fmt.Println("Number of bits in the SDR must be greater than 21")
+		se.logger.Log(utils.Warn, "Number of bits in the SDR must be greater than 21",
+			utils.Field("width", se.Width))
 	}
 
 	return se
@@ -233,7 +240,8 @@ func (se *ScalerEncoder) getFirstOnBit(input float64) int {
 		if se.ClipInput && !se.Periodic {
 
 			if se.Verbosity > 0 {
-				fmt.Printf("Clipped input %v=%v to minval %v", se.Name, input, se.MinVal)
+				se.logger.Log(utils.Warn, "Clipped input to minval", utils.Field("input", input),
+					utils.Field("minVal", se.MinVal))
 			}
 			input = se.MinVal
 		} else {
@@ -252,7 +260,8 @@ func (se *ScalerEncoder) getFirstOnBit(input float64) int {
 			if input > se.MaxVal {
 				if se.ClipInput {
 					if se.Verbosity > 0 {
-						fmt.Printf("Clipped input %v=%v to maxval %v", se.Name, input, se.MaxVal)
+						se.logger.Log(utils.Warn, "Clipped input to maxval", utils.Field("input", input),
+							utils.Field("maxVal", se.MaxVal))
 					}
 					input = se.MaxVal
 				} else {
@@ -297,6 +306,13 @@ func (se *ScalerEncoder) getBucketIndices(input float64) []int {
 	return []int{bucketIdx}
 }
 
+/*
+ Returns the bucket of the input, used to classify encoded values
+*/
+func (se *ScalerEncoder) BucketIndex(input float64) int {
+	return se.getBucketIndices(input)[0]
+}
+
 /*
  Returns encoded input
 */
@@ -351,13 +367,12 @@ func (se *ScalerEncoder) EncodeToSlice(input float64, learn bool, output []bool)
 	// set the output (except for periodic wraparound)
 	utils.FillSliceRangeBool(output, true, minbin, (maxbin+1)-minbin)
 
-	if se.Verbosity >= 2 {
-		fmt.Println("input:", input)
-		fmt.Printf("half width:%v \n", se.Width)
-		fmt.Printf("range: %v - %v \n", se.MinVal, se.MaxVal)
-		fmt.Printf("n: %v width: %v resolution: %v \n", se.N, se.Width, se.Resolution)
-		fmt.Printf("radius: %v periodic: %v \n", se.Radius, se.Periodic)
-		fmt.Printf("output: %v \n", output)
+	if se.Verbosity >= 2 && se.logger.Enabled(utils.Debug) {
+		se.logger.Log(utils.Debug, "Encoded input", utils.Field("input", input),
+			utils.Field("width", se.Width), utils.Field("minVal", se.MinVal),
+			utils.Field("maxVal", se.MaxVal), utils.Field("n", se.N),
+			utils.Field("resolution", se.Resolution), utils.Field("radius", se.Radius),
+			utils.Field("periodic", se.Periodic), utils.Field("output", utils.OnIndices(output)))
 	}
 
 	//}
@@ -542,9 +557,9 @@ func (se *ScalerEncoder) Decode(encoded []bool) []utils.TupleFloat {
 
 	}
 
-	if se.Verbosity >= 2 {
-		fmt.Println("raw output:", utils.Bool2Int(encoded[:se.N]))
-		fmt.Println("filtered output:", utils.Bool2Int(tmpOutput))
+	if se.Verbosity >= 2 && se.logger.Enabled(utils.Debug) {
+		se.logger.Log(utils.Debug, "Decoding", utils.Field("raw", utils.Bool2Int(encoded[:se.N])),
+			utils.Field("filtered", utils.Bool2Int(tmpOutput)))
 	}
 
 	// ------------------------------------------------------------------------
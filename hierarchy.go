@@ -0,0 +1,316 @@
+package htm
+
+import (
+	"github.com/nupic-community/htm/utils"
+	"sort"
+)
+
+//Cells a region passes up to the next level
+type RegionOutput int
+
+const (
+	//Only the currently active cells
+	ActiveCellsOutput RegionOutput = 0
+	//Active cells plus the cells predicted for the next step
+	ActivePredictiveCellsOutput RegionOutput = 1
+	//Union SDR of the regions union pooler
+	UnionOutput RegionOutput = 2
+)
+
+/*
+ Params of a single hierarchy level. The SP input dimensions of every
+level but the first are derived from the output width of the level below,
+the temporal memory column dimensions and temporal pooler column count
+from the SP.
+*/
+type HierarchyLevelParams struct {
+	SP SpParams
+	//Temporal memory params, used unless TP is set
+	TM TemporalMemoryParams
+	//Temporal pooler params, replaces the temporal memory when set
+	TP *TemporalPoolerParams
+	//Union pooler params, required by UnionOutput
+	Union *UnionPoolerParams
+	//Cells passed to the next level
+	Output RegionOutput
+	//Number of steps whose outputs are unioned into a single input of the
+	//next level, the next level computes once every PoolingSteps steps
+	PoolingSteps int
+}
+
+//Initializes default hierarchy level params
+func NewHierarchyLevelParams() HierarchyLevelParams {
+	p := HierarchyLevelParams{}
+	p.SP = NewSpParams()
+	p.TM = *NewTemporalMemoryParams()
+	p.Output = ActiveCellsOutput
+	p.PoolingSteps = 1
+	return p
+}
+
+/*
+ A region pairs a spatial pooler with a temporal memory or temporal
+pooler, it is the building block of a hierarchy.
+*/
+type Region struct {
+	SP *SpatialPooler
+	//Set unless the region uses a temporal pooler
+	TM *TemporalMemory
+	//Set when the region uses a temporal pooler
+	TP *TemporalPooler
+	//Optional, pools the active cells into a stable union SDR
+	Union *UnionPooler
+
+	cellsPerColumn      int
+	activeArray         []bool
+	activeColumns       []int
+	prevPredicted       []int
+	prevPredictiveCells []int
+	anomalyScore        float64
+}
+
+//Creates a region, spParams.InputDimensions must be set
+func NewRegion(spParams SpParams, tmParams TemporalMemoryParams, tpParams *TemporalPoolerParams) *Region {
+	r := new(Region)
+	r.SP = NewSpatialPooler(spParams)
+
+	if tpParams != nil {
+		params := *tpParams
+		params.NumberOfCols = r.SP.NumColumns()
+		r.TP = NewTemporalPooler(params)
+		r.cellsPerColumn = params.CellsPerColumn
+	} else {
+		params := tmParams
+		params.ColumnDimensions = spParams.ColumnDimensions
+		r.TM = NewTemporalMemory(&params)
+		r.cellsPerColumn = params.CellsPerColumn
+	}
+
+	r.activeArray = make([]bool, r.SP.NumColumns())
+	return r
+}
+
+//Returns the number of cells in the region
+func (r *Region) NumCells() int {
+	return r.SP.NumColumns() * r.cellsPerColumn
+}
+
+/*
+ Adds a union pooler fed by the regions active and correctly predicted
+cells, the SP input dimensions are set to the regions cell count.
+*/
+func (r *Region) EnableUnionPooler(params UnionPoolerParams) {
+	params.SP.InputDimensions = []int{r.NumCells()}
+	r.Union = NewUnionPooler(params)
+}
+
+//Feeds an input through the spatial pooler and the temporal memory or
+//pooler, then the union pooler if enabled
+func (r *Region) Compute(input []bool, learn bool) {
+	utils.FillSliceBool(r.activeArray, false)
+	r.SP.Compute(input, learn, r.activeArray, r.SP.InhibitColumns)
+	r.activeColumns = utils.OnIndices(r.activeArray)
+	r.anomalyScore = ComputeRawAnomalyScore(r.activeColumns, r.prevPredicted)
+
+	if r.TM != nil {
+		r.TM.Compute(r.activeColumns, learn)
+	} else {
+		r.TP.Compute(r.activeArray, learn, true)
+	}
+
+	if r.Union != nil {
+		activeCells := r.ActiveCells()
+		var predictedActive []int
+		for _, cell := range activeCells {
+			if utils.ContainsInt(cell, r.prevPredictiveCells) {
+				predictedActive = append(predictedActive, cell)
+			}
+		}
+		r.Union.Compute(activeCells, predictedActive, learn)
+	}
+
+	r.prevPredictiveCells = r.PredictiveCells()
+	r.prevPredicted = r.PredictedColumns()
+}
+
+//Returns the columns active in the last compute
+func (r *Region) ActiveColumns() []int {
+	return r.activeColumns
+}
+
+//Returns the sorted indices of the active cells
+func (r *Region) ActiveCells() []int {
+	var result []int
+	if r.TM != nil {
+		result = append(result, r.TM.ActiveCells...)
+	} else {
+		result = r.stateCells(r.TP.DynamicState.InfActiveState)
+	}
+	sort.Ints(result)
+	return result
+}
+
+//Returns the sorted indices of the cells predicted for the next step
+func (r *Region) PredictiveCells() []int {
+	var result []int
+	if r.TM != nil {
+		result = append(result, r.TM.PredictiveCells...)
+	} else {
+		result = r.stateCells(r.TP.DynamicState.InfPredictedState)
+	}
+	sort.Ints(result)
+	return result
+}
+
+//Returns the sorted columns predicted for the next step
+func (r *Region) PredictedColumns() []int {
+	var result []int
+	for _, cell := range r.PredictiveCells() {
+		col := cell / r.cellsPerColumn
+		if len(result) == 0 || result[len(result)-1] != col {
+			result = append(result, col)
+		}
+	}
+	return result
+}
+
+//Returns the raw anomaly score of the last compute
+func (r *Region) AnomalyScore() float64 {
+	return r.anomalyScore
+}
+
+//Returns the cells passed to the next level
+func (r *Region) OutputCells(output RegionOutput) []int {
+	switch output {
+	case ActiveCellsOutput:
+		return r.ActiveCells()
+	case ActivePredictiveCellsOutput:
+		result := utils.Add(r.ActiveCells(), r.PredictiveCells())
+		sort.Ints(result)
+		return result
+	case UnionOutput:
+		if r.Union == nil {
+			panic("Union output requires a union pooler")
+		}
+		return r.Union.UnionSDR()
+	}
+	panic("Unknown region output")
+}
+
+//Returns the number of bits of a region output
+func (r *Region) OutputWidth(output RegionOutput) int {
+	if output == UnionOutput {
+		if r.Union == nil {
+			panic("Union output requires a union pooler")
+		}
+		return r.Union.SP.NumColumns()
+	}
+	return r.NumCells()
+}
+
+//Starts a new sequence
+func (r *Region) Reset() {
+	if r.TM != nil {
+		r.TM.Reset()
+	} else {
+		r.TP.Reset()
+	}
+	if r.Union != nil {
+		r.Union.Reset()
+	}
+	r.prevPredicted = nil
+	r.prevPredictiveCells = nil
+}
+
+//Flattens a temporal pooler state matrix into cell indices
+func (r *Region) stateCells(state *SparseBinaryMatrix) []int {
+	var result []int
+	for _, entry := range state.Entries() {
+		result = append(result, entry.Row*r.cellsPerColumn+entry.Col)
+	}
+	return result
+}
+
+/*
+ A hierarchy stacks regions, the output cells of each level are the input
+of the level above. Pooling a levels output over several steps lets the
+level above it learn structure at a longer timescale.
+*/
+type Hierarchy struct {
+	Levels []*Region
+	params []HierarchyLevelParams
+	//Output of each level unioned since the level above last computed
+	pools      [][]bool
+	poolCounts []int
+}
+
+//Creates a hierarchy, the first level is the one fed by the input
+func NewHierarchy(levels []HierarchyLevelParams) *Hierarchy {
+	if len(levels) == 0 {
+		panic("A hierarchy needs at least one level")
+	}
+
+	h := new(Hierarchy)
+	h.params = make([]HierarchyLevelParams, len(levels))
+	copy(h.params, levels)
+
+	for idx, params := range h.params {
+		if params.PoolingSteps < 1 {
+			panic("Pooling steps must be greater than 0")
+		}
+		if idx > 0 {
+			below := h.Levels[idx-1]
+			params.SP.InputDimensions = []int{below.OutputWidth(h.params[idx-1].Output)}
+		}
+		region := NewRegion(params.SP, params.TM, params.TP)
+		if params.Union != nil {
+			region.EnableUnionPooler(*params.Union)
+		}
+		h.Levels = append(h.Levels, region)
+		h.pools = append(h.pools, make([]bool, region.OutputWidth(params.Output)))
+		h.poolCounts = append(h.poolCounts, 0)
+	}
+
+	return h
+}
+
+/*
+ Feeds an input to the first level and passes it up the hierarchy.
+Returns the number of levels that computed, levels above a pooling level
+only compute once its pooling window is complete.
+*/
+func (h *Hierarchy) Compute(input []bool, learn bool) int {
+	levelInput := input
+
+	for idx, region := range h.Levels {
+		region.Compute(levelInput, learn)
+		if idx == len(h.Levels)-1 {
+			return len(h.Levels)
+		}
+
+		pool := h.pools[idx]
+		for _, cell := range region.OutputCells(h.params[idx].Output) {
+			pool[cell] = true
+		}
+		h.poolCounts[idx]++
+		if h.poolCounts[idx] < h.params[idx].PoolingSteps {
+			return idx + 1
+		}
+
+		levelInput = make([]bool, len(pool))
+		copy(levelInput, pool)
+		utils.FillSliceBool(pool, false)
+		h.poolCounts[idx] = 0
+	}
+
+	return len(h.Levels)
+}
+
+//Starts a new sequence on every level and discards partial pools
+func (h *Hierarchy) Reset() {
+	for idx, region := range h.Levels {
+		region.Reset()
+		utils.FillSliceBool(h.pools[idx], false)
+		h.poolCounts[idx] = 0
+	}
+}
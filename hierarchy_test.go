@@ -0,0 +1,119 @@
+package htm
+
+import (
+	"github.com/nupic-community/htm/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func hierarchyLevel(inputs int) HierarchyLevelParams {
+	p := NewHierarchyLevelParams()
+	p.SP.InputDimensions = []int{inputs}
+	p.SP.ColumnDimensions = []int{128}
+	p.SP.PotentialRadius = 1000
+	p.SP.GlobalInhibition = true
+	p.SP.NumActiveColumnsPerInhArea = 8
+	p.SP.Seed = 1
+	p.TM.CellsPerColumn = 4
+	p.TM.ActivationThreshold = 6
+	p.TM.MinThreshold = 4
+	p.TM.MaxNewSynapseCount = 8
+	p.TM.InitialPermanence = 0.51
+	return p
+}
+
+//Input with a block of 10 bits set for each of 4 symbols
+func hierarchyInput(symbol int) []bool {
+	return boolRange(symbol*10, symbol*10+9, 40)
+}
+
+func TestHierarchyDimensions(t *testing.T) {
+	levels := []HierarchyLevelParams{hierarchyLevel(40), hierarchyLevel(0), hierarchyLevel(0)}
+	levels[2].SP.ColumnDimensions = []int{64}
+	h := NewHierarchy(levels)
+
+	assert.Equal(t, 3, len(h.Levels))
+	assert.Equal(t, 512, h.Levels[0].NumCells())
+	assert.Equal(t, 512, h.Levels[1].SP.numInputs)
+	assert.Equal(t, 512, h.Levels[2].SP.numInputs)
+	assert.Equal(t, 256, h.Levels[2].NumCells())
+	//the params passed in are left untouched
+	assert.Equal(t, []int{0}, levels[1].SP.InputDimensions)
+}
+
+func TestHierarchyPooling(t *testing.T) {
+	levels := []HierarchyLevelParams{hierarchyLevel(40), hierarchyLevel(0), hierarchyLevel(0)}
+	levels[0].PoolingSteps = 2
+	levels[1].PoolingSteps = 2
+	h := NewHierarchy(levels)
+
+	var computed []int
+	for i := 0; i < 8; i++ {
+		computed = append(computed, h.Compute(hierarchyInput(i%4), true))
+	}
+	assert.Equal(t, []int{1, 2, 1, 3, 1, 2, 1, 3}, computed)
+
+	//a reset discards the partially pooled steps
+	assert.Equal(t, 1, h.Compute(hierarchyInput(0), true))
+	h.Reset()
+	assert.Equal(t, 1, h.Compute(hierarchyInput(1), true))
+	assert.Equal(t, 2, h.Compute(hierarchyInput(2), true))
+}
+
+func TestHierarchyLearnsSequence(t *testing.T) {
+	levels := []HierarchyLevelParams{hierarchyLevel(40), hierarchyLevel(0)}
+	levels[0].PoolingSteps = 2
+	levels[0].Output = ActivePredictiveCellsOutput
+	h := NewHierarchy(levels)
+
+	for i := 0; i < 400; i++ {
+		h.Compute(hierarchyInput(i%4), true)
+	}
+
+	//the bottom level predicts every step, the top level every pair of steps
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, 0.0, h.Levels[0].AnomalyScore())
+		h.Compute(hierarchyInput(i), true)
+	}
+	assert.Equal(t, 0.0, h.Levels[1].AnomalyScore())
+	assert.Equal(t, 8, len(h.Levels[1].ActiveColumns()))
+	assert.True(t, len(h.Levels[1].PredictedColumns()) > 0)
+}
+
+func TestRegionOutputs(t *testing.T) {
+	p := hierarchyLevel(40)
+	r := NewRegion(p.SP, p.TM, nil)
+	for i := 0; i < 40; i++ {
+		r.Compute(hierarchyInput(i%4), true)
+	}
+	active := r.ActiveCells()
+	predictive := r.PredictiveCells()
+	assert.True(t, len(active) > 0)
+	assert.True(t, len(predictive) > 0)
+	assert.Equal(t, active, r.OutputCells(ActiveCellsOutput))
+	output := r.OutputCells(ActivePredictiveCellsOutput)
+	for _, cell := range append(active, predictive...) {
+		assert.True(t, utils.ContainsInt(cell, output))
+	}
+	for _, cell := range active {
+		assert.True(t, utils.ContainsInt(cell/4, r.ActiveColumns()))
+	}
+
+	//temporal pooler regions report cells the same way
+	tpParams := NewTemporalPoolerParams()
+	tpParams.CellsPerColumn = 4
+	r = NewRegion(p.SP, p.TM, tpParams)
+	assert.Nil(t, r.TM)
+	assert.Equal(t, 128, r.TP.params.NumberOfCols)
+	r.Compute(hierarchyInput(0), true)
+	//every active column bursts on the first step
+	assert.Equal(t, 32, len(r.ActiveCells()))
+	assert.Equal(t, 1.0, r.AnomalyScore())
+}
+
+func TestHierarchyInvalidParams(t *testing.T) {
+	assert.Panics(t, func() { NewHierarchy(nil) })
+	level := hierarchyLevel(40)
+	level.PoolingSteps = 0
+	assert.Panics(t, func() { NewHierarchy([]HierarchyLevelParams{level}) })
+}
@@ -0,0 +1,374 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+//Function combining the values of a field within a window
+type AggregateFunc string
+
+const (
+	Sum   AggregateFunc = "sum"
+	Mean  AggregateFunc = "mean"
+	First AggregateFunc = "first"
+	Last  AggregateFunc = "last"
+	Max   AggregateFunc = "max"
+	Min   AggregateFunc = "min"
+	//Most frequent value, for categories. Ties go to the value seen first
+	Mode AggregateFunc = "mode"
+)
+
+//Handling of windows without records
+type GapPolicy int
+
+const (
+	//Empty windows are not emitted
+	SkipGaps GapPolicy = 0
+	//Empty windows repeat the values of the previous window
+	RepeatGaps GapPolicy = 1
+)
+
+//Handling of records whose window was already emitted
+type LatePolicy int
+
+const (
+	//Late records are counted and discarded
+	DropLate LatePolicy = 0
+	//Late records are reported as errors
+	RejectLate LatePolicy = 1
+)
+
+//Field of the aggregated records and the function producing it
+type AggregateField struct {
+	Name string
+	Func AggregateFunc
+}
+
+type AggregatorParams struct {
+	//Field holding the record timestamp, either a time.Time or a string
+	//in TimestampFormat. The aggregated records hold the window start
+	TimestampField  string
+	TimestampFormat string
+	//Length of the windows, e.g. time.ParseDuration("5m"). Windows are
+	//aligned to multiples of the length since the zero time in UTC
+	Window time.Duration
+	//Fields of the aggregated records, other fields are ignored
+	Fields []AggregateField
+	Gaps   GapPolicy
+	Late   LatePolicy
+	//Time a window is kept open after its end, records arriving out of
+	//order within this time are still aggregated
+	AllowedLateness time.Duration
+}
+
+//Initializes default aggregator params
+func NewAggregatorParams() AggregatorParams {
+	p := AggregatorParams{}
+	p.TimestampField = "timestamp"
+	p.TimestampFormat = DefaultDateFormat
+	p.Window = time.Hour
+	p.Gaps = SkipGaps
+	p.Late = DropLate
+	return p
+}
+
+//Records of a single window
+type window struct {
+	start time.Time
+	aggs  []*fieldAggregate
+}
+
+//Values of a single field within a window
+type fieldAggregate struct {
+	count        int
+	sum          float64
+	max          float64
+	min          float64
+	first        interface{}
+	firstTime    time.Time
+	last         interface{}
+	lastTime     time.Time
+	modeCounts   map[string]int
+	modeValues   map[string]interface{}
+	modeOrdering []string
+}
+
+/*
+ Groups timestamped records into fixed windows, one record is emitted per
+window. Windows are emitted once a record at least AllowedLateness past
+their end arrives, so records are expected in roughly increasing time
+order.
+*/
+type Aggregator struct {
+	params AggregatorParams
+	//Open windows keyed by start in unix nanoseconds, time.Time keys would
+	//tell equal instants in different locations apart
+	windows map[int64]*window
+	//Start of the next window to emit
+	next    time.Time
+	started bool
+	emitted bool
+	//Latest timestamp seen
+	watermark time.Time
+	prev      Record
+	dropped   int
+}
+
+//Creates an aggregator, invalid params are returned as errors
+func NewAggregator(params AggregatorParams) (*Aggregator, error) {
+	if len(params.TimestampField) == 0 {
+		return nil, fmt.Errorf("timestamp field not specified")
+	}
+	if params.Window <= 0 {
+		return nil, fmt.Errorf("window must be greater than 0")
+	}
+	if params.AllowedLateness < 0 {
+		return nil, fmt.Errorf("allowed lateness must be >= 0")
+	}
+	if params.Gaps != SkipGaps && params.Gaps != RepeatGaps {
+		return nil, fmt.Errorf("unknown gap policy %v", int(params.Gaps))
+	}
+	if params.Late != DropLate && params.Late != RejectLate {
+		return nil, fmt.Errorf("unknown late policy %v", int(params.Late))
+	}
+	for _, field := range params.Fields {
+		switch field.Func {
+		case Sum, Mean, First, Last, Max, Min, Mode:
+		default:
+			return nil, fmt.Errorf("field %v: unknown aggregate function %q", field.Name, field.Func)
+		}
+		if field.Name == params.TimestampField {
+			return nil, fmt.Errorf("field %v: the timestamp field can't be aggregated", field.Name)
+		}
+	}
+
+	a := &Aggregator{params: params}
+	a.params.Fields = append([]AggregateField(nil), params.Fields...)
+	a.windows = make(map[int64]*window)
+	return a, nil
+}
+
+//Returns the number of late records dropped
+func (a *Aggregator) Dropped() int {
+	return a.dropped
+}
+
+func (a *Aggregator) timestamp(record Record) (time.Time, error) {
+	switch v := record[a.params.TimestampField].(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		return time.Parse(a.params.TimestampFormat, v)
+	case nil:
+		return time.Time{}, fmt.Errorf("missing field %v", a.params.TimestampField)
+	}
+	return time.Time{}, fmt.Errorf("unsupported timestamp %v", record[a.params.TimestampField])
+}
+
+/*
+ Adds a record and returns the records of the windows it completed,
+oldest first.
+*/
+func (a *Aggregator) Add(record Record) ([]Record, error) {
+	ts, err := a.timestamp(record)
+	if err != nil {
+		return nil, err
+	}
+	start := ts.Truncate(a.params.Window)
+
+	if a.emitted && start.Before(a.next) {
+		if a.params.Late == RejectLate {
+			return nil, fmt.Errorf("late record at %v, window already emitted", ts)
+		}
+		a.dropped++
+		return nil, nil
+	}
+
+	w := a.windows[start.UnixNano()]
+	if w == nil {
+		w = &window{start: start}
+	}
+	if err := a.add(w, record, ts); err != nil {
+		return nil, err
+	}
+	a.windows[start.UnixNano()] = w
+
+	// Until something is emitted the first window may still move back
+	if !a.started || start.Before(a.next) {
+		a.next = start
+	}
+	if !a.started || ts.After(a.watermark) {
+		a.watermark = ts
+	}
+	a.started = true
+
+	return a.emit(a.watermark.Add(-a.params.AllowedLateness)), nil
+}
+
+//Returns the records of every open window, at the end of a stream
+func (a *Aggregator) Flush() []Record {
+	if len(a.windows) == 0 {
+		return nil
+	}
+	var last time.Time
+	for _, w := range a.windows {
+		if w.start.After(last) {
+			last = w.start
+		}
+	}
+	return a.emit(last.Add(a.params.Window))
+}
+
+//Adds the fields of a record to a windows aggregates
+func (a *Aggregator) add(w *window, record Record, ts time.Time) error {
+
+	// Values are validated before any aggregate is changed
+	nums := make([]float64, len(a.params.Fields))
+	for idx, field := range a.params.Fields {
+		value, ok := record[field.Name]
+		if !ok || value == nil {
+			continue
+		}
+		switch field.Func {
+		case Sum, Mean, Max, Min:
+			val, err := floatValue(value)
+			if err != nil {
+				return fmt.Errorf("field %v: %v", field.Name, err)
+			}
+			nums[idx] = val
+		}
+	}
+
+	if w.aggs == nil {
+		w.aggs = make([]*fieldAggregate, len(a.params.Fields))
+		for idx := range w.aggs {
+			w.aggs[idx] = &fieldAggregate{max: math.Inf(-1), min: math.Inf(1)}
+		}
+	}
+
+	for idx, field := range a.params.Fields {
+		value, ok := record[field.Name]
+		if !ok || value == nil {
+			continue
+		}
+		agg := w.aggs[idx]
+		agg.count++
+		agg.sum += nums[idx]
+		agg.max = math.Max(agg.max, nums[idx])
+		agg.min = math.Min(agg.min, nums[idx])
+
+		if agg.count == 1 || ts.Before(agg.firstTime) {
+			agg.first, agg.firstTime = value, ts
+		}
+		if agg.count == 1 || !ts.Before(agg.lastTime) {
+			agg.last, agg.lastTime = value, ts
+		}
+
+		if field.Func == Mode {
+			if agg.modeCounts == nil {
+				agg.modeCounts = make(map[string]int)
+				agg.modeValues = make(map[string]interface{})
+			}
+			key := fmt.Sprintf("%v", value)
+			if _, ok := agg.modeCounts[key]; !ok {
+				agg.modeOrdering = append(agg.modeOrdering, key)
+				agg.modeValues[key] = value
+			}
+			agg.modeCounts[key]++
+		}
+	}
+
+	return nil
+}
+
+//Emits the windows ending at or before until, filling gaps between them
+func (a *Aggregator) emit(until time.Time) []Record {
+	var result []Record
+
+	for !a.next.Add(a.params.Window).After(until) {
+		w, ok := a.windows[a.next.UnixNano()]
+		switch {
+		case ok:
+			record := a.aggregate(w)
+			delete(a.windows, a.next.UnixNano())
+			result = append(result, record)
+			a.prev = record
+			a.emitted = true
+		case a.params.Gaps == RepeatGaps && a.prev != nil:
+			record := make(Record, len(a.prev))
+			for k, v := range a.prev {
+				record[k] = v
+			}
+			record[a.params.TimestampField] = a.next
+			result = append(result, record)
+		default:
+			// Skip straight to the next window holding records, windows
+			// still open may receive late records though
+			next, ok := a.nextWindow()
+			if open := until.Truncate(a.params.Window); !ok || open.Before(next) {
+				next = open
+			}
+			if next.After(a.next) {
+				a.next = next
+				continue
+			}
+		}
+		a.next = a.next.Add(a.params.Window)
+	}
+
+	return result
+}
+
+//Returns the start of the earliest open window
+func (a *Aggregator) nextWindow() (time.Time, bool) {
+	if len(a.windows) == 0 {
+		return time.Time{}, false
+	}
+	starts := make([]time.Time, 0, len(a.windows))
+	for _, w := range a.windows {
+		starts = append(starts, w.start)
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i].Before(starts[j])
+	})
+	return starts[0], true
+}
+
+//Builds the record of a window, fields without values are left out
+func (a *Aggregator) aggregate(w *window) Record {
+	record := Record{a.params.TimestampField: w.start}
+
+	for idx, field := range a.params.Fields {
+		agg := w.aggs[idx]
+		if agg.count == 0 {
+			continue
+		}
+		switch field.Func {
+		case Sum:
+			record[field.Name] = agg.sum
+		case Mean:
+			record[field.Name] = agg.sum / float64(agg.count)
+		case First:
+			record[field.Name] = agg.first
+		case Last:
+			record[field.Name] = agg.last
+		case Max:
+			record[field.Name] = agg.max
+		case Min:
+			record[field.Name] = agg.min
+		case Mode:
+			best := agg.modeOrdering[0]
+			for _, key := range agg.modeOrdering[1:] {
+				if agg.modeCounts[key] > agg.modeCounts[best] {
+					best = key
+				}
+			}
+			record[field.Name] = agg.modeValues[best]
+		}
+	}
+
+	return record
+}
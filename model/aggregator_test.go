@@ -0,0 +1,190 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func aggregatorTime(hour, min int) time.Time {
+	return time.Date(2015, 6, 1, hour, min, 0, 0, time.UTC)
+}
+
+func testAggregator(t *testing.T, configure func(p *AggregatorParams)) *Aggregator {
+	params := NewAggregatorParams()
+	params.Fields = []AggregateField{{"value", Sum}}
+	if configure != nil {
+		configure(&params)
+	}
+	a, err := NewAggregator(params)
+	assert.Nil(t, err)
+	return a
+}
+
+//Adds records of value 1 at the times and returns the emitted timestamps
+func addTimes(t *testing.T, a *Aggregator, times ...time.Time) []time.Time {
+	var result []time.Time
+	for _, ts := range times {
+		records, err := a.Add(Record{"timestamp": ts, "value": 1})
+		assert.Nil(t, err)
+		for _, record := range records {
+			result = append(result, record["timestamp"].(time.Time))
+		}
+	}
+	return result
+}
+
+func TestAggregatorFuncs(t *testing.T) {
+	a := testAggregator(t, func(p *AggregatorParams) {
+		p.Window = 5 * time.Minute
+		p.Fields = []AggregateField{
+			{"sum", Sum}, {"mean", Mean}, {"first", First}, {"last", Last},
+			{"max", Max}, {"min", Min}, {"kind", Mode},
+		}
+	})
+
+	input := []Record{
+		{"timestamp": aggregatorTime(0, 1), "sum": 1, "mean": "1", "first": 1, "last": 1, "max": 1, "min": 1, "kind": "a"},
+		//arrives out of order within the window
+		{"timestamp": aggregatorTime(0, 0), "sum": 2.5, "mean": 3.0, "first": 2, "last": 2, "max": 5, "min": 5, "kind": "b"},
+		{"timestamp": aggregatorTime(0, 4), "sum": 3, "mean": 2.0, "first": 3, "last": 3, "max": 2, "min": 2, "kind": "b"},
+		{"timestamp": aggregatorTime(0, 3), "kind": "a", "ignored": 1},
+	}
+	for _, record := range input {
+		records, err := a.Add(record)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(records))
+	}
+
+	records, err := a.Add(Record{"timestamp": "2015-06-01 00:05:00", "sum": 1, "kind": "c"})
+	assert.Nil(t, err)
+	assert.Equal(t, []Record{{
+		"timestamp": aggregatorTime(0, 0),
+		"sum":       6.5,
+		"mean":      2.0,
+		"first":     2,
+		"last":      3,
+		"max":       5.0,
+		"min":       1.0,
+		//ties go to the value seen first
+		"kind": "a",
+	}}, records)
+
+	//fields without values are left out
+	assert.Equal(t, []Record{{
+		"timestamp": aggregatorTime(0, 5),
+		"sum":       1.0,
+		"kind":      "c",
+	}}, a.Flush())
+	assert.Nil(t, a.Flush())
+}
+
+func TestAggregatorGaps(t *testing.T) {
+	a := testAggregator(t, nil)
+	emitted := addTimes(t, a, aggregatorTime(0, 30), aggregatorTime(3, 10), aggregatorTime(4, 0))
+	assert.Equal(t, []time.Time{aggregatorTime(0, 0), aggregatorTime(3, 0)}, emitted)
+
+	a = testAggregator(t, func(p *AggregatorParams) {
+		p.Gaps = RepeatGaps
+	})
+	addTimes(t, a, aggregatorTime(0, 30), aggregatorTime(0, 40))
+	records, err := a.Add(Record{"timestamp": aggregatorTime(3, 10), "value": 1})
+	assert.Nil(t, err)
+	assert.Equal(t, []Record{
+		{"timestamp": aggregatorTime(0, 0), "value": 2.0},
+		{"timestamp": aggregatorTime(1, 0), "value": 2.0},
+		{"timestamp": aggregatorTime(2, 0), "value": 2.0},
+	}, records)
+}
+
+func TestAggregatorLateRecords(t *testing.T) {
+	a := testAggregator(t, nil)
+	addTimes(t, a, aggregatorTime(0, 30), aggregatorTime(1, 10))
+	records, err := a.Add(Record{"timestamp": aggregatorTime(0, 50), "value": 1})
+	assert.Nil(t, err)
+	assert.Nil(t, records)
+	assert.Equal(t, 1, a.Dropped())
+
+	a = testAggregator(t, func(p *AggregatorParams) {
+		p.Late = RejectLate
+	})
+	addTimes(t, a, aggregatorTime(0, 30), aggregatorTime(1, 10))
+	_, err = a.Add(Record{"timestamp": aggregatorTime(0, 50), "value": 1})
+	assert.NotNil(t, err)
+
+	//windows are kept open for the allowed lateness
+	a = testAggregator(t, func(p *AggregatorParams) {
+		p.AllowedLateness = 2 * time.Hour
+	})
+	assert.Nil(t, addTimes(t, a, aggregatorTime(0, 30), aggregatorTime(1, 10), aggregatorTime(0, 50)))
+	assert.Equal(t, []time.Time{aggregatorTime(0, 0)}, addTimes(t, a, aggregatorTime(3, 0)))
+	//empty windows are skipped up to the first one still open
+	assert.Equal(t, []time.Time{aggregatorTime(1, 0)}, addTimes(t, a, aggregatorTime(5, 30), aggregatorTime(3, 40)))
+	assert.Equal(t, 0, a.Dropped())
+	assert.Nil(t, addTimes(t, a, aggregatorTime(2, 30)))
+	assert.Equal(t, 1, a.Dropped())
+
+	records = a.Flush()
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, Record{"timestamp": aggregatorTime(3, 0), "value": 2.0}, records[0])
+	assert.Equal(t, aggregatorTime(5, 0), records[1]["timestamp"])
+}
+
+func TestAggregatorErrors(t *testing.T) {
+	invalid := []func(p *AggregatorParams){
+		func(p *AggregatorParams) { p.TimestampField = "" },
+		func(p *AggregatorParams) { p.Window = 0 },
+		func(p *AggregatorParams) { p.AllowedLateness = -1 },
+		func(p *AggregatorParams) { p.Gaps = 2 },
+		func(p *AggregatorParams) { p.Late = 2 },
+		func(p *AggregatorParams) { p.Fields = []AggregateField{{"value", "median"}} },
+		func(p *AggregatorParams) { p.Fields = []AggregateField{{"timestamp", First}} },
+	}
+	for _, configure := range invalid {
+		params := NewAggregatorParams()
+		configure(&params)
+		_, err := NewAggregator(params)
+		assert.NotNil(t, err)
+	}
+
+	a := testAggregator(t, nil)
+	_, err := a.Add(Record{"value": 1})
+	assert.Equal(t, "missing field timestamp", err.Error())
+	_, err = a.Add(Record{"timestamp": "yesterday", "value": 1})
+	assert.NotNil(t, err)
+	_, err = a.Add(Record{"timestamp": aggregatorTime(0, 0), "value": "abc"})
+	assert.NotNil(t, err)
+	//invalid records are not aggregated
+	assert.Nil(t, a.Flush())
+}
+
+func TestAggregatorPipeline(t *testing.T) {
+	cfg, err := ParseJSONConfig([]byte(`{
+		"Fields": [
+			{"Name": "timestamp", "Type": "date", "Params": {"TimeOfDayWidth": 21, "TimeOfDayRadius": 1}},
+			{"Name": "value", "Type": "scalar",
+			 "Params": {"Width": 21, "MinVal": 0, "MaxVal": 100, "N": 200, "ClipInput": true}}
+		],
+		"SP": {"ColumnDimensions": [256], "PotentialRadius": 500, "NumActiveColumnsPerInhArea": 10}
+	}`))
+	assert.Nil(t, err)
+	p, err := NewPipeline(cfg)
+	assert.Nil(t, err)
+
+	a := testAggregator(t, func(p *AggregatorParams) {
+		p.Window = 15 * time.Minute
+		p.Fields = []AggregateField{{"value", Mean}}
+	})
+	count := 0
+	for min := 0; min < 120; min += 4 {
+		records, err := a.Add(Record{"timestamp": aggregatorTime(0, 0).Add(time.Duration(min) * time.Minute), "value": min})
+		assert.Nil(t, err)
+		for _, record := range records {
+			_, err := p.Compute(record, true)
+			assert.Nil(t, err)
+			count++
+		}
+	}
+	//the last window is still open
+	assert.Equal(t, 7, count)
+}
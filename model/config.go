@@ -0,0 +1,204 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/nupic-community/htm"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+//Temporal algorithms a config can use
+const (
+	TemporalMemory = "tm"
+	TemporalPooler = "tp"
+)
+
+/*
+ Describes how a record field is encoded. Type is one of "scalar", "date"
+or "category", Params holds the matching encoder params and is applied on
+top of the encoders defaults.
+*/
+type FieldConfig struct {
+	Name string
+	Type string
+	//Layout of date fields, see time.Parse
+	Format string
+	Params json.RawMessage
+}
+
+/*
+ Model description. SP, TM, TP and Classifier start from the library
+defaults, only the params that differ need to be specified. The SP input
+dimensions are derived from the encoders, the TM column dimensions and TP
+column count from the SP, setting them is an error. Temporal selects the temporal algorithm, "tm"
+(default) or "tp". When PredictedField is set a classifier predicts its
+values Classifier.Steps records ahead.
+*/
+type Config struct {
+	Fields         []FieldConfig
+	PredictedField string
+	Temporal       string
+	SP             htm.SpParams
+	TM             htm.TemporalMemoryParams
+	TP             htm.TemporalPoolerParams
+	Classifier     htm.SDRClassifierParams
+}
+
+//Returns a config holding the library defaults
+func NewConfig() *Config {
+	cfg := &Config{
+		Temporal:   TemporalMemory,
+		SP:         htm.NewSpParams(),
+		TM:         *htm.NewTemporalMemoryParams(),
+		TP:         *htm.NewTemporalPoolerParams(),
+		Classifier: htm.NewSDRClassifierParams(),
+	}
+	//Derived by the pipeline
+	cfg.SP.InputDimensions = nil
+	cfg.TM.ColumnDimensions = nil
+	cfg.TP.NumberOfCols = 0
+	return cfg
+}
+
+/*
+ Reads and validates a config file. Files ending in .yaml or .yml are
+parsed as YAML, anything else as JSON.
+*/
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseYAMLConfig(data)
+	}
+	return ParseJSONConfig(data)
+}
+
+//Parses and validates a JSON config, unknown keys are rejected
+func ParseJSONConfig(data []byte) (*Config, error) {
+	cfg := NewConfig()
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+/*
+ Parses and validates a YAML config. Keys are the same as the JSON keys,
+the document is converted to JSON so both formats share the same
+decoding and validation rules.
+*/
+func ParseYAMLConfig(data []byte) (*Config, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+
+	jsonData, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+	return ParseJSONConfig(jsonData)
+}
+
+//Checks the config describes a model that can be built
+func (cfg *Config) Validate() error {
+	if len(cfg.Fields) == 0 {
+		return fmt.Errorf("invalid config: no fields specified")
+	}
+
+	names := make(map[string]bool, len(cfg.Fields))
+	for _, field := range cfg.Fields {
+		if len(field.Name) == 0 {
+			return fmt.Errorf("invalid config: field name not specified")
+		}
+		if names[field.Name] {
+			return fmt.Errorf("invalid config: duplicate field %v", field.Name)
+		}
+		names[field.Name] = true
+
+		enc, err := newFieldEncoder(field)
+		if err != nil {
+			return fmt.Errorf("invalid config: field %v: %v", field.Name, err)
+		}
+		if field.Name == cfg.PredictedField {
+			if _, ok := enc.(bucketEncoder); !ok {
+				return fmt.Errorf("invalid config: predicted field %v of type %v can not be classified",
+					field.Name, field.Type)
+			}
+		}
+	}
+
+	if len(cfg.PredictedField) > 0 && !names[cfg.PredictedField] {
+		return fmt.Errorf("invalid config: predicted field %v not found", cfg.PredictedField)
+	}
+
+	if len(cfg.SP.ColumnDimensions) == 0 {
+		return fmt.Errorf("invalid config: SP.ColumnDimensions not specified")
+	}
+	for _, dim := range cfg.SP.ColumnDimensions {
+		if dim <= 0 {
+			return fmt.Errorf("invalid config: SP.ColumnDimensions must be greater than 0")
+		}
+	}
+	if cfg.SP.NumActiveColumnsPerInhArea <= 0 && cfg.SP.LocalAreaDensity <= 0 {
+		return fmt.Errorf("invalid config: SP.NumActiveColumnsPerInhArea or SP.LocalAreaDensity must be greater than 0")
+	}
+	if len(cfg.SP.InputDimensions) > 0 {
+		return fmt.Errorf("invalid config: SP.InputDimensions is derived from the fields and can't be set")
+	}
+	if len(cfg.TM.ColumnDimensions) > 0 {
+		return fmt.Errorf("invalid config: TM.ColumnDimensions is derived from SP.ColumnDimensions and can't be set")
+	}
+	if cfg.TP.NumberOfCols != 0 {
+		return fmt.Errorf("invalid config: TP.NumberOfCols is derived from SP.ColumnDimensions and can't be set")
+	}
+
+	switch cfg.Temporal {
+	case TemporalMemory:
+		if cfg.TM.CellsPerColumn <= 0 {
+			return fmt.Errorf("invalid config: TM.CellsPerColumn must be greater than 0")
+		}
+	case TemporalPooler:
+		if cfg.TP.CellsPerColumn <= 0 {
+			return fmt.Errorf("invalid config: TP.CellsPerColumn must be greater than 0")
+		}
+	default:
+		return fmt.Errorf("invalid config: unknown temporal algorithm %q", cfg.Temporal)
+	}
+
+	if len(cfg.PredictedField) > 0 {
+		if len(cfg.Classifier.Steps) == 0 {
+			return fmt.Errorf("invalid config: Classifier.Steps not specified")
+		}
+		for _, step := range cfg.Classifier.Steps {
+			if step < 0 {
+				return fmt.Errorf("invalid config: Classifier.Steps must be >= 0")
+			}
+		}
+		if cfg.Classifier.Alpha <= 0 {
+			return fmt.Errorf("invalid config: Classifier.Alpha must be greater than 0")
+		}
+		if cfg.Classifier.ActValueAlpha <= 0 || cfg.Classifier.ActValueAlpha > 1 {
+			return fmt.Errorf("invalid config: Classifier.ActValueAlpha must be in (0,1]")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testJSONConfig = `{
+	"Fields": [
+		{"Name": "timestamp", "Type": "date", "Format": "2006-01-02 15:04",
+		 "Params": {"SeasonWidth": 0, "WeekendWidth": 0, "TimeOfDayWidth": 21, "TimeOfDayRadius": 1}},
+		{"Name": "value", "Type": "scalar",
+		 "Params": {"Width": 21, "MinVal": 0, "MaxVal": 100, "N": 120, "ClipInput": true}},
+		{"Name": "kind", "Type": "category",
+		 "Params": {"Width": 11, "Categories": ["a", "b"]}}
+	],
+	"PredictedField": "value",
+	"SP": {"ColumnDimensions": [256], "PotentialRadius": 500, "GlobalInhibition": true,
+		"NumActiveColumnsPerInhArea": 10, "Seed": 1},
+	"TM": {"CellsPerColumn": 4, "ActivationThreshold": 6, "MinThreshold": 4,
+		"MaxNewSynapseCount": 10, "InitialPermanence": 0.51},
+	"Classifier": {"Steps": [1, 2], "Alpha": 0.1}
+}`
+
+const testYAMLConfig = `
+Fields:
+  - Name: timestamp
+    Type: date
+    Format: "2006-01-02 15:04"
+    Params: {SeasonWidth: 0, WeekendWidth: 0, TimeOfDayWidth: 21, TimeOfDayRadius: 1}
+  - Name: value
+    Type: scalar
+    Params: {Width: 21, MinVal: 0, MaxVal: 100, N: 120, ClipInput: true}
+  - Name: kind
+    Type: category
+    Params: {Width: 11, Categories: [a, b]}
+PredictedField: value
+SP:
+  ColumnDimensions: [256]
+  PotentialRadius: 500
+  GlobalInhibition: true
+  NumActiveColumnsPerInhArea: 10
+  Seed: 1
+TM:
+  CellsPerColumn: 4
+  ActivationThreshold: 6
+  MinThreshold: 4
+  MaxNewSynapseCount: 10
+  InitialPermanence: 0.51
+Classifier:
+  Steps: [1, 2]
+  Alpha: 0.1
+`
+
+func TestParseJSONConfig(t *testing.T) {
+	cfg, err := ParseJSONConfig([]byte(testJSONConfig))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(cfg.Fields))
+	assert.Equal(t, "value", cfg.PredictedField)
+	assert.Equal(t, TemporalMemory, cfg.Temporal)
+	assert.Equal(t, []int{256}, cfg.SP.ColumnDimensions)
+	assert.Equal(t, 4, cfg.TM.CellsPerColumn)
+	assert.Equal(t, []int{1, 2}, cfg.Classifier.Steps)
+	//unspecified params keep their defaults
+	assert.Equal(t, 0.5, cfg.SP.PotentialPct)
+	assert.Equal(t, 0.1, cfg.TM.PermanenceIncrement)
+	assert.Equal(t, 0.3, cfg.Classifier.ActValueAlpha)
+	assert.Equal(t, 10, cfg.TP.CellsPerColumn)
+}
+
+func TestParseYAMLConfig(t *testing.T) {
+	yamlCfg, err := ParseYAMLConfig([]byte(testYAMLConfig))
+	assert.Nil(t, err)
+	jsonCfg, err := ParseJSONConfig([]byte(testJSONConfig))
+	assert.Nil(t, err)
+
+	assert.Equal(t, len(jsonCfg.Fields), len(yamlCfg.Fields))
+	for idx := range jsonCfg.Fields {
+		assert.Equal(t, jsonCfg.Fields[idx].Name, yamlCfg.Fields[idx].Name)
+		assert.Equal(t, jsonCfg.Fields[idx].Format, yamlCfg.Fields[idx].Format)
+	}
+	assert.Equal(t, jsonCfg.SP, yamlCfg.SP)
+	assert.Equal(t, jsonCfg.TM, yamlCfg.TM)
+	assert.Equal(t, jsonCfg.Classifier, yamlCfg.Classifier)
+
+	_, err = ParseYAMLConfig([]byte("Fields: [{Name: x, Type: scalar, Params: {Widht: 21}}]"))
+	assert.NotNil(t, err)
+	_, err = ParseYAMLConfig([]byte("Fields: [\n"))
+	assert.NotNil(t, err)
+	_, err = ParseYAMLConfig([]byte(""))
+	assert.NotNil(t, err)
+}
+
+func TestConfigValidation(t *testing.T) {
+	invalid := []string{
+		//unknown keys
+		`{"Fields": [{"Name": "x", "Type": "scalar", "Params": {"Widht": 21}}]}`,
+		`{"Fields": [{"Name": "x", "Type": "scalar", "Params": {"Width": 21, "MaxVal": 1}}], "Classifer": {}}`,
+		//unknown field type
+		`{"Fields": [{"Name": "x", "Type": "image"}]}`,
+		//encoder validation errors are reported rather than panicking
+		`{"Fields": [{"Name": "x", "Type": "scalar", "Params": {"Width": 20, "MinVal": 0, "MaxVal": 1}}]}`,
+		`{"Fields": []}`,
+		`{"Fields": [{"Type": "category", "Params": {"Width": 3, "Categories": ["a"]}}]}`,
+		`{"Fields": [{"Name": "x", "Type": "category", "Params": {"Width": 3, "Categories": ["a"]}},
+			{"Name": "x", "Type": "category", "Params": {"Width": 3, "Categories": ["a"]}}]}`,
+		`{"Fields": [{"Name": "x", "Type": "category", "Params": {"Width": 3, "Categories": ["a"]}}],
+			"PredictedField": "y"}`,
+		`{"Fields": [{"Name": "x", "Type": "date"}], "PredictedField": "x"}`,
+		`{"Fields": [{"Name": "x", "Type": "category", "Params": {"Width": 3, "Categories": ["a"]}}],
+			"Temporal": "lstm"}`,
+		`{"Fields": [{"Name": "x", "Type": "category", "Params": {"Width": 3, "Categories": ["a"]}}],
+			"SP": {"ColumnDimensions": []}}`,
+		`{"Fields": [{"Name": "x", "Type": "category", "Params": {"Width": 3, "Categories": ["a"]}}],
+			"PredictedField": "x", "Classifier": {"Alpha": 0}}`,
+		`{"Fields": [{"Name": "x", "Type": "category", "Params": {"Width": 3, "Categories": ["a"]}}],
+			"Temporal": "tp", "TP": {"CellsPerColumn": 0}}`,
+		//dimensions derived by the pipeline
+		`{"Fields": [{"Name": "x", "Type": "category", "Params": {"Width": 3, "Categories": ["a"]}}],
+			"SP": {"InputDimensions": [6]}}`,
+		`{"Fields": [{"Name": "x", "Type": "category", "Params": {"Width": 3, "Categories": ["a"]}}],
+			"TM": {"ColumnDimensions": [2048]}}`,
+		`{"Fields": [{"Name": "x", "Type": "category", "Params": {"Width": 3, "Categories": ["a"]}}],
+			"Temporal": "tp", "TP": {"NumberOfCols": 2048}}`,
+	}
+
+	for _, data := range invalid {
+		_, err := ParseJSONConfig([]byte(data))
+		assert.NotNil(t, err, data)
+	}
+
+	_, err := ParseJSONConfig([]byte(`{"Fields": [{"Name": "x", "Type": "image"}]}`))
+	assert.Equal(t, `invalid config: field x: unknown field type "image"`, err.Error())
+
+	_, err = ParseJSONConfig([]byte(`{"Fields": [{"Name": "x", "Type": "category",
+		"Params": {"Width": 3, "Categories": ["a"]}}], "TM": {"ColumnDimensions": [2048]}}`))
+	assert.Equal(t, "invalid config: TM.ColumnDimensions is derived from SP.ColumnDimensions and can't be set",
+		err.Error())
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "htmconfig")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	jsonPath := filepath.Join(dir, "model.json")
+	yamlPath := filepath.Join(dir, "model.yml")
+	assert.Nil(t, ioutil.WriteFile(jsonPath, []byte(testJSONConfig), 0644))
+	assert.Nil(t, ioutil.WriteFile(yamlPath, []byte(testYAMLConfig), 0644))
+
+	cfg, err := LoadConfig(jsonPath)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(cfg.Fields))
+	cfg, err = LoadConfig(yamlPath)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(cfg.Fields))
+
+	_, err = LoadConfig(filepath.Join(dir, "missing.json"))
+	assert.NotNil(t, err)
+}
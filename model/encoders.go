@@ -0,0 +1,194 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/nupic-community/htm/encoders"
+	"strconv"
+	"time"
+)
+
+//Default layout of date fields, see time.Parse
+const DefaultDateFormat = "2006-01-02 15:04:05"
+
+/*
+ Encodes a single field value into a slice of the encoders width. Values
+are either strings, as read from a CSV file, or the fields native type.
+*/
+type fieldEncoder interface {
+	width() int
+	encode(value interface{}, output []bool) error
+}
+
+//Field encoder whose values can be classified
+type bucketEncoder interface {
+	fieldEncoder
+	//Returns the bucket of a value along with its numeric value
+	bucket(value interface{}) (int, float64, error)
+}
+
+type scalarField struct {
+	enc *encoders.ScalerEncoder
+}
+
+func (f *scalarField) width() int {
+	return f.enc.N
+}
+
+func (f *scalarField) value(value interface{}) (float64, error) {
+	return floatValue(value)
+}
+
+//Converts a native or string numeric value
+func floatValue(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("unsupported scalar value %v", value)
+}
+
+func (f *scalarField) encode(value interface{}, output []bool) error {
+	val, err := f.value(value)
+	if err != nil {
+		return err
+	}
+	f.enc.EncodeToSlice(val, false, output)
+	return nil
+}
+
+func (f *scalarField) bucket(value interface{}) (int, float64, error) {
+	val, err := f.value(value)
+	if err != nil {
+		return 0, 0, err
+	}
+	return f.enc.BucketIndex(val), val, nil
+}
+
+type dateField struct {
+	enc    *encoders.DateEncoder
+	format string
+}
+
+func (f *dateField) width() int {
+	return f.enc.Width()
+}
+
+func (f *dateField) encode(value interface{}, output []bool) error {
+	var date time.Time
+	switch v := value.(type) {
+	case time.Time:
+		date = v
+	case string:
+		var err error
+		if date, err = time.Parse(f.format, v); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported date value %v", value)
+	}
+	f.enc.EncodeToSlice(date, output)
+	return nil
+}
+
+type categoryField struct {
+	enc *encoders.CategoryEncoder
+}
+
+func (f *categoryField) width() int {
+	return f.enc.N
+}
+
+func (f *categoryField) value(value interface{}) (string, error) {
+	if v, ok := value.(string); ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("unsupported category value %v", value)
+}
+
+func (f *categoryField) encode(value interface{}, output []bool) error {
+	val, err := f.value(value)
+	if err != nil {
+		return err
+	}
+	f.enc.EncodeToSlice(val, output)
+	return nil
+}
+
+//Category buckets are the category indices, which are also used as the
+//numeric value
+func (f *categoryField) bucket(value interface{}) (int, float64, error) {
+	val, err := f.value(value)
+	if err != nil {
+		return 0, 0, err
+	}
+	idx := f.enc.CategoryIndex(val)
+	return idx, float64(idx), nil
+}
+
+//Decodes params strictly on top of defaults
+func decodeParams(data json.RawMessage, params interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(params)
+}
+
+/*
+ Creates the encoder described by a field config. Encoder constructors
+panic on invalid params, those are returned as errors.
+*/
+func newFieldEncoder(field FieldConfig) (result fieldEncoder, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+
+	switch field.Type {
+	case "scalar":
+		p := encoders.NewScalerEncoderParams(0, 0, 0)
+		if err := decodeParams(field.Params, p); err != nil {
+			return nil, err
+		}
+		if len(p.Name) == 0 {
+			p.Name = field.Name
+		}
+		return &scalarField{encoders.NewScalerEncoder(p)}, nil
+	case "date":
+		p := encoders.NewDateEncoderParams()
+		if err := decodeParams(field.Params, p); err != nil {
+			return nil, err
+		}
+		if len(p.Name) == 0 {
+			p.Name = field.Name
+		}
+		format := field.Format
+		if len(format) == 0 {
+			format = DefaultDateFormat
+		}
+		return &dateField{encoders.NewDateEncoder(p), format}, nil
+	case "category":
+		p := encoders.NewCategoryEncoderParams(0, nil)
+		if err := decodeParams(field.Params, p); err != nil {
+			return nil, err
+		}
+		if len(p.Name) == 0 {
+			p.Name = field.Name
+		}
+		return &categoryField{encoders.NewCategoryEncoder(p)}, nil
+	}
+
+	return nil, fmt.Errorf("unknown field type %q", field.Type)
+}
@@ -0,0 +1,329 @@
+package model
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Value types of a NuPIC data file field
+type FieldType string
+
+const (
+	FloatField    FieldType = "float"
+	IntField      FieldType = "int"
+	StringField   FieldType = "string"
+	DatetimeField FieldType = "datetime"
+	BoolField     FieldType = "bool"
+)
+
+//Special meaning of a NuPIC data file field
+type FieldFlag string
+
+const (
+	NoFlag FieldFlag = ""
+	//Timestamp of the record
+	TimestampFlag FieldFlag = "T"
+	//A true value starts a new sequence
+	ResetFlag FieldFlag = "R"
+	//Sequence id, a new sequence starts whenever it changes
+	SequenceFlag FieldFlag = "S"
+)
+
+//Layouts tried when parsing datetime values, NuPIC files may hold
+//fractional seconds which time.Parse accepts without a layout element
+var fileDateFormats = []string{
+	DefaultDateFormat,
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+//Describes a single column of a NuPIC data file
+type FileField struct {
+	Name string
+	Type FieldType
+	Flag FieldFlag
+}
+
+//Parses a single value of the fields type, empty values are not allowed
+func (f FileField) parse(value string) (interface{}, error) {
+	switch f.Type {
+	case FloatField:
+		return strconv.ParseFloat(value, 64)
+	case IntField:
+		return strconv.Atoi(value)
+	case StringField:
+		return value, nil
+	case DatetimeField:
+		for _, format := range fileDateFormats {
+			if date, err := time.Parse(format, value); err == nil {
+				return date, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid datetime %q", value)
+	case BoolField:
+		return strconv.ParseBool(value)
+	}
+	return nil, fmt.Errorf("unknown field type %q", f.Type)
+}
+
+//Formats a native or string value of the fields type
+func (f FileField) format(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case time.Time:
+		return v.Format(DefaultDateFormat + ".999999"), nil
+	}
+	return "", fmt.Errorf("unsupported %v value %v", f.Type, value)
+}
+
+//Checks field types and flags, at most one field may carry each flag
+func validateFileFields(fields []FileField) error {
+	flags := make(map[FieldFlag]bool)
+	for _, field := range fields {
+		switch field.Type {
+		case FloatField, IntField, StringField, DatetimeField, BoolField:
+		default:
+			return fmt.Errorf("field %v: unknown type %q", field.Name, field.Type)
+		}
+		switch field.Flag {
+		case NoFlag:
+			continue
+		case TimestampFlag, ResetFlag, SequenceFlag:
+		default:
+			return fmt.Errorf("field %v: unknown flag %q", field.Name, field.Flag)
+		}
+		if flags[field.Flag] {
+			return fmt.Errorf("more than one field flagged %v", field.Flag)
+		}
+		flags[field.Flag] = true
+	}
+	return nil
+}
+
+/*
+ Reads NuPIC data files. The file starts with three header rows holding
+the field names, the field types (float, int, string, datetime or bool)
+and the field flags (T timestamp, R reset, S sequence id, empty for none).
+Records hold the values in their native types, float64, int, string,
+time.Time or bool. Empty values are left out of the record.
+*/
+type FileReader struct {
+	reader *csv.Reader
+	fields []FileField
+	//Rows read including the headers
+	row      int
+	sequence string
+	started  bool
+}
+
+//Creates a reader and reads the three header rows
+func NewFileReader(in io.Reader) (*FileReader, error) {
+	r := &FileReader{reader: csv.NewReader(in)}
+	r.reader.FieldsPerRecord = -1
+
+	var headers [3][]string
+	for idx := range headers {
+		row, err := r.reader.Read()
+		if err == io.EOF {
+			return nil, fmt.Errorf("expected 3 header rows, found %v", idx)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("header row %v: %v", idx+1, err)
+		}
+		headers[idx] = row
+		r.row++
+	}
+
+	names, types, flags := headers[0], headers[1], headers[2]
+	if len(types) != len(names) {
+		return nil, fmt.Errorf("found %v field types for %v fields", len(types), len(names))
+	}
+	// Files without flags may hold a single empty cell
+	if len(flags) == 1 && len(names) > 1 && len(strings.TrimSpace(flags[0])) == 0 {
+		flags = make([]string, len(names))
+	}
+	if len(flags) != len(names) {
+		return nil, fmt.Errorf("found %v field flags for %v fields", len(flags), len(names))
+	}
+
+	for idx, name := range names {
+		r.fields = append(r.fields, FileField{
+			Name: strings.TrimSpace(name),
+			Type: FieldType(strings.ToLower(strings.TrimSpace(types[idx]))),
+			Flag: FieldFlag(strings.ToUpper(strings.TrimSpace(flags[idx]))),
+		})
+	}
+	if err := validateFileFields(r.fields); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+//Returns the fields of the file
+func (r *FileReader) Fields() []FileField {
+	return append([]FileField(nil), r.fields...)
+}
+
+/*
+ Reads the next record. Reset reports whether the record starts a new
+sequence, either because its reset field is true or because its sequence
+id differs from the previous records. Returns io.EOF after the last
+record.
+*/
+func (r *FileReader) Next() (record Record, reset bool, err error) {
+	row, err := r.reader.Read()
+	if err != nil {
+		if err != io.EOF {
+			err = fmt.Errorf("row %v: %v", r.row+1, err)
+		}
+		return nil, false, err
+	}
+	r.row++
+
+	if len(row) != len(r.fields) {
+		return nil, false, fmt.Errorf("row %v: found %v values for %v fields", r.row, len(row), len(r.fields))
+	}
+
+	record = make(Record, len(r.fields))
+	for idx, field := range r.fields {
+		raw := strings.TrimSpace(row[idx])
+		if field.Flag == SequenceFlag {
+			if r.started && raw != r.sequence {
+				reset = true
+			}
+			r.sequence = raw
+		}
+		if len(raw) == 0 {
+			continue
+		}
+
+		value, err := field.parse(raw)
+		if err != nil {
+			return nil, false, fmt.Errorf("row %v: field %v: %v", r.row, field.Name, err)
+		}
+		record[field.Name] = value
+
+		if field.Flag == ResetFlag {
+			switch v := value.(type) {
+			case bool:
+				reset = reset || v
+			case int:
+				reset = reset || v != 0
+			case float64:
+				reset = reset || v != 0
+			default:
+				return nil, false, fmt.Errorf("row %v: reset field %v is not numeric", r.row, field.Name)
+			}
+		}
+	}
+	r.started = true
+
+	return record, reset, nil
+}
+
+/*
+ Writes NuPIC data files, the three header rows are written on creation.
+Values may be strings or native values, missing values are left empty.
+*/
+type FileWriter struct {
+	writer *csv.Writer
+	fields []FileField
+}
+
+//Creates a writer and writes the header rows
+func NewFileWriter(out io.Writer, fields []FileField) (*FileWriter, error) {
+	if err := validateFileFields(fields); err != nil {
+		return nil, err
+	}
+
+	w := &FileWriter{writer: csv.NewWriter(out)}
+	w.fields = append(w.fields, fields...)
+
+	names := make([]string, len(fields))
+	types := make([]string, len(fields))
+	flags := make([]string, len(fields))
+	for idx, field := range fields {
+		names[idx] = field.Name
+		types[idx] = string(field.Type)
+		flags[idx] = string(field.Flag)
+	}
+	for _, row := range [][]string{names, types, flags} {
+		if err := w.writer.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+//Writes a single record
+func (w *FileWriter) Write(record Record) error {
+	row := make([]string, len(w.fields))
+	for idx, field := range w.fields {
+		value, err := field.format(record[field.Name])
+		if err != nil {
+			return fmt.Errorf("field %v: %v", field.Name, err)
+		}
+		row[idx] = value
+	}
+	return w.writer.Write(row)
+}
+
+//Writes any buffered records to the underlying writer
+func (w *FileWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+/*
+ Runs every record of a NuPIC data file through a pipeline, the pipeline
+is reset whenever a record starts a new sequence. fn, if not nil, is
+called with each record and its result, an error returned by fn stops the
+replay.
+*/
+func Replay(p *Pipeline, reader *FileReader, learn bool, fn func(record Record, result Result) error) error {
+	for {
+		record, reset, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if reset {
+			p.Reset()
+		}
+
+		result, err := p.Compute(record, learn)
+		if err != nil {
+			return fmt.Errorf("row %v: %v", reader.row, err)
+		}
+		if fn != nil {
+			if err := fn(record, result); err != nil {
+				return err
+			}
+		}
+	}
+}
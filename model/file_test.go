@@ -0,0 +1,187 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testFile = `timestamp,consumption,kind,reset,sequence
+datetime,float,string,int,string
+T,,,R,S
+2015-06-01 00:00:00,1.5,gas,0,a
+2015-06-01 01:00:00.250000,2,power,0,a
+2015-06-01 02:00:00,,gas,1,a
+2015-06-01 03:00:00,4,gas,0,b
+2015-06-01 04:00:00,5,gas,0,b
+`
+
+func TestFileReader(t *testing.T) {
+	r, err := NewFileReader(strings.NewReader(testFile))
+	assert.Nil(t, err)
+	assert.Equal(t, []FileField{
+		{"timestamp", DatetimeField, TimestampFlag},
+		{"consumption", FloatField, NoFlag},
+		{"kind", StringField, NoFlag},
+		{"reset", IntField, ResetFlag},
+		{"sequence", StringField, SequenceFlag},
+	}, r.Fields())
+
+	record, reset, err := r.Next()
+	assert.Nil(t, err)
+	assert.False(t, reset)
+	assert.Equal(t, Record{
+		"timestamp":   time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC),
+		"consumption": 1.5,
+		"kind":        "gas",
+		"reset":       0,
+		"sequence":    "a",
+	}, record)
+
+	record, reset, err = r.Next()
+	assert.Nil(t, err)
+	assert.False(t, reset)
+	assert.Equal(t, time.Date(2015, 6, 1, 1, 0, 0, 250000000, time.UTC), record["timestamp"])
+
+	//reset flag set, empty values are left out
+	record, reset, err = r.Next()
+	assert.Nil(t, err)
+	assert.True(t, reset)
+	_, ok := record["consumption"]
+	assert.False(t, ok)
+
+	//sequence id changed
+	_, reset, err = r.Next()
+	assert.Nil(t, err)
+	assert.True(t, reset)
+
+	_, reset, err = r.Next()
+	assert.Nil(t, err)
+	assert.False(t, reset)
+
+	_, _, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestFileReaderErrors(t *testing.T) {
+	cases := map[string]string{
+		"a,b\nfloat,float\n":              "expected 3 header rows, found 2",
+		"a,b\nfloat\n,\n":                 "found 1 field types for 2 fields",
+		"a,b\nfloat,float\nT,R,S\n":       "found 3 field flags for 2 fields",
+		"a,b\nfloat,double\n,\n":          `field b: unknown type "double"`,
+		"a,b\nfloat,float\nX,\n":          `field a: unknown flag "X"`,
+		"a,b\ndatetime,datetime\nT,T\n":   "more than one field flagged T",
+		"a,b\nfloat,int\n,\n1,2.5\n":      "row 4: field b: strconv.Atoi: parsing \"2.5\": invalid syntax",
+		"a,b\nfloat,float\n,\n1\n":        "row 4: found 1 values for 2 fields",
+		"a,b\ndatetime,float\n,\nnow,1\n": `row 4: field a: invalid datetime "now"`,
+	}
+
+	for input, expected := range cases {
+		r, err := NewFileReader(strings.NewReader(input))
+		if err == nil {
+			_, _, err = r.Next()
+		}
+		if assert.NotNil(t, err, input) {
+			assert.Equal(t, expected, err.Error(), input)
+		}
+	}
+
+	//flags row may be a single empty cell
+	r, err := NewFileReader(strings.NewReader("a,b\nfloat,float\n\"\"\n1,2\n"))
+	assert.Nil(t, err)
+	record, _, err := r.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, Record{"a": 1.0, "b": 2.0}, record)
+}
+
+func TestFileWriter(t *testing.T) {
+	fields := []FileField{
+		{"timestamp", DatetimeField, TimestampFlag},
+		{"consumption", FloatField, NoFlag},
+		{"kind", StringField, NoFlag},
+		{"reset", IntField, ResetFlag},
+		{"sequence", StringField, SequenceFlag},
+	}
+
+	//records read back are written unchanged
+	r, err := NewFileReader(strings.NewReader(testFile))
+	assert.Nil(t, err)
+	var out bytes.Buffer
+	w, err := NewFileWriter(&out, fields)
+	assert.Nil(t, err)
+	for {
+		record, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		assert.Nil(t, w.Write(record))
+	}
+	assert.Nil(t, w.Flush())
+	assert.Equal(t, strings.Replace(testFile, "01:00:00.250000", "01:00:00.25", 1), out.String())
+
+	//strings are written as is
+	out.Reset()
+	w, err = NewFileWriter(&out, fields[1:3])
+	assert.Nil(t, err)
+	assert.Nil(t, w.Write(Record{"consumption": "3.0", "kind": "gas"}))
+	assert.NotNil(t, w.Write(Record{"consumption": []int{1}}))
+	assert.Nil(t, w.Flush())
+	assert.Equal(t, "consumption,kind\nfloat,string\n,\n3.0,gas\n", out.String())
+
+	_, err = NewFileWriter(&out, []FileField{{"a", "double", NoFlag}})
+	assert.NotNil(t, err)
+}
+
+func TestReplay(t *testing.T) {
+	cfg, err := ParseJSONConfig([]byte(testPredictionConfig))
+	assert.Nil(t, err)
+
+	//repeating sequence of 4 records, every sequence starts with a reset
+	var in bytes.Buffer
+	in.WriteString("value,reset\nfloat,int\n,R\n")
+	for idx := 0; idx < 200; idx++ {
+		reset := 0
+		if idx%4 == 0 {
+			reset = 1
+		}
+		in.WriteString(fmt.Sprintf("%v,%v\n", (idx%4)*25, reset))
+	}
+
+	r, err := NewFileReader(&in)
+	assert.Nil(t, err)
+	p, err := NewPipeline(cfg)
+	assert.Nil(t, err)
+
+	var results []Result
+	err = Replay(p, r, true, func(record Record, result Result) error {
+		results = append(results, result)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 200, len(results))
+
+	//the first record of a sequence is never predicted, the rest are
+	for _, result := range results[len(results)-4:] {
+		if result.ActualValue == 0 {
+			assert.Equal(t, 1.0, result.AnomalyScore)
+		} else {
+			assert.Equal(t, 0.0, result.AnomalyScore)
+		}
+	}
+
+	//errors of the callback stop the replay
+	r, err = NewFileReader(strings.NewReader("value\nfloat\n\"\"\n1\n2\n"))
+	assert.Nil(t, err)
+	count := 0
+	err = Replay(p, r, false, func(record Record, result Result) error {
+		count++
+		return fmt.Errorf("stop")
+	})
+	assert.Equal(t, "stop", err.Error())
+	assert.Equal(t, 1, count)
+}
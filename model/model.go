@@ -0,0 +1,212 @@
+package model
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"github.com/nupic-community/htm"
+	"github.com/nupic-community/htm/utils"
+	"io"
+)
+
+//Version of the format written by Model.Save
+const modelFormatVersion = 1
+
+//Inferences made from a single record
+type Inferences struct {
+	//Number of the record, counting from 0
+	RecordNum     int
+	ActiveColumns []int
+	//Columns predicted for the next record
+	PredictedColumns []int
+	//Raw anomaly score of the active columns against the previous prediction
+	AnomalyScore float64
+	//Most likely value of the predicted field keyed by the number of steps
+	//ahead, empty if no predicted field is configured
+	BestPredictions map[int]float64
+	//Probability of each predicted field value keyed by the number of steps
+	//ahead
+	Predictions map[int]map[float64]float64
+}
+
+/*
+ Model runs records through the pipeline described by a config and returns
+its inferences, without exposing the algorithms. Learning is enabled when
+a model is created.
+
+	m, err := model.NewModel(cfg)
+	inferences, err := m.Run(model.Record{"timestamp": ts, "consumption": 5.3})
+	fmt.Println(inferences.BestPredictions[1], inferences.AnomalyScore)
+*/
+type Model struct {
+	cfg      *Config
+	pipeline *Pipeline
+	learning bool
+}
+
+//Creates a model from a config, the config is validated first
+func NewModel(cfg *Config) (*Model, error) {
+	pipeline, err := NewPipeline(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Model{cfg: cfg, pipeline: pipeline, learning: true}, nil
+}
+
+//Returns the names of the fields a record must hold
+func (m *Model) FieldNames() []string {
+	return m.pipeline.FieldNames()
+}
+
+//Runs a single record through the model, learning from it if enabled
+func (m *Model) Run(record Record) (Inferences, error) {
+	inferences := Inferences{RecordNum: m.pipeline.recordNum}
+
+	result, err := m.pipeline.Compute(record, m.learning)
+	if err != nil {
+		return inferences, err
+	}
+
+	inferences.ActiveColumns = result.ActiveColumns
+	inferences.PredictedColumns = result.PredictedColumns
+	inferences.AnomalyScore = result.AnomalyScore
+
+	if m.pipeline.Classifier != nil {
+		inferences.BestPredictions = make(map[int]float64)
+		inferences.Predictions = make(map[int]map[float64]float64)
+		for step, probabilities := range result.Classification.Probabilities {
+			if best, ok := result.Classification.BestPrediction(step); ok {
+				inferences.BestPredictions[step] = best
+			}
+			// Buckets may share a value
+			values := make(map[float64]float64)
+			for bucket, probability := range probabilities {
+				values[result.Classification.ActualValues[bucket]] += probability
+			}
+			inferences.Predictions[step] = values
+		}
+	}
+
+	return inferences, nil
+}
+
+//Enables learning, records passed to Run are learned from
+func (m *Model) EnableLearning() {
+	m.learning = true
+}
+
+//Disables learning, the model only infers
+func (m *Model) DisableLearning() {
+	m.learning = false
+}
+
+//Returns true if learning is enabled
+func (m *Model) IsLearning() bool {
+	return m.learning
+}
+
+//Starts a new sequence, the next record is not predicted from the last one
+func (m *Model) ResetSequenceStates() {
+	m.pipeline.Reset()
+}
+
+//Attaches an observer to the algorithms of the model, nil detaches it
+func (m *Model) SetObserver(observer htm.Observer) {
+	m.pipeline.SP.SetObserver(observer)
+	if m.pipeline.TM != nil {
+		m.pipeline.TM.SetObserver(observer)
+	}
+	if m.pipeline.TP != nil {
+		m.pipeline.TP.SetObserver(observer)
+	}
+}
+
+//Attaches a logger to the algorithms of the model, nil detaches it
+func (m *Model) SetLogger(logger utils.Logger) {
+	m.pipeline.SP.SetLogger(logger)
+	if m.pipeline.TP != nil {
+		m.pipeline.TP.SetLogger(logger)
+	}
+}
+
+//Saved form of a model
+type modelState struct {
+	Version       int
+	Config        []byte
+	Learning      bool
+	RecordNum     int
+	PrevPredicted []int
+	SP            *htm.SpatialPooler
+	TM            *htm.TemporalMemory
+	TP            *htm.TemporalPooler
+	Classifier    *htm.SDRClassifier
+}
+
+/*
+ Writes the config and learned state of the model. Loggers, observers and
+SP topologies are not saved.
+*/
+func (m *Model) Save(w io.Writer) error {
+	// Fields that can't be serialized are left out
+	cfg := *m.cfg
+	cfg.SP.Logger, cfg.SP.Observer, cfg.SP.Topology = nil, nil, nil
+	cfg.TM.Observer = nil
+	cfg.TP.Logger, cfg.TP.Observer = nil, nil
+	data, err := json.Marshal(&cfg)
+	if err != nil {
+		return err
+	}
+
+	return gob.NewEncoder(w).Encode(modelState{
+		Version:       modelFormatVersion,
+		Config:        data,
+		Learning:      m.learning,
+		RecordNum:     m.pipeline.recordNum,
+		PrevPredicted: m.pipeline.prevPredicted,
+		SP:            m.pipeline.SP,
+		TM:            m.pipeline.TM,
+		TP:            m.pipeline.TP,
+		Classifier:    m.pipeline.Classifier,
+	})
+}
+
+/*
+ Reads a model written by Save, it continues where the saved model left off.
+Loggers and observers are not restored, use SetLogger and SetObserver to
+attach them.
+*/
+func Load(r io.Reader) (*Model, error) {
+	var state modelState
+	if err := gob.NewDecoder(r).Decode(&state); err != nil {
+		return nil, err
+	}
+	if state.Version != modelFormatVersion {
+		return nil, fmt.Errorf("unsupported model format version %v", state.Version)
+	}
+
+	cfg, err := ParseJSONConfig(state.Config)
+	if err != nil {
+		return nil, err
+	}
+	// The saved algorithms replace the ones a new model would create
+	p, err := newPipelineShell(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if state.SP == nil || state.SP.NumInputs() != len(p.input) ||
+		(state.TM != nil) != (cfg.Temporal == TemporalMemory) ||
+		(state.TP != nil) != (cfg.Temporal == TemporalPooler) ||
+		(state.Classifier != nil) != (p.predicted != -1) {
+		return nil, fmt.Errorf("saved model state does not match its config")
+	}
+
+	p.SP = state.SP
+	p.TM = state.TM
+	p.TP = state.TP
+	p.Classifier = state.Classifier
+	p.activeColumns = make([]bool, p.SP.NumColumns())
+	p.recordNum = state.RecordNum
+	p.prevPredicted = state.PrevPredicted
+
+	return &Model{cfg: cfg, pipeline: p, learning: state.Learning}, nil
+}
@@ -0,0 +1,217 @@
+package model
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"github.com/nupic-community/htm"
+	"github.com/nupic-community/htm/utils"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type countingObserver struct {
+	htm.NopObserver
+	sp, tp, tm int
+}
+
+func (o *countingObserver) SpatialPoolerStep(event htm.SpStepEvent) {
+	o.sp++
+}
+
+func (o *countingObserver) TemporalPoolerStep(event htm.TpStepEvent) {
+	o.tp++
+}
+
+func (o *countingObserver) TemporalMemoryStep(event htm.TmStepEvent) {
+	o.tm++
+}
+
+func trainedModel(t *testing.T) *Model {
+	cfg, err := ParseJSONConfig([]byte(testPredictionConfig))
+	assert.Nil(t, err)
+	m, err := NewModel(cfg)
+	assert.Nil(t, err)
+	assert.True(t, m.IsLearning())
+
+	for idx := 0; idx < 200; idx++ {
+		inferences, err := m.Run(testRecord(idx))
+		assert.Nil(t, err)
+		assert.Equal(t, idx, inferences.RecordNum)
+	}
+	return m
+}
+
+func TestModelRun(t *testing.T) {
+	m := trainedModel(t)
+	assert.Equal(t, []string{"value"}, m.FieldNames())
+
+	//the sequence continues with 0, 25 and 50
+	inferences, err := m.Run(testRecord(200))
+	assert.Nil(t, err)
+	assert.Equal(t, 200, inferences.RecordNum)
+	assert.Equal(t, 20, len(inferences.ActiveColumns))
+	assert.True(t, len(inferences.PredictedColumns) > 0)
+	assert.Equal(t, 0.0, inferences.AnomalyScore)
+	assert.Equal(t, map[int]float64{1: 25, 2: 50}, inferences.BestPredictions)
+	assert.True(t, inferences.Predictions[1][25] > 0.5)
+	total := 0.0
+	for _, probability := range inferences.Predictions[2] {
+		total += probability
+	}
+	assert.InDelta(t, 1.0, total, 1e-9)
+
+	_, err = m.Run(Record{})
+	assert.Equal(t, "missing field value", err.Error())
+}
+
+func TestModelLearning(t *testing.T) {
+	m := trainedModel(t)
+	learnNum := m.pipeline.SP.IterationLearnNum
+
+	m.DisableLearning()
+	assert.False(t, m.IsLearning())
+	_, err := m.Run(testRecord(200))
+	assert.Nil(t, err)
+	assert.Equal(t, learnNum, m.pipeline.SP.IterationLearnNum)
+
+	m.EnableLearning()
+	_, err = m.Run(testRecord(201))
+	assert.Nil(t, err)
+	assert.Equal(t, learnNum+1, m.pipeline.SP.IterationLearnNum)
+
+	//a new sequence is never predicted
+	m.ResetSequenceStates()
+	inferences, err := m.Run(testRecord(202))
+	assert.Nil(t, err)
+	assert.Equal(t, 1.0, inferences.AnomalyScore)
+}
+
+func TestModelSaveLoad(t *testing.T) {
+	m := trainedModel(t)
+	m.DisableLearning()
+
+	var buf bytes.Buffer
+	assert.Nil(t, m.Save(&buf))
+	loaded, err := Load(&buf)
+	assert.Nil(t, err)
+	assert.False(t, loaded.IsLearning())
+	assert.Equal(t, m.FieldNames(), loaded.FieldNames())
+
+	//the loaded model continues where the saved one left off
+	for idx := 200; idx < 208; idx++ {
+		expected, err := m.Run(testRecord(idx))
+		assert.Nil(t, err)
+		actual, err := loaded.Run(testRecord(idx))
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	//and keeps learning like it
+	m.EnableLearning()
+	loaded.EnableLearning()
+	for idx := 208; idx < 220; idx++ {
+		expected, err := m.Run(testRecord(idx))
+		assert.Nil(t, err)
+		actual, err := loaded.Run(testRecord(idx))
+		assert.Nil(t, err)
+		assert.Equal(t, expected.AnomalyScore, actual.AnomalyScore)
+	}
+	assert.Equal(t, m.pipeline.SP.IterationLearnNum, loaded.pipeline.SP.IterationLearnNum)
+}
+
+func TestModelSaveLoadTemporalPooler(t *testing.T) {
+	cfg, err := ParseJSONConfig([]byte(testJSONConfig))
+	assert.Nil(t, err)
+	cfg.Temporal = TemporalPooler
+	cfg.TP.CellsPerColumn = 4
+	cfg.TP.ActivationThreshold = 6
+	cfg.TP.MinThreshold = 4
+	cfg.TP.NewSynapseCount = 10
+	m, err := NewModel(cfg)
+	assert.Nil(t, err)
+	for idx := 0; idx < 100; idx++ {
+		_, err := m.Run(testRecord(idx))
+		assert.Nil(t, err)
+	}
+	m.DisableLearning()
+
+	var buf bytes.Buffer
+	assert.Nil(t, m.Save(&buf))
+	loaded, err := Load(&buf)
+	assert.Nil(t, err)
+	assert.NotNil(t, loaded.pipeline.TP)
+	assert.Nil(t, loaded.pipeline.TM)
+
+	for idx := 100; idx < 108; idx++ {
+		expected, err := m.Run(testRecord(idx))
+		assert.Nil(t, err)
+		actual, err := loaded.Run(testRecord(idx))
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func TestModelLoadObserverAndLogger(t *testing.T) {
+	m := trainedModel(t)
+	var buf bytes.Buffer
+	assert.Nil(t, m.Save(&buf))
+	loaded, err := Load(&buf)
+	assert.Nil(t, err)
+
+	observer := new(countingObserver)
+	loaded.SetObserver(observer)
+	_, err = loaded.Run(testRecord(200))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, observer.sp)
+	assert.Equal(t, 1, observer.tm)
+
+	loaded.SetObserver(nil)
+	_, err = loaded.Run(testRecord(201))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, observer.sp)
+
+	//temporal pooler models log debug messages at its verbosity
+	cfg, err := ParseJSONConfig([]byte(testJSONConfig))
+	assert.Nil(t, err)
+	cfg.Temporal = TemporalPooler
+	cfg.TP.Verbosity = 3
+	m, err = NewModel(cfg)
+	assert.Nil(t, err)
+	buf.Reset()
+	assert.Nil(t, m.Save(&buf))
+	loaded, err = Load(&buf)
+	assert.Nil(t, err)
+
+	var log bytes.Buffer
+	loaded.SetLogger(utils.NewWriterLogger(&log, utils.Debug))
+	loaded.SetObserver(observer)
+	_, err = loaded.Run(testRecord(0))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, observer.tp)
+	assert.True(t, strings.Contains(log.String(), "component=tp"))
+}
+
+func TestModelSaveErrors(t *testing.T) {
+	_, err := Load(strings.NewReader("not a model"))
+	assert.NotNil(t, err)
+
+	//saved algorithms must match the config
+	m := trainedModel(t)
+	data, err := json.Marshal(m.cfg)
+	assert.Nil(t, err)
+	var buf bytes.Buffer
+	assert.Nil(t, gob.NewEncoder(&buf).Encode(modelState{Version: modelFormatVersion,
+		Config: data, SP: m.pipeline.SP, Classifier: m.pipeline.Classifier}))
+	_, err = Load(&buf)
+	assert.Equal(t, "saved model state does not match its config", err.Error())
+
+	buf.Reset()
+	assert.Nil(t, gob.NewEncoder(&buf).Encode(modelState{Version: 2}))
+	_, err = Load(&buf)
+	assert.Equal(t, "unsupported model format version 2", err.Error())
+
+	_, err = NewModel(NewConfig())
+	assert.NotNil(t, err)
+}
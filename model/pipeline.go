@@ -0,0 +1,196 @@
+package model
+
+import (
+	"fmt"
+	"github.com/nupic-community/htm"
+	"github.com/nupic-community/htm/utils"
+	"sort"
+)
+
+//Field values of a single record keyed by field name
+type Record map[string]interface{}
+
+//Output of a single record
+type Result struct {
+	ActiveColumns []int
+	//Columns predicted for the next record
+	PredictedColumns []int
+	ActiveCells      []int
+	//Raw anomaly score of the active columns against the previous prediction
+	AnomalyScore float64
+	//Predictions of the predicted field, empty if none is configured
+	Classification htm.ClassifierResult
+	//Numeric value of the predicted field in the record
+	ActualValue float64
+}
+
+/*
+ Runnable model built from a config. Records are encoded field by field,
+the encodings are concatenated and fed through the spatial pooler, the
+temporal memory or pooler and, when a predicted field is configured, the
+classifier.
+*/
+type Pipeline struct {
+	SP *htm.SpatialPooler
+	//Set when the config uses the temporal memory
+	TM *htm.TemporalMemory
+	//Set when the config uses the temporal pooler
+	TP *htm.TemporalPooler
+	//Set when the config has a predicted field
+	Classifier *htm.SDRClassifier
+
+	fields   []string
+	encoders []fieldEncoder
+	offsets  []int
+	//Index of the predicted field, -1 if none
+	predicted int
+
+	input         []bool
+	activeColumns []bool
+	prevPredicted []int
+	recordNum     int
+}
+
+//Builds a pipeline from a config, the config is validated first
+func NewPipeline(cfg *Config) (*Pipeline, error) {
+	p, err := newPipelineShell(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	spParams := cfg.SP
+	spParams.InputDimensions = []int{len(p.input)}
+	p.SP = htm.NewSpatialPooler(spParams)
+
+	switch cfg.Temporal {
+	case TemporalMemory:
+		tmParams := cfg.TM
+		tmParams.ColumnDimensions = spParams.ColumnDimensions
+		p.TM = htm.NewTemporalMemory(&tmParams)
+	case TemporalPooler:
+		tpParams := cfg.TP
+		tpParams.NumberOfCols = p.SP.NumColumns()
+		p.TP = htm.NewTemporalPooler(tpParams)
+	}
+
+	if p.predicted != -1 {
+		p.Classifier = htm.NewSDRClassifier(cfg.Classifier)
+	}
+
+	p.activeColumns = make([]bool, p.SP.NumColumns())
+
+	return p, nil
+}
+
+//Validates the config and sets up the encoders of a pipeline, the
+//algorithms are left for the caller to create or restore
+func newPipelineShell(cfg *Config) (*Pipeline, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := new(Pipeline)
+	p.predicted = -1
+
+	width := 0
+	for idx, field := range cfg.Fields {
+		enc, err := newFieldEncoder(field)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %v", field.Name, err)
+		}
+		if field.Name == cfg.PredictedField {
+			p.predicted = idx
+		}
+
+		p.fields = append(p.fields, field.Name)
+		p.encoders = append(p.encoders, enc)
+		p.offsets = append(p.offsets, width)
+		width += enc.width()
+	}
+	p.input = make([]bool, width)
+
+	return p, nil
+}
+
+//Returns the names of the fields a record must hold
+func (p *Pipeline) FieldNames() []string {
+	return append([]string(nil), p.fields...)
+}
+
+//Runs a single record through the model
+func (p *Pipeline) Compute(record Record, learn bool) (Result, error) {
+	var result Result
+
+	// Encoders only set their on bits
+	utils.FillSliceBool(p.input, false)
+	for idx, enc := range p.encoders {
+		value, ok := record[p.fields[idx]]
+		if !ok {
+			return result, fmt.Errorf("missing field %v", p.fields[idx])
+		}
+		output := p.input[p.offsets[idx] : p.offsets[idx]+enc.width()]
+		if err := enc.encode(value, output); err != nil {
+			return result, fmt.Errorf("field %v: %v", p.fields[idx], err)
+		}
+	}
+
+	bucketIdx, actValue := 0, 0.0
+	if p.predicted != -1 {
+		var err error
+		enc := p.encoders[p.predicted].(bucketEncoder)
+		bucketIdx, actValue, err = enc.bucket(record[p.fields[p.predicted]])
+		if err != nil {
+			return result, fmt.Errorf("field %v: %v", p.fields[p.predicted], err)
+		}
+	}
+
+	utils.FillSliceBool(p.activeColumns, false)
+	p.SP.Compute(p.input, learn, p.activeColumns, p.SP.InhibitColumns)
+	result.ActiveColumns = utils.OnIndices(p.activeColumns)
+	result.AnomalyScore = htm.ComputeRawAnomalyScore(result.ActiveColumns, p.prevPredicted)
+
+	if p.TM != nil {
+		p.TM.Compute(result.ActiveColumns, learn)
+		result.ActiveCells = append([]int(nil), p.TM.ActiveCells...)
+		sort.Ints(result.ActiveCells)
+		result.PredictedColumns = p.tmPredictedColumns()
+	} else {
+		output := p.TP.Compute(p.activeColumns, learn, true)
+		result.ActiveCells = utils.OnIndices(output)
+		result.PredictedColumns = p.TP.DynamicState.InfPredictedState.NonZeroRows()
+		sort.Ints(result.PredictedColumns)
+	}
+	p.prevPredicted = result.PredictedColumns
+
+	if p.Classifier != nil {
+		result.ActualValue = actValue
+		result.Classification = p.Classifier.Compute(p.recordNum, result.ActiveCells,
+			bucketIdx, actValue, learn, true)
+	}
+	p.recordNum++
+
+	return result, nil
+}
+
+//Returns the columns of the temporal memories predictive cells
+func (p *Pipeline) tmPredictedColumns() []int {
+	var result []int
+	for _, cell := range p.TM.PredictiveCells {
+		col := p.TM.Connections.ColumnForCell(cell)
+		if !utils.ContainsInt(col, result) {
+			result = append(result, col)
+		}
+	}
+	sort.Ints(result)
+	return result
+}
+
+//Starts a new sequence, the next record is not predicted from the last one
+func (p *Pipeline) Reset() {
+	if p.TM != nil {
+		p.TM.Reset()
+	} else {
+		p.TP.Reset()
+	}
+	p.prevPredicted = nil
+}
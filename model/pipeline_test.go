@@ -0,0 +1,119 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+//Model predicting a scalar from the value alone
+const testPredictionConfig = `{
+	"Fields": [
+		{"Name": "value", "Type": "scalar",
+		 "Params": {"Width": 21, "MinVal": 0, "MaxVal": 100, "N": 200, "ClipInput": true}}
+	],
+	"PredictedField": "value",
+	"SP": {"ColumnDimensions": [1024], "PotentialRadius": 500, "GlobalInhibition": true,
+		"NumActiveColumnsPerInhArea": 20, "Seed": 1},
+	"TM": {"CellsPerColumn": 4, "ActivationThreshold": 12, "MinThreshold": 8,
+		"MaxNewSynapseCount": 20, "InitialPermanence": 0.51},
+	"Classifier": {"Steps": [1, 2], "Alpha": 0.1}
+}`
+
+//Repeating sequence of 4 records
+func testRecord(idx int) Record {
+	return Record{
+		"timestamp": time.Date(2015, 6, 1, (idx%4)*6, 0, 0, 0, time.UTC),
+		"value":     float64((idx % 4) * 25),
+		"kind":      []string{"a", "b"}[idx%2],
+	}
+}
+
+func TestPipelineTemporalMemory(t *testing.T) {
+	cfg, err := ParseJSONConfig([]byte(testPredictionConfig))
+	assert.Nil(t, err)
+	p, err := NewPipeline(cfg)
+	assert.Nil(t, err)
+	assert.NotNil(t, p.TM)
+	assert.Nil(t, p.TP)
+	assert.NotNil(t, p.Classifier)
+	assert.Equal(t, []string{"value"}, p.FieldNames())
+
+	var result Result
+	for idx := 0; idx < 200; idx++ {
+		result, err = p.Compute(testRecord(idx), true)
+		assert.Nil(t, err)
+		assert.Equal(t, 20, len(result.ActiveColumns))
+	}
+	assert.Equal(t, 0.0, result.AnomalyScore)
+
+	//last record had value 75, the sequence continues with 0 and 25
+	val, ok := result.Classification.BestPrediction(1)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.0, val, 1e-9)
+	val, ok = result.Classification.BestPrediction(2)
+	assert.True(t, ok)
+	assert.InDelta(t, 25.0, val, 1e-9)
+	assert.True(t, len(result.ActiveCells) > 0)
+
+	//string values, as read from csv files, are accepted too
+	_, err = p.Compute(Record{"value": "0"}, false)
+	assert.Nil(t, err)
+
+	_, err = p.Compute(Record{}, false)
+	assert.Equal(t, "missing field value", err.Error())
+	_, err = p.Compute(Record{"value": "abc"}, false)
+	assert.NotNil(t, err)
+}
+
+func TestPipelineStringRecords(t *testing.T) {
+	cfg, err := ParseJSONConfig([]byte(testJSONConfig))
+	assert.Nil(t, err)
+	p, err := NewPipeline(cfg)
+	assert.Nil(t, err)
+
+	result, err := p.Compute(Record{"timestamp": "2015-06-01 00:00", "value": "0", "kind": "a"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, len(result.ActiveColumns))
+	assert.Equal(t, 1.0, result.AnomalyScore)
+
+	_, err = p.Compute(Record{"timestamp": "2015-06-01", "value": "0", "kind": "a"}, true)
+	assert.True(t, err != nil && err.Error()[:16] == "field timestamp:")
+}
+
+func TestPipelineTemporalPooler(t *testing.T) {
+	cfg, err := ParseJSONConfig([]byte(testJSONConfig))
+	assert.Nil(t, err)
+	cfg.Temporal = TemporalPooler
+	cfg.PredictedField = ""
+	cfg.TP.CellsPerColumn = 4
+	cfg.TP.ActivationThreshold = 6
+	cfg.TP.MinThreshold = 4
+	cfg.TP.NewSynapseCount = 10
+
+	p, err := NewPipeline(cfg)
+	assert.Nil(t, err)
+	assert.Nil(t, p.TM)
+	assert.NotNil(t, p.TP)
+	assert.Nil(t, p.Classifier)
+
+	var result Result
+	for idx := 0; idx < 100; idx++ {
+		result, err = p.Compute(testRecord(idx), true)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 10, len(result.ActiveColumns))
+	assert.True(t, len(result.ActiveCells) > 0)
+	assert.Nil(t, result.Classification.Probabilities)
+
+	p.Reset()
+	result, err = p.Compute(testRecord(0), false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1.0, result.AnomalyScore)
+}
+
+func TestNewPipelineInvalidConfig(t *testing.T) {
+	cfg := NewConfig()
+	_, err := NewPipeline(cfg)
+	assert.Equal(t, "invalid config: no fields specified", err.Error())
+}
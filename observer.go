@@ -0,0 +1,95 @@
+//
+// Per step telemetry for the spatial pooler, temporal pooler and temporal memory
+//
+
+package htm
+
+/*
+ Receives a summary of every compute step. Observers are called
+synchronously at the end of Compute and must not retain or modify the
+model, implementations should be cheap and hand the event off to whatever
+metrics system is in use. Embed NopObserver to only handle some components.
+*/
+type Observer interface {
+	SpatialPoolerStep(event SpStepEvent)
+	TemporalPoolerStep(event TpStepEvent)
+	TemporalMemoryStep(event TmStepEvent)
+}
+
+//Observer ignoring every event
+type NopObserver struct{}
+
+func (NopObserver) SpatialPoolerStep(event SpStepEvent)  {}
+func (NopObserver) TemporalPoolerStep(event TpStepEvent) {}
+func (NopObserver) TemporalMemoryStep(event TmStepEvent) {}
+
+//Replaces the observer notified after every compute, nil disables it
+func (sp *SpatialPooler) SetObserver(observer Observer) {
+	sp.observer = observer
+}
+
+//Replaces the observer notified after every compute, nil disables it
+func (tp *TemporalPooler) SetObserver(observer Observer) {
+	tp.params.Observer = observer
+}
+
+//Replaces the observer notified after every compute, nil disables it
+func (tm *TemporalMemory) SetObserver(observer Observer) {
+	tm.params.Observer = observer
+}
+
+//Summary of a spatial pooler compute step
+type SpStepEvent struct {
+	Iteration     int
+	Learn         bool
+	ActiveColumns int
+	//Raw overlap distribution over all columns
+	MinOverlap  int
+	MaxOverlap  int
+	MeanOverlap float64
+	//Boost factor range over all columns
+	MinBoost float64
+	MaxBoost float64
+}
+
+//Summary of a temporal pooler compute step, cell counts are taken from
+//the inference state when inference was computed, otherwise from the
+//learning state
+type TpStepEvent struct {
+	Iteration       int
+	Learn           bool
+	ActiveColumns   int
+	BurstingColumns int
+	ActiveCells     int
+	PredictedCells  int
+	//Structural changes made by learning during this step
+	SegmentsCreated   int
+	SegmentsDestroyed int
+	SynapsesCreated   int
+	SynapsesDestroyed int
+}
+
+//Summary of a temporal memory compute step
+type TmStepEvent struct {
+	Iteration       int
+	Learn           bool
+	ActiveColumns   int
+	BurstingColumns int
+	ActiveCells     int
+	PredictiveCells int
+	ActiveSegments  int
+	SegmentsCreated int
+	SynapsesCreated int
+}
+
+//Returns the number of columns whose cells are all active, state is
+//indexed by [column][cell]
+func countBurstingColumns(state BinaryMatrix) int {
+	result := 0
+	for _, count := range RowSums(state) {
+		if count == state.Cols() {
+			result++
+		}
+	}
+	return result
+}
@@ -0,0 +1,137 @@
+package htm
+
+import (
+	"github.com/nupic-community/htm/utils"
+	"github.com/zacg/testify/assert"
+	"testing"
+)
+
+type recordingObserver struct {
+	NopObserver
+	sp []SpStepEvent
+	tp []TpStepEvent
+	tm []TmStepEvent
+}
+
+func (r *recordingObserver) SpatialPoolerStep(event SpStepEvent) {
+	r.sp = append(r.sp, event)
+}
+
+func (r *recordingObserver) TemporalPoolerStep(event TpStepEvent) {
+	r.tp = append(r.tp, event)
+}
+
+func (r *recordingObserver) TemporalMemoryStep(event TmStepEvent) {
+	r.tm = append(r.tm, event)
+}
+
+func TestSpatialPoolerObserver(t *testing.T) {
+	observer := new(recordingObserver)
+	spParams := NewSpParams()
+	spParams.InputDimensions = []int{20}
+	spParams.ColumnDimensions = []int{16}
+	spParams.PotentialRadius = 20
+	spParams.GlobalInhibition = true
+	spParams.NumActiveColumnsPerInhArea = 2
+	spParams.Observer = observer
+	sp := NewSpatialPooler(spParams)
+
+	input := make([]bool, sp.NumInputs())
+	utils.FillSliceRangeBool(input, true, 0, 10)
+	active := make([]bool, sp.NumColumns())
+	sp.Compute(input, true, active, sp.InhibitColumns)
+	sp.Compute(input, false, active, sp.InhibitColumns)
+
+	assert.Equal(t, 2, len(observer.sp))
+	event := observer.sp[0]
+	assert.Equal(t, 1, event.Iteration)
+	assert.True(t, event.Learn)
+	assert.Equal(t, 2, event.ActiveColumns)
+	assert.True(t, event.MinOverlap <= event.MaxOverlap)
+	assert.True(t, event.MeanOverlap >= float64(event.MinOverlap))
+	assert.True(t, event.MeanOverlap <= float64(event.MaxOverlap))
+	assert.True(t, event.MinBoost >= 1)
+	assert.False(t, observer.sp[1].Learn)
+	assert.Equal(t, 2, observer.sp[1].Iteration)
+}
+
+func TestTemporalPoolerObserver(t *testing.T) {
+	observer := new(recordingObserver)
+	tps := NewTemporalPoolerParams()
+	tps.NumberOfCols = 50
+	tps.CellsPerColumn = 2
+	tps.ActivationThreshold = 8
+	tps.MinThreshold = 10
+	tps.InitialPerm = 0.5
+	tps.ConnectedPerm = 0.5
+	tps.NewSynapseCount = 10
+	tps.PermanenceDec = 0.0
+	tps.PermanenceInc = 0.1
+	tps.GlobalDecay = 0
+	tps.BurnIn = 1
+	tps.PamLength = 10
+	tps.Observer = observer
+	tp := NewTemporalPooler(*tps)
+
+	inputs := make([][]bool, 5)
+	for i := range inputs {
+		inputs[i] = boolRange(i*10, i*10+9, 50)
+	}
+	for i := 0; i < 10; i++ {
+		for p := 0; p < 5; p++ {
+			tp.Compute(inputs[p], true, false)
+		}
+		tp.Reset()
+	}
+	assert.Equal(t, 50, len(observer.tp))
+
+	segments, synapses := 0, 0
+	for _, event := range observer.tp {
+		assert.True(t, event.Learn)
+		assert.Equal(t, 10, event.ActiveColumns)
+		segments += event.SegmentsCreated - event.SegmentsDestroyed
+		synapses += event.SynapsesCreated - event.SynapsesDestroyed
+	}
+	stats := tp.CalcSegmentStats(false)
+	assert.Equal(t, stats.NumSegments, segments)
+	assert.Equal(t, stats.NumSynapses, synapses)
+
+	//a learned sequence is predicted, unexpected input bursts
+	tp.Compute(inputs[0], false, true)
+	tp.Compute(inputs[1], false, true)
+	event := observer.tp[len(observer.tp)-1]
+	assert.Equal(t, 51+1, event.Iteration)
+	assert.Equal(t, 0, event.BurstingColumns)
+	assert.Equal(t, 10, event.ActiveCells)
+	assert.Equal(t, 10, event.PredictedCells)
+	assert.Equal(t, 0, event.SegmentsCreated)
+
+	tp.Compute(boolRange(5, 14, 50), false, true)
+	assert.Equal(t, 10, observer.tp[len(observer.tp)-1].BurstingColumns)
+}
+
+func TestTemporalMemoryObserver(t *testing.T) {
+	observer := new(recordingObserver)
+	tmp := NewTemporalMemoryParams()
+	tmp.ColumnDimensions = []int{32}
+	tmp.CellsPerColumn = 4
+	tmp.ActivationThreshold = 3
+	tmp.MinThreshold = 2
+	tmp.InitialPermanence = 0.51
+	tmp.Observer = observer
+	tm := NewTemporalMemory(tmp)
+
+	tm.Compute([]int{0, 1, 2, 3}, true)
+	tm.Compute([]int{4, 5, 6, 7}, true)
+
+	assert.Equal(t, 2, len(observer.tm))
+	first, second := observer.tm[0], observer.tm[1]
+	assert.Equal(t, 1, first.Iteration)
+	assert.Equal(t, 4, first.ActiveColumns)
+	assert.Equal(t, 4, first.BurstingColumns)
+	assert.Equal(t, 16, first.ActiveCells)
+	assert.Equal(t, 2, second.Iteration)
+	assert.Equal(t, tm.Connections.NumberOfSegments(), first.SegmentsCreated+second.SegmentsCreated)
+	assert.Equal(t, tm.Connections.NumberOfSynapses(), first.SynapsesCreated+second.SynapsesCreated)
+	assert.True(t, second.SynapsesCreated > 0)
+}
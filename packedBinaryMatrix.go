@@ -0,0 +1,366 @@
+package htm
+
+import (
+	"math/bits"
+)
+
+const wordSize = 64
+
+//Bit packed binary matrix, every row is stored as a run of uint64 words
+//(padded to a word boundary). Uses 1/8th the memory of DenseBinaryMatrix
+//and allows word at a time RowAndSum, Or, And and Xor.
+type PackedBinaryMatrix struct {
+	Width       int
+	Height      int
+	wordsPerRow int
+	words       []uint64
+}
+
+//Create new packed binary matrix of specified size
+func NewPackedBinaryMatrix(height, width int) *PackedBinaryMatrix {
+	m := &PackedBinaryMatrix{}
+	m.Height = height
+	m.Width = width
+	m.wordsPerRow = (width + wordSize - 1) / wordSize
+	m.words = make([]uint64, height*m.wordsPerRow)
+	return m
+}
+
+//Create packed binary matrix from specified dense matrix
+func NewPackedBinaryMatrixFromDense(values [][]bool) *PackedBinaryMatrix {
+	if len(values) < 1 {
+		panic("No values specified.")
+	}
+
+	m := NewPackedBinaryMatrix(len(values), len(values[0]))
+	for r := 0; r < m.Height; r++ {
+		m.SetRowFromDense(r, values[r])
+	}
+	return m
+}
+
+//Create packed binary matrix from specified flattened dense matrix
+func NewPackedBinaryMatrixFromDense1D(values []bool, rows, cols int) *PackedBinaryMatrix {
+	if len(values) < 1 {
+		panic("No values specified.")
+	}
+	if len(values) != rows*cols {
+		panic("Invalid size")
+	}
+
+	m := NewPackedBinaryMatrix(rows, cols)
+	for r := 0; r < m.Height; r++ {
+		m.SetRowFromDense(r, values[r*cols:(r*cols)+cols])
+	}
+	return m
+}
+
+//Creates a packed binary matrix from specified integer array
+//(any values greater than 0 are true)
+func NewPackedBinaryMatrixFromInts(values [][]int) *PackedBinaryMatrix {
+	if len(values) < 1 {
+		panic("No values specified.")
+	}
+
+	m := NewPackedBinaryMatrix(len(values), len(values[0]))
+	for r := 0; r < m.Height; r++ {
+		for c := 0; c < m.Width; c++ {
+			if values[r][c] > 0 {
+				m.Set(r, c, true)
+			}
+		}
+	}
+	return m
+}
+
+//Returns number of rows
+func (sm *PackedBinaryMatrix) Rows() int {
+	return sm.Height
+}
+
+//Returns number of columns
+func (sm *PackedBinaryMatrix) Cols() int {
+	return sm.Width
+}
+
+//Returns the words backing the specified row
+func (sm *PackedBinaryMatrix) row(row int) []uint64 {
+	start := row * sm.wordsPerRow
+	return sm.words[start : start+sm.wordsPerRow]
+}
+
+//Calls fn with the index of every set bit of the specified words
+func eachSetBit(words []uint64, fn func(int)) {
+	for w, word := range words {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			fn(w*wordSize + bit)
+			word &= word - 1
+		}
+	}
+}
+
+//Packs a dense row into words
+func (sm *PackedBinaryMatrix) packRow(values []bool) []uint64 {
+	result := make([]uint64, sm.wordsPerRow)
+	for i, val := range values {
+		if val {
+			result[i/wordSize] |= 1 << uint(i%wordSize)
+		}
+	}
+	return result
+}
+
+//Returns all true/on indices
+func (sm *PackedBinaryMatrix) Entries() []SparseEntry {
+	result := make([]SparseEntry, 0, sm.TotalNonZeroCount())
+	for r := 0; r < sm.Height; r++ {
+		eachSetBit(sm.row(r), func(c int) {
+			result = append(result, SparseEntry{r, c})
+		})
+	}
+	return result
+}
+
+//Returns flattend dense represenation
+func (sm *PackedBinaryMatrix) Flatten() []bool {
+	result := make([]bool, sm.Height*sm.Width)
+	for r := 0; r < sm.Height; r++ {
+		eachSetBit(sm.row(r), func(c int) {
+			result[(r*sm.Width)+c] = true
+		})
+	}
+	return result
+}
+
+//Get value at col,row position
+func (sm *PackedBinaryMatrix) Get(row int, col int) bool {
+	sm.validateRowCol(row, col)
+	return sm.words[row*sm.wordsPerRow+col/wordSize]&(1<<uint(col%wordSize)) != 0
+}
+
+//Set value at row,col position
+func (sm *PackedBinaryMatrix) Set(row int, col int, value bool) {
+	sm.validateRowCol(row, col)
+	idx := row*sm.wordsPerRow + col/wordSize
+	if value {
+		sm.words[idx] |= 1 << uint(col%wordSize)
+	} else {
+		sm.words[idx] &^= 1 << uint(col%wordSize)
+	}
+}
+
+//Replaces specified row with values, assumes values is ordered
+//correctly
+func (sm *PackedBinaryMatrix) ReplaceRow(row int, values []bool) {
+	sm.SetRowFromDense(row, values)
+}
+
+//Replaces row with true values at specified indices
+func (sm *PackedBinaryMatrix) ReplaceRowByIndices(row int, indices []int) {
+	sm.validateRow(row)
+	words := sm.row(row)
+	for i := range words {
+		words[i] = 0
+	}
+	for _, val := range indices {
+		sm.Set(row, val, true)
+	}
+}
+
+//Returns dense row
+func (sm *PackedBinaryMatrix) GetDenseRow(row int) []bool {
+	sm.validateRow(row)
+	result := make([]bool, sm.Width)
+	eachSetBit(sm.row(row), func(c int) {
+		result[c] = true
+	})
+	return result
+}
+
+//Returns a rows "on" indices
+func (sm *PackedBinaryMatrix) GetRowIndices(row int) []int {
+	sm.validateRow(row)
+	result := make([]int, 0, sm.rowCount(row))
+	eachSetBit(sm.row(row), func(c int) {
+		result = append(result, c)
+	})
+	return result
+}
+
+//Returns a columns "on" indices
+func (sm *PackedBinaryMatrix) GetColIndices(col int) []int {
+	result := make([]int, 0, sm.Height)
+	for r := 0; r < sm.Height; r++ {
+		if sm.Get(r, col) {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+//Sets a sparse row from dense representation
+func (sm *PackedBinaryMatrix) SetRowFromDense(row int, denseRow []bool) {
+	sm.validateRow(row)
+	if len(denseRow) != sm.Width {
+		panic("Row width does not match matrix")
+	}
+	copy(sm.row(row), sm.packRow(denseRow))
+}
+
+//Returns the number of true entries in the specified row
+func (sm *PackedBinaryMatrix) rowCount(row int) int {
+	count := 0
+	for _, word := range sm.row(row) {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+//In a normal matrix this would be multiplication in binary terms
+//we just and then sum the true entries
+func (sm *PackedBinaryMatrix) RowAndSum(row []bool) []int {
+	sm.validateCol(len(row))
+	packed := sm.packRow(row)
+	result := make([]int, sm.Height)
+
+	for r := 0; r < sm.Height; r++ {
+		count := 0
+		for i, word := range sm.row(r) {
+			count += bits.OnesCount64(word & packed[i])
+		}
+		result[r] = count
+	}
+
+	return result
+}
+
+//Returns row indexes with at least 1 true column, in ascending order
+func (sm *PackedBinaryMatrix) NonZeroRows() []int {
+	var result []int
+	for r := 0; r < sm.Height; r++ {
+		for _, word := range sm.row(r) {
+			if word != 0 {
+				result = append(result, r)
+				break
+			}
+		}
+	}
+	return result
+}
+
+//Returns # of rows with at least 1 true value
+func (sm *PackedBinaryMatrix) TotalTrueRows() int {
+	return len(sm.NonZeroRows())
+}
+
+//Returns # of cols with at least 1 true value
+func (sm *PackedBinaryMatrix) TotalTrueCols() int {
+	union := make([]uint64, sm.wordsPerRow)
+	for r := 0; r < sm.Height; r++ {
+		for i, word := range sm.row(r) {
+			union[i] |= word
+		}
+	}
+
+	count := 0
+	for _, word := range union {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+//Returns total true entries
+func (sm *PackedBinaryMatrix) TotalNonZeroCount() int {
+	count := 0
+	for _, word := range sm.words {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+//Applies op word by word to 2 matrices of equal size
+func (sm *PackedBinaryMatrix) wordwise(sm2 *PackedBinaryMatrix, op func(a, b uint64) uint64) *PackedBinaryMatrix {
+	if sm.Width != sm2.Width || sm.Height != sm2.Height {
+		panic("Matrix sizes do not match")
+	}
+	result := NewPackedBinaryMatrix(sm.Height, sm.Width)
+	for i, word := range sm.words {
+		result.words[i] = op(word, sm2.words[i])
+	}
+	return result
+}
+
+//Ors 2 matrices
+func (sm *PackedBinaryMatrix) Or(sm2 *PackedBinaryMatrix) *PackedBinaryMatrix {
+	return sm.wordwise(sm2, func(a, b uint64) uint64 { return a | b })
+}
+
+//Ands 2 matrices
+func (sm *PackedBinaryMatrix) And(sm2 *PackedBinaryMatrix) *PackedBinaryMatrix {
+	return sm.wordwise(sm2, func(a, b uint64) uint64 { return a & b })
+}
+
+//Xors 2 matrices
+func (sm *PackedBinaryMatrix) Xor(sm2 *PackedBinaryMatrix) *PackedBinaryMatrix {
+	return sm.wordwise(sm2, func(a, b uint64) uint64 { return a ^ b })
+}
+
+//Clears  all entries
+func (sm *PackedBinaryMatrix) Clear() {
+	for i := range sm.words {
+		sm.words[i] = 0
+	}
+}
+
+//Fills specified row with specified value
+func (sm *PackedBinaryMatrix) FillRow(row int, val bool) {
+	sm.validateRow(row)
+	words := sm.row(row)
+	for i := range words {
+		words[i] = 0
+	}
+	if val {
+		for c := 0; c < sm.Width; c++ {
+			words[c/wordSize] |= 1 << uint(c%wordSize)
+		}
+	}
+}
+
+//Copys a matrix
+func (sm *PackedBinaryMatrix) Copy() *PackedBinaryMatrix {
+	if sm == nil {
+		return nil
+	}
+
+	result := new(PackedBinaryMatrix)
+	result.Width = sm.Width
+	result.Height = sm.Height
+	result.wordsPerRow = sm.wordsPerRow
+	result.words = make([]uint64, len(sm.words))
+	copy(result.words, sm.words)
+	return result
+}
+
+func (sm *PackedBinaryMatrix) ToString() string {
+	return binaryMatrixString(sm)
+}
+
+func (sm *PackedBinaryMatrix) validateCol(col int) {
+	if col > sm.Width {
+		panic("Specified row is wider than matrix.")
+	}
+}
+
+func (sm *PackedBinaryMatrix) validateRow(row int) {
+	if row < 0 || row >= sm.Height {
+		panic("Specified row is out of bounds.")
+	}
+}
+
+func (sm *PackedBinaryMatrix) validateRowCol(row int, col int) {
+	sm.validateRow(row)
+	if col < 0 || col >= sm.Width {
+		panic("Specified col is out of bounds.")
+	}
+}
@@ -0,0 +1,219 @@
+package htm
+
+import (
+	"math"
+)
+
+type SDRClassifierParams struct {
+	//Number of steps ahead to predict, e.g. {1, 5}
+	Steps []int
+	//Learning rate of the weights
+	Alpha float64
+	//Rate of the moving average of the actual value of each bucket
+	ActValueAlpha float64
+}
+
+//Initializes default classifier params
+func NewSDRClassifierParams() SDRClassifierParams {
+	p := SDRClassifierParams{}
+	p.Steps = []int{1}
+	p.Alpha = 0.001
+	p.ActValueAlpha = 0.3
+	return p
+}
+
+//Pattern seen at a record, kept until it is too old to learn from
+type classifierHistoryEntry struct {
+	recordNum int
+	patternNZ []int
+}
+
+/*
+ The SDR classifier maps the active cells of the temporal memory or pooler
+to a probability distribution over the buckets of an encoded field, for
+each of the requested number of steps into the future. It is a single
+layer softmax network whose weights are trained with the bucket that was
+actually seen nSteps after each pattern.
+*/
+type SDRClassifier struct {
+	SDRClassifierParams
+	maxSteps int
+
+	//weights[step][input bit] holds one weight per bucket
+	weights      map[int]map[int][]float64
+	numBuckets   int
+	actualValues []float64
+	//Set when an actual value has been recorded for the bucket
+	actualValueSeen []bool
+	history         []classifierHistoryEntry
+}
+
+//Result of a classifier compute
+type ClassifierResult struct {
+	//Estimated actual value of each bucket
+	ActualValues []float64
+	//Probability of each bucket, keyed by the number of steps ahead
+	Probabilities map[int][]float64
+}
+
+//Returns the actual value of the most likely bucket nSteps ahead, false
+//if there is no prediction for nSteps
+func (cr ClassifierResult) BestPrediction(nSteps int) (float64, bool) {
+	probabilities, ok := cr.Probabilities[nSteps]
+	if !ok || len(probabilities) == 0 {
+		return 0, false
+	}
+	best := 0
+	for idx, val := range probabilities {
+		if val > probabilities[best] {
+			best = idx
+		}
+	}
+	return cr.ActualValues[best], true
+}
+
+//Creates a new sdr classifier
+func NewSDRClassifier(params SDRClassifierParams) *SDRClassifier {
+	if len(params.Steps) == 0 {
+		panic("At least one step must be specified")
+	}
+	if params.Alpha <= 0 {
+		panic("Alpha must be greater than 0")
+	}
+	if params.ActValueAlpha <= 0 || params.ActValueAlpha > 1 {
+		panic("ActValueAlpha must be in (0,1]")
+	}
+
+	c := new(SDRClassifier)
+	c.SDRClassifierParams = params
+	c.Steps = append([]int(nil), params.Steps...)
+	c.weights = make(map[int]map[int][]float64, len(params.Steps))
+	for _, step := range c.Steps {
+		if step < 0 {
+			panic("Steps must be >= 0")
+		}
+		if step > c.maxSteps {
+			c.maxSteps = step
+		}
+		c.weights[step] = make(map[int][]float64)
+	}
+
+	return c
+}
+
+/*
+ Processes one input record.
+
+param recordNum Record number, increasing by one each record
+param patternNZ Active cells of the record
+param bucketIdx Bucket of the classified field, used for learning
+param actValue Actual value of the classified field, used for learning
+param learn Whether to learn
+param infer Whether to compute the predictions
+*/
+func (c *SDRClassifier) Compute(recordNum int, patternNZ []int, bucketIdx int,
+	actValue float64, learn bool, infer bool) ClassifierResult {
+
+	// Keep the patterns needed to learn the furthest step
+	c.history = append(c.history, classifierHistoryEntry{recordNum, append([]int(nil), patternNZ...)})
+	if len(c.history) > c.maxSteps+1 {
+		c.history = append(c.history[:0], c.history[1:]...)
+	}
+
+	if learn {
+		if bucketIdx < 0 {
+			panic("Bucket index must be >= 0")
+		}
+		c.growBuckets(bucketIdx + 1)
+
+		// Update the actual value of the bucket
+		if !c.actualValueSeen[bucketIdx] {
+			c.actualValues[bucketIdx] = actValue
+			c.actualValueSeen[bucketIdx] = true
+		} else {
+			c.actualValues[bucketIdx] = (1.0-c.ActValueAlpha)*c.actualValues[bucketIdx] +
+				c.ActValueAlpha*actValue
+		}
+
+		// Train every step with the pattern seen that many records ago
+		for _, entry := range c.history {
+			nSteps := recordNum - entry.recordNum
+			weights, ok := c.weights[nSteps]
+			if !ok {
+				continue
+			}
+
+			distribution := c.inferStep(nSteps, entry.patternNZ)
+			for b := range distribution {
+				target := 0.0
+				if b == bucketIdx {
+					target = 1.0
+				}
+				delta := c.Alpha * (target - distribution[b])
+				for _, bit := range entry.patternNZ {
+					weights[bit] = c.bitWeights(weights[bit])
+					weights[bit][b] += delta
+				}
+			}
+		}
+	}
+
+	result := ClassifierResult{}
+	if infer {
+		result.ActualValues = append([]float64(nil), c.actualValues...)
+		result.Probabilities = make(map[int][]float64, len(c.Steps))
+		for _, step := range c.Steps {
+			result.Probabilities[step] = c.inferStep(step, patternNZ)
+		}
+	}
+
+	return result
+}
+
+//Returns the softmax of the summed weights of the active bits for a step
+func (c *SDRClassifier) inferStep(step int, patternNZ []int) []float64 {
+	result := make([]float64, c.numBuckets)
+	if c.numBuckets == 0 {
+		return result
+	}
+
+	weights := c.weights[step]
+	for _, bit := range patternNZ {
+		for b, w := range weights[bit] {
+			result[b] += w
+		}
+	}
+
+	// Numerically stable softmax
+	max := result[0]
+	for _, val := range result {
+		max = math.Max(max, val)
+	}
+	sum := 0.0
+	for b, val := range result {
+		result[b] = math.Exp(val - max)
+		sum += result[b]
+	}
+	for b := range result {
+		result[b] /= sum
+	}
+
+	return result
+}
+
+//Extends the bucket storage to hold at least n buckets
+func (c *SDRClassifier) growBuckets(n int) {
+	for c.numBuckets < n {
+		c.actualValues = append(c.actualValues, 0)
+		c.actualValueSeen = append(c.actualValueSeen, false)
+		c.numBuckets++
+	}
+}
+
+//Returns weights extended to the current number of buckets
+func (c *SDRClassifier) bitWeights(weights []float64) []float64 {
+	for len(weights) < c.numBuckets {
+		weights = append(weights, 0)
+	}
+	return weights
+}
@@ -0,0 +1,74 @@
+package htm
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSDRClassifierLearnsSequence(t *testing.T) {
+	params := NewSDRClassifierParams()
+	params.Steps = []int{0, 1}
+	params.Alpha = 0.1
+	c := NewSDRClassifier(params)
+
+	patterns := [][]int{{1, 5, 9}, {2, 6, 10}, {3, 7, 11}}
+	values := []float64{10, 20, 30}
+
+	recordNum := 0
+	var result ClassifierResult
+	for rep := 0; rep < 100; rep++ {
+		for idx, pattern := range patterns {
+			result = c.Compute(recordNum, pattern, idx, values[idx], true, true)
+			recordNum++
+		}
+	}
+
+	//last pattern was {3,7,11}
+	val, ok := result.BestPrediction(0)
+	assert.True(t, ok)
+	assert.Equal(t, 30.0, val)
+	val, ok = result.BestPrediction(1)
+	assert.True(t, ok)
+	assert.Equal(t, 10.0, val)
+	assert.Equal(t, values, result.ActualValues)
+	assert.True(t, result.Probabilities[1][0] > 0.9)
+
+	sum := 0.0
+	for _, p := range result.Probabilities[1] {
+		sum += p
+	}
+	assert.InDelta(t, 1.0, sum, 1e-9)
+
+	_, ok = result.BestPrediction(5)
+	assert.False(t, ok)
+
+	//inference only does not change the weights
+	result = c.Compute(recordNum, patterns[0], 0, 0, false, true)
+	val, _ = result.BestPrediction(1)
+	assert.Equal(t, 20.0, val)
+}
+
+func TestSDRClassifierActualValues(t *testing.T) {
+	params := NewSDRClassifierParams()
+	params.ActValueAlpha = 0.5
+	c := NewSDRClassifier(params)
+
+	c.Compute(0, []int{1}, 2, 10, true, false)
+	result := c.Compute(1, []int{1}, 2, 20, true, true)
+	assert.Equal(t, []float64{0, 0, 15}, result.ActualValues)
+	assert.Equal(t, 3, len(result.Probabilities[1]))
+}
+
+func TestSDRClassifierInvalidParams(t *testing.T) {
+	params := NewSDRClassifierParams()
+	params.Steps = nil
+	assert.Panics(t, func() { NewSDRClassifier(params) })
+
+	params = NewSDRClassifierParams()
+	params.Alpha = 0
+	assert.Panics(t, func() { NewSDRClassifier(params) })
+
+	params = NewSDRClassifierParams()
+	params.ActValueAlpha = 1.5
+	assert.Panics(t, func() { NewSDRClassifier(params) })
+}
@@ -2,12 +2,11 @@ package htm
 
 import (
 	"fmt"
-	"github.com/cznic/mathutil"
 	//"github.com/skelterjohn/go.matrix"
 	"math"
 	//"math/rand"
 	//"sort"
-	"github.com/gonum/floats"
+	//"github.com/gonum/floats"
 	"github.com/nupic-community/htm/utils"
 )
 
@@ -69,21 +68,28 @@ func (s *Segment) Equals(seg *Segment) bool {
 //Creates a new segment
 func NewSegment(tp *TemporalPooler, isSequenceSeg bool) *Segment {
 	seg := Segment{}
-	seg.tp = tp
-	seg.segId = tp.GetSegId()
-	seg.isSequenceSeg = isSequenceSeg
-	seg.lastActiveIteration = tp.lrnIterationIdx
-	seg.positiveActivations = 1
-	seg.totalActivations = 1
-
-	seg.lastPosDutyCycle = 1.0 / float64(tp.lrnIterationIdx)
-	seg.lastPosDutyCycleIteration = tp.lrnIterationIdx
+	seg.init(tp, isSequenceSeg)
 
 	//TODO: initialize synapse collection
 
 	return &seg
 }
 
+//Resets segment variables for a newly created segment, synapses
+//are truncated but their storage is kept
+func (s *Segment) init(tp *TemporalPooler, isSequenceSeg bool) {
+	s.tp = tp
+	s.segId = tp.GetSegId()
+	s.isSequenceSeg = isSequenceSeg
+	s.lastActiveIteration = tp.lrnIterationIdx
+	s.positiveActivations = 1
+	s.totalActivations = 1
+
+	s.lastPosDutyCycle = 1.0 / float64(tp.lrnIterationIdx)
+	s.lastPosDutyCycleIteration = tp.lrnIterationIdx
+	s.syns = s.syns[:0]
+}
+
 /*
 Compute/update and return the positive activations duty cycle of
 this segment. This is a measure of how often this segment is
@@ -180,69 +186,50 @@ func (s *Segment) freeNSynapses(numToFree int, inactiveSynapseIndices []int) {
 		panic("Number to free cannot be larger than existing synapses.")
 	}
 
-	if s.tp.params.Verbosity >= 5 {
-		fmt.Println("freeNSynapses with numToFree=", numToFree)
-		fmt.Println("inactiveSynapseIndices= ", inactiveSynapseIndices)
+	if s.tp.debugEnabled(5) {
+		s.tp.debug("freeNSynapses", utils.Field("numToFree", numToFree),
+			utils.Field("inactiveSynapseIndices", inactiveSynapseIndices))
 	}
 
-	var candidates []int
-	// Remove the lowest perm inactive synapses first
-	if len(inactiveSynapseIndices) > 0 {
-		perms := make([]float64, len(inactiveSynapseIndices))
-		for idx, _ := range perms {
-			perms[idx] = s.syns[idx].Permanence
-		}
-		var indexes []int
-		floats.Argsort(perms, indexes)
-		//sort perms
-		cSize := mathutil.Min(numToFree, len(perms))
-		candidates = make([]int, cSize)
-		//indexes[0:cSize]
-		for i := 0; i < cSize; i++ {
-			candidates[i] = inactiveSynapseIndices[indexes[i]]
-		}
+	if s.tp.debugEnabled(4) {
+		s.tp.debug("Deleting synapses from segment to make room for new ones",
+			utils.Field("numToFree", numToFree), utils.Field("before", s.ToString()))
 	}
 
-	// Do we need more? if so, remove the lowest perm active synapses too
-	var activeSynIndices []int
-	if len(candidates) < numToFree {
-		for i := 0; i < len(s.syns); i++ {
-			if !utils.ContainsInt(i, inactiveSynapseIndices) {
-				activeSynIndices = append(activeSynIndices, i)
+	// Remove the lowest perm inactive synapses first, if we need more
+	// remove the lowest perm active synapses too. Synapses to delete are
+	// flagged with a negative permanence and compacted in place so the
+	// segment keeps its storage.
+	for n := 0; n < numToFree; n++ {
+		candidate := -1
+		candidateInactive := false
+		for idx, syn := range s.syns {
+			if syn.Permanence < 0 {
+				continue
+			}
+			inactive := utils.ContainsInt(idx, inactiveSynapseIndices)
+			if candidate == -1 ||
+				(inactive && !candidateInactive) ||
+				(inactive == candidateInactive && syn.Permanence < s.syns[candidate].Permanence) {
+				candidate = idx
+				candidateInactive = inactive
 			}
 		}
-
-		perms := make([]float64, len(activeSynIndices))
-		for i := range perms {
-			perms[i] = s.syns[i].Permanence
-		}
-		var indexes []int
-		floats.Argsort(perms, indexes)
-
-		moreToFree := numToFree - len(candidates)
-		//moreCandidates := make([]int, moreToFree)
-		for i := 0; i < moreToFree; i++ {
-			candidates = append(candidates, activeSynIndices[indexes[i]])
-		}
+		s.syns[candidate].Permanence = -1
 	}
 
-	if s.tp.params.Verbosity >= 4 {
-		fmt.Printf("Deleting %v synapses from segment to make room for new ones: %v \n",
-			len(candidates), candidates)
-		fmt.Println("Before:", s.ToString())
-	}
-
-	// Delete candidate syns by copying undeleted to new slice
-	var newSyns []Synapse
-	for idx, val := range s.syns {
-		if !utils.ContainsInt(idx, candidates) {
-			newSyns = append(newSyns, val)
+	w := 0
+	for _, syn := range s.syns {
+		if syn.Permanence >= 0 {
+			s.syns[w] = syn
+			w++
 		}
 	}
-	s.syns = newSyns
+	s.syns = s.syns[:w]
+	s.tp.stepChanges.SynapsesDestroyed += numToFree
 
-	if s.tp.params.Verbosity >= 4 {
-		fmt.Println("After:", s.ToString())
+	if s.tp.debugEnabled(4) {
+		s.tp.debug("Deleted synapses from segment", utils.Field("after", s.ToString()))
 	}
 
 }
@@ -285,6 +272,7 @@ Adds a new synapse
 */
 func (s *Segment) AddSynapse(srcCellCol, srcCellIdx int, perm float64) {
 	s.syns = append(s.syns, Synapse{srcCellCol, srcCellIdx, perm})
+	s.tp.stepChanges.SynapsesCreated++
 }
 
 /*
@@ -301,8 +289,9 @@ func (tp *TemporalPooler) getSegmentActiveSynapses(c int, i int, s *Segment,
 	activeState *SparseBinaryMatrix, newSynapses bool) *SegmentUpdate {
 	var activeSynapses []SynapseUpdateState
 
-	if tp.params.Verbosity >= 5 {
-		fmt.Printf("Entering getSegActiveSyns syns:%v segnil:%v newsyns:%v \n", 0, s == nil, newSynapses)
+	if tp.debugEnabled(5) {
+		tp.debug("Entering getSegmentActiveSynapses", utils.Field("column", c), utils.Field("cell", i),
+			utils.Field("newSegment", s == nil), utils.Field("newSynapses", newSynapses))
 	}
 
 	if s != nil {
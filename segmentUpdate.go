@@ -1,7 +1,6 @@
 package htm
 
 import (
-	"fmt"
 	"github.com/nupic-community/htm/utils"
 
 	//"github.com/cznic/mathutil"
@@ -105,8 +104,9 @@ func (segUpdate *SegmentUpdate) adaptSegments(tp *TemporalPooler) bool {
 
 	if segment != nil {
 
-		if tp.params.Verbosity >= 4 {
-			fmt.Printf("Reinforcing segment #%v for cell[%v,%v] \n", segment.segId, c, i)
+		if tp.debugEnabled(4) {
+			tp.debug("Reinforcing segment", utils.Field("segment", segment.segId),
+				utils.Field("column", c), utils.Field("cell", i))
 		}
 
 		//modify existing segment
@@ -159,18 +159,16 @@ func (segUpdate *SegmentUpdate) adaptSegments(tp *TemporalPooler) bool {
 
 	} else {
 		//create new segment
-		newSegment := NewSegment(tp, segUpdate.sequenceSegment)
+		newSegment := tp.createSegment(c, i, segUpdate.sequenceSegment)
 
 		for _, val := range activeSynapses {
 			newSegment.AddSynapse(val.Index, val.CellIndex, tp.params.InitialPerm)
 		}
 
-		if tp.params.Verbosity >= 3 {
-			fmt.Printf("New segment #%v for cell[%v,%v] \n", tp.segId, c, i)
-			fmt.Print(newSegment.ToString())
+		if tp.debugEnabled(3) {
+			tp.debug("New segment", utils.Field("segment", tp.segId), utils.Field("column", c),
+				utils.Field("cell", i), utils.Field("synapses", newSegment.ToString()))
 		}
-
-		tp.cells[c][i] = append(tp.cells[c][i], *newSegment)
 	}
 
 	return trimSegment
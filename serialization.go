@@ -0,0 +1,645 @@
+package htm
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/nupic-community/htm/utils"
+	"github.com/zacg/go.matrix"
+	"math/rand"
+	"sort"
+)
+
+/*
+ Gob encoding of the learned state of the algorithms, used to save and
+restore models. Loggers, observers, diagnostic writers and SP topologies
+can't be encoded, they are nil after decoding. Random generators are
+reseeded from the seed and iteration count, so a restored model learns
+deterministically but does not repeat the random choices the original
+would have made.
+*/
+
+func gobEncode(state interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(state); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gobDecode(data []byte, state interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(state)
+}
+
+type packedBinaryMatrixState struct {
+	Width  int
+	Height int
+	Words  []uint64
+}
+
+func (m *PackedBinaryMatrix) GobEncode() ([]byte, error) {
+	return gobEncode(packedBinaryMatrixState{m.Width, m.Height, m.words})
+}
+
+func (m *PackedBinaryMatrix) GobDecode(data []byte) error {
+	var state packedBinaryMatrixState
+	if err := gobDecode(data, &state); err != nil {
+		return err
+	}
+	*m = *NewPackedBinaryMatrix(state.Height, state.Width)
+	copy(m.words, state.Words)
+	return nil
+}
+
+type sparseFloatMatrixState struct {
+	Width  int
+	Height int
+	Cols   [][]int
+	Values [][]float64
+}
+
+func (m *SparseFloatMatrix) GobEncode() ([]byte, error) {
+	state := sparseFloatMatrixState{Width: m.Width, Height: m.Height}
+	state.Cols = make([][]int, m.Height)
+	state.Values = make([][]float64, m.Height)
+	for r, row := range m.rows {
+		state.Cols[r] = row.cols
+		state.Values[r] = row.values
+	}
+	return gobEncode(state)
+}
+
+func (m *SparseFloatMatrix) GobDecode(data []byte) error {
+	var state sparseFloatMatrixState
+	if err := gobDecode(data, &state); err != nil {
+		return err
+	}
+	*m = *NewSparseFloatMatrix(state.Height, state.Width)
+	for r := range m.rows {
+		if r < len(state.Cols) {
+			m.rows[r] = sparseFloatRow{state.Cols[r], state.Values[r]}
+		}
+	}
+	return nil
+}
+
+type spatialPoolerState struct {
+	NumColumns                 int
+	NumInputs                  int
+	ColumnDimensions           []int
+	InputDimensions            []int
+	PotentialRadius            int
+	PotentialPct               float64
+	WrapAround                 bool
+	GlobalInhibition           bool
+	NumActiveColumnsPerInhArea int
+	LocalAreaDensity           float64
+	StimulusThreshold          int
+	SynPermInactiveDec         float64
+	SynPermActiveInc           float64
+	SynPermBelowStimulusInc    float64
+	SynPermConnected           float64
+	MinPctOverlapDutyCycles    float64
+	MinPctActiveDutyCycles     float64
+	DutyCyclePeriod            int
+	MaxBoost                   float64
+	BoostMode                  BoostMode
+	BoostStrength              float64
+	SpVerbosity                int
+	SynPermMin                 float64
+	SynPermMax                 float64
+	SynPermTrimThreshold       float64
+	UpdatePeriod               int
+	InitConnectedPct           float64
+	Version                    float64
+	IterationNum               int
+	IterationLearnNum          int
+	Seed                       int
+
+	PotentialPools       *PackedBinaryMatrix
+	Permanences          *SparseFloatMatrix
+	TieBreaker           []float64
+	ConnectedSynapses    *PackedBinaryMatrix
+	ConnectedCounts      []int
+	OverlapDutyCycles    []float64
+	ActiveDutyCycles     []float64
+	MinOverlapDutyCycles []float64
+	MinActiveDutyCycles  []float64
+	BoostFactors         []float64
+	InhibitionRadius     int
+}
+
+func (sp *SpatialPooler) GobEncode() ([]byte, error) {
+	return gobEncode(spatialPoolerState{
+		NumColumns:                 sp.numColumns,
+		NumInputs:                  sp.numInputs,
+		ColumnDimensions:           sp.ColumnDimensions,
+		InputDimensions:            sp.InputDimensions,
+		PotentialRadius:            sp.PotentialRadius,
+		PotentialPct:               sp.PotentialPct,
+		WrapAround:                 sp.WrapAround,
+		GlobalInhibition:           sp.GlobalInhibition,
+		NumActiveColumnsPerInhArea: sp.NumActiveColumnsPerInhArea,
+		LocalAreaDensity:           sp.LocalAreaDensity,
+		StimulusThreshold:          sp.StimulusThreshold,
+		SynPermInactiveDec:         sp.SynPermInactiveDec,
+		SynPermActiveInc:           sp.SynPermActiveInc,
+		SynPermBelowStimulusInc:    sp.SynPermBelowStimulusInc,
+		SynPermConnected:           sp.SynPermConnected,
+		MinPctOverlapDutyCycles:    sp.MinPctOverlapDutyCycles,
+		MinPctActiveDutyCycles:     sp.MinPctActiveDutyCycles,
+		DutyCyclePeriod:            sp.DutyCyclePeriod,
+		MaxBoost:                   sp.MaxBoost,
+		BoostMode:                  sp.BoostMode,
+		BoostStrength:              sp.BoostStrength,
+		SpVerbosity:                sp.SpVerbosity,
+		SynPermMin:                 sp.SynPermMin,
+		SynPermMax:                 sp.SynPermMax,
+		SynPermTrimThreshold:       sp.SynPermTrimThreshold,
+		UpdatePeriod:               sp.UpdatePeriod,
+		InitConnectedPct:           sp.InitConnectedPct,
+		Version:                    sp.Version,
+		IterationNum:               sp.IterationNum,
+		IterationLearnNum:          sp.IterationLearnNum,
+		Seed:                       sp.Seed,
+		PotentialPools:             sp.potentialPools,
+		Permanences:                sp.permanences,
+		TieBreaker:                 sp.tieBreaker,
+		ConnectedSynapses:          sp.connectedSynapses,
+		ConnectedCounts:            sp.connectedCounts,
+		OverlapDutyCycles:          sp.overlapDutyCycles,
+		ActiveDutyCycles:           sp.activeDutyCycles,
+		MinOverlapDutyCycles:       sp.minOverlapDutyCycles,
+		MinActiveDutyCycles:        sp.minActiveDutyCycles,
+		BoostFactors:               sp.boostFactors,
+		InhibitionRadius:           sp.inhibitionRadius,
+	})
+}
+
+func (sp *SpatialPooler) GobDecode(data []byte) error {
+	var s spatialPoolerState
+	if err := gobDecode(data, &s); err != nil {
+		return err
+	}
+
+	*sp = SpatialPooler{
+		numColumns:                 s.NumColumns,
+		numInputs:                  s.NumInputs,
+		ColumnDimensions:           s.ColumnDimensions,
+		InputDimensions:            s.InputDimensions,
+		PotentialRadius:            s.PotentialRadius,
+		PotentialPct:               s.PotentialPct,
+		WrapAround:                 s.WrapAround,
+		GlobalInhibition:           s.GlobalInhibition,
+		NumActiveColumnsPerInhArea: s.NumActiveColumnsPerInhArea,
+		LocalAreaDensity:           s.LocalAreaDensity,
+		StimulusThreshold:          s.StimulusThreshold,
+		SynPermInactiveDec:         s.SynPermInactiveDec,
+		SynPermActiveInc:           s.SynPermActiveInc,
+		SynPermBelowStimulusInc:    s.SynPermBelowStimulusInc,
+		SynPermConnected:           s.SynPermConnected,
+		MinPctOverlapDutyCycles:    s.MinPctOverlapDutyCycles,
+		MinPctActiveDutyCycles:     s.MinPctActiveDutyCycles,
+		DutyCyclePeriod:            s.DutyCyclePeriod,
+		MaxBoost:                   s.MaxBoost,
+		BoostMode:                  s.BoostMode,
+		BoostStrength:              s.BoostStrength,
+		SpVerbosity:                s.SpVerbosity,
+		SynPermMin:                 s.SynPermMin,
+		SynPermMax:                 s.SynPermMax,
+		SynPermTrimThreshold:       s.SynPermTrimThreshold,
+		UpdatePeriod:               s.UpdatePeriod,
+		InitConnectedPct:           s.InitConnectedPct,
+		Version:                    s.Version,
+		IterationNum:               s.IterationNum,
+		IterationLearnNum:          s.IterationLearnNum,
+		Seed:                       s.Seed,
+		potentialPools:             s.PotentialPools,
+		permanences:                s.Permanences,
+		tieBreaker:                 s.TieBreaker,
+		connectedSynapses:          s.ConnectedSynapses,
+		connectedCounts:            s.ConnectedCounts,
+		overlapDutyCycles:          s.OverlapDutyCycles,
+		activeDutyCycles:           s.ActiveDutyCycles,
+		minOverlapDutyCycles:       s.MinOverlapDutyCycles,
+		minActiveDutyCycles:        s.MinActiveDutyCycles,
+		boostFactors:               s.BoostFactors,
+		inhibitionRadius:           s.InhibitionRadius,
+	}
+	sp.logger = utils.WithFields(nil, utils.Field("component", "sp"))
+	if sp.Seed >= 0 {
+		sp.rand = rand.New(rand.NewSource(int64(sp.Seed + sp.IterationNum)))
+	}
+	return nil
+}
+
+type temporalMemoryState struct {
+	Params                   TemporalMemoryParams
+	ActiveCells              []int
+	PredictiveCells          []int
+	ActiveSegments           []int
+	ActiveSynapsesForSegment map[int][]int
+	WinnerCells              []int
+	Iteration                int
+	//Cell of every segment
+	Segments []int
+	Synapses []TmSynapse
+}
+
+func (tm *TemporalMemory) GobEncode() ([]byte, error) {
+	state := temporalMemoryState{
+		Params:                   *tm.params,
+		ActiveCells:              tm.ActiveCells,
+		PredictiveCells:          tm.PredictiveCells,
+		ActiveSegments:           tm.ActiveSegments,
+		ActiveSynapsesForSegment: tm.ActiveSynapsesForSegment,
+		WinnerCells:              tm.WinnerCells,
+		Iteration:                tm.iteration,
+		Segments:                 tm.Connections.segments,
+	}
+	state.Params.Observer = nil
+	for _, syn := range tm.Connections.synapses {
+		state.Synapses = append(state.Synapses, *syn)
+	}
+	return gobEncode(state)
+}
+
+func (tm *TemporalMemory) GobDecode(data []byte) error {
+	var s temporalMemoryState
+	if err := gobDecode(data, &s); err != nil {
+		return err
+	}
+
+	*tm = *NewTemporalMemory(&s.Params)
+	tm.ActiveCells = s.ActiveCells
+	tm.PredictiveCells = s.PredictiveCells
+	tm.ActiveSegments = s.ActiveSegments
+	tm.ActiveSynapsesForSegment = s.ActiveSynapsesForSegment
+	tm.WinnerCells = s.WinnerCells
+	tm.iteration = s.Iteration
+	tm.rand = rand.New(rand.NewSource(int64(s.Params.Seed + s.Iteration)))
+
+	// Recreating in order restores the segment and synapse indices
+	for _, cell := range s.Segments {
+		tm.Connections.CreateSegment(cell)
+	}
+	for _, syn := range s.Synapses {
+		tm.Connections.CreateSynapse(syn.Segment, syn.SourceCell, syn.Permanence)
+	}
+	return nil
+}
+
+type classifierHistoryState struct {
+	RecordNum int
+	PatternNZ []int
+}
+
+type sdrClassifierState struct {
+	Params          SDRClassifierParams
+	Weights         map[int]map[int][]float64
+	NumBuckets      int
+	ActualValues    []float64
+	ActualValueSeen []bool
+	History         []classifierHistoryState
+}
+
+func (c *SDRClassifier) GobEncode() ([]byte, error) {
+	state := sdrClassifierState{
+		Params:          c.SDRClassifierParams,
+		Weights:         c.weights,
+		NumBuckets:      c.numBuckets,
+		ActualValues:    c.actualValues,
+		ActualValueSeen: c.actualValueSeen,
+	}
+	for _, entry := range c.history {
+		state.History = append(state.History, classifierHistoryState{entry.recordNum, entry.patternNZ})
+	}
+	return gobEncode(state)
+}
+
+func (c *SDRClassifier) GobDecode(data []byte) error {
+	var s sdrClassifierState
+	if err := gobDecode(data, &s); err != nil {
+		return err
+	}
+
+	*c = *NewSDRClassifier(s.Params)
+	for step, weights := range s.Weights {
+		if weights != nil {
+			c.weights[step] = weights
+		}
+	}
+	c.numBuckets = s.NumBuckets
+	c.actualValues = s.ActualValues
+	c.actualValueSeen = s.ActualValueSeen
+	for _, entry := range s.History {
+		c.history = append(c.history, classifierHistoryEntry{entry.RecordNum, entry.PatternNZ})
+	}
+	return nil
+}
+
+type sparseBinaryMatrixState struct {
+	Width   int
+	Height  int
+	Entries []SparseEntry
+}
+
+func (m *SparseBinaryMatrix) GobEncode() ([]byte, error) {
+	return gobEncode(sparseBinaryMatrixState{m.Width, m.Height, m.entries})
+}
+
+func (m *SparseBinaryMatrix) GobDecode(data []byte) error {
+	var state sparseBinaryMatrixState
+	if err := gobDecode(data, &state); err != nil {
+		return err
+	}
+	*m = SparseBinaryMatrix{Width: state.Width, Height: state.Height, entries: state.Entries}
+	return nil
+}
+
+type denseMatrixState struct {
+	Rows   int
+	Cols   int
+	Values []float64
+}
+
+func encodeDenseMatrix(m *matrix.DenseMatrix) *denseMatrixState {
+	if m == nil {
+		return nil
+	}
+	state := &denseMatrixState{m.Rows(), m.Cols(), make([]float64, m.Rows()*m.Cols())}
+	for r := 0; r < state.Rows; r++ {
+		for c := 0; c < state.Cols; c++ {
+			state.Values[r*state.Cols+c] = m.Get(r, c)
+		}
+	}
+	return state
+}
+
+func decodeDenseMatrix(state *denseMatrixState) *matrix.DenseMatrix {
+	if state == nil {
+		return nil
+	}
+	return matrix.MakeDenseMatrix(state.Values, state.Rows, state.Cols)
+}
+
+type dynamicStateState struct {
+	LrnActiveState             *SparseBinaryMatrix
+	LrnActiveStateLast         *SparseBinaryMatrix
+	LrnPredictedState          *SparseBinaryMatrix
+	LrnPredictedStateLast      *SparseBinaryMatrix
+	InfActiveState             *SparseBinaryMatrix
+	InfActiveStateLast         *SparseBinaryMatrix
+	InfActiveStateBackup       *SparseBinaryMatrix
+	InfActiveStateCandidate    *SparseBinaryMatrix
+	InfPredictedState          *SparseBinaryMatrix
+	InfPredictedStateLast      *SparseBinaryMatrix
+	InfPredictedStateBackup    *SparseBinaryMatrix
+	InfPredictedStateCandidate *SparseBinaryMatrix
+	CellConfidence             *denseMatrixState
+	CellConfidenceLast         *denseMatrixState
+	CellConfidenceCandidate    *denseMatrixState
+	ColConfidence              []float64
+	ColConfidenceLast          []float64
+	ColConfidenceCandidate     []float64
+}
+
+func (ds *DynamicState) GobEncode() ([]byte, error) {
+	return gobEncode(dynamicStateState{
+		LrnActiveState:             ds.LrnActiveState,
+		LrnActiveStateLast:         ds.LrnActiveStateLast,
+		LrnPredictedState:          ds.LrnPredictedState,
+		LrnPredictedStateLast:      ds.LrnPredictedStateLast,
+		InfActiveState:             ds.InfActiveState,
+		InfActiveStateLast:         ds.InfActiveStateLast,
+		InfActiveStateBackup:       ds.InfActiveStateBackup,
+		InfActiveStateCandidate:    ds.InfActiveStateCandidate,
+		InfPredictedState:          ds.InfPredictedState,
+		InfPredictedStateLast:      ds.InfPredictedStateLast,
+		InfPredictedStateBackup:    ds.InfPredictedStateBackup,
+		InfPredictedStateCandidate: ds.InfPredictedStateCandidate,
+		CellConfidence:             encodeDenseMatrix(ds.CellConfidence),
+		CellConfidenceLast:         encodeDenseMatrix(ds.CellConfidenceLast),
+		CellConfidenceCandidate:    encodeDenseMatrix(ds.CellConfidenceCandidate),
+		ColConfidence:              ds.ColConfidence,
+		ColConfidenceLast:          ds.ColConfidenceLast,
+		ColConfidenceCandidate:     ds.ColConfidenceCandidate,
+	})
+}
+
+func (ds *DynamicState) GobDecode(data []byte) error {
+	var s dynamicStateState
+	if err := gobDecode(data, &s); err != nil {
+		return err
+	}
+
+	*ds = DynamicState{
+		LrnActiveState:             s.LrnActiveState,
+		LrnActiveStateLast:         s.LrnActiveStateLast,
+		LrnPredictedState:          s.LrnPredictedState,
+		LrnPredictedStateLast:      s.LrnPredictedStateLast,
+		InfActiveState:             s.InfActiveState,
+		InfActiveStateLast:         s.InfActiveStateLast,
+		InfActiveStateBackup:       s.InfActiveStateBackup,
+		InfActiveStateCandidate:    s.InfActiveStateCandidate,
+		InfPredictedState:          s.InfPredictedState,
+		InfPredictedStateLast:      s.InfPredictedStateLast,
+		InfPredictedStateBackup:    s.InfPredictedStateBackup,
+		InfPredictedStateCandidate: s.InfPredictedStateCandidate,
+		CellConfidence:             decodeDenseMatrix(s.CellConfidence),
+		CellConfidenceLast:         decodeDenseMatrix(s.CellConfidenceLast),
+		CellConfidenceCandidate:    decodeDenseMatrix(s.CellConfidenceCandidate),
+		ColConfidence:              s.ColConfidence,
+		ColConfidenceLast:          s.ColConfidenceLast,
+		ColConfidenceCandidate:     s.ColConfidenceCandidate,
+	}
+	return nil
+}
+
+type tpSegmentState struct {
+	Col                       int
+	Cell                      int
+	SegId                     int
+	IsSequenceSeg             bool
+	LastActiveIteration       int
+	PositiveActivations       int
+	TotalActivations          int
+	LastPosDutyCycle          float64
+	LastPosDutyCycleIteration int
+	Syns                      []Synapse
+}
+
+type tpSegmentUpdateState struct {
+	CreationDate int
+	ColumnIdx    int
+	CellIdx      int
+	//Id of the updated segment, -1 for a new segment
+	SegId            int
+	ActiveSynapses   []SynapseUpdateState
+	SequenceSegment  bool
+	Phase1Flag       bool
+	WeaklyPredicting bool
+	LrnIterationIdx  int
+}
+
+type trivialPredictorState struct {
+	Methods        []PredictorMethod
+	InternalStats  map[PredictorMethod]*TpStats
+	State          map[PredictorMethod]TrivialPredictorState
+	ColumnCount    []int
+	AverageDensity float64
+}
+
+type temporalPoolerState struct {
+	Params              TemporalPoolerParams
+	ActiveColumns       []int
+	Segments            []tpSegmentState
+	LrnIterationIdx     int
+	IterationIdx        int
+	SegId               int
+	CurrentOutput       *SparseBinaryMatrix
+	PamCounter          int
+	AvgInputDensity     float64
+	AvgLearnedSeqLength float64
+	ResetCalled         bool
+	LearnedSeqLength    int
+	TrivialPredictor    *trivialPredictorState
+	Stats               *TpStats
+	SegmentUpdates      []tpSegmentUpdateState
+	PrevInfPatterns     [][]int
+	PrevLrnPatterns     [][]int
+	PrevPredictedState  *SparseBinaryMatrix
+	DynamicState        *DynamicState
+}
+
+func (tp *TemporalPooler) GobEncode() ([]byte, error) {
+	state := temporalPoolerState{
+		Params:              tp.params,
+		ActiveColumns:       tp.activeColumns,
+		LrnIterationIdx:     tp.lrnIterationIdx,
+		IterationIdx:        tp.iterationIdx,
+		SegId:               tp.segId,
+		CurrentOutput:       tp.CurrentOutput,
+		PamCounter:          tp.pamCounter,
+		AvgInputDensity:     tp.avgInputDensity,
+		AvgLearnedSeqLength: tp.avgLearnedSeqLength,
+		ResetCalled:         tp.resetCalled,
+		LearnedSeqLength:    tp.learnedSeqLength,
+		Stats:               tp.internalStats,
+		PrevInfPatterns:     tp.prevInfPatterns,
+		PrevLrnPatterns:     tp.prevLrnPatterns,
+		PrevPredictedState:  tp.prevPredictedState,
+		DynamicState:        tp.DynamicState,
+	}
+	state.Params.Logger, state.Params.Observer = nil, nil
+
+	for c, col := range tp.cells {
+		for i, cell := range col {
+			for _, seg := range cell {
+				state.Segments = append(state.Segments, tpSegmentState{c, i, seg.segId,
+					seg.isSequenceSeg, seg.lastActiveIteration, seg.positiveActivations,
+					seg.totalActivations, seg.lastPosDutyCycle, seg.lastPosDutyCycleIteration,
+					seg.syns})
+			}
+		}
+	}
+
+	// Updates refer to their segment by id, keys are sorted so equal
+	// poolers encode equally
+	var keys []utils.TupleInt
+	for key := range tp.segmentUpdates {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].A < keys[j].A || (keys[i].A == keys[j].A && keys[i].B < keys[j].B)
+	})
+	for _, key := range keys {
+		for _, update := range tp.segmentUpdates[key] {
+			u := update.Update
+			segId := -1
+			if u.segment != nil {
+				segId = u.segment.segId
+			}
+			state.SegmentUpdates = append(state.SegmentUpdates, tpSegmentUpdateState{
+				update.CreationDate, u.columnIdx, u.cellIdx, segId, u.activeSynapses,
+				u.sequenceSegment, u.phase1Flag, u.weaklyPredicting, u.lrnIterationIdx})
+		}
+	}
+
+	if p := tp.trivialPredictor; p != nil {
+		state.TrivialPredictor = &trivialPredictorState{p.Methods, p.InternalStats, p.State,
+			p.ColumnCount, p.AverageDensity}
+	}
+	return gobEncode(state)
+}
+
+func (tp *TemporalPooler) GobDecode(data []byte) error {
+	var s temporalPoolerState
+	if err := gobDecode(data, &s); err != nil {
+		return err
+	}
+
+	*tp = *NewTemporalPooler(s.Params)
+	tp.activeColumns = s.ActiveColumns
+	tp.lrnIterationIdx = s.LrnIterationIdx
+	tp.iterationIdx = s.IterationIdx
+	tp.segId = s.SegId
+	tp.CurrentOutput = s.CurrentOutput
+	tp.pamCounter = s.PamCounter
+	tp.avgInputDensity = s.AvgInputDensity
+	tp.avgLearnedSeqLength = s.AvgLearnedSeqLength
+	tp.resetCalled = s.ResetCalled
+	tp.learnedSeqLength = s.LearnedSeqLength
+	tp.prevInfPatterns = s.PrevInfPatterns
+	tp.prevLrnPatterns = s.PrevLrnPatterns
+	tp.prevPredictedState = s.PrevPredictedState
+	if s.Stats != nil {
+		tp.internalStats = s.Stats
+	}
+	if s.DynamicState != nil {
+		tp.DynamicState = s.DynamicState
+	}
+	tp.rand = rand.New(rand.NewSource(int64(s.Params.Seed + s.IterationIdx)))
+
+	// Segments are placed in the cells storage, fixed size cells reuse
+	// their preallocated slots
+	for _, seg := range s.Segments {
+		cell := tp.cells[seg.Col][seg.Cell]
+		if tp.isFixedSize() {
+			cell = cell[:len(cell)+1]
+		} else {
+			cell = append(cell, Segment{})
+		}
+		tp.cells[seg.Col][seg.Cell] = cell
+		dst := &cell[len(cell)-1]
+		*dst = Segment{tp, seg.SegId, seg.IsSequenceSeg, seg.LastActiveIteration,
+			seg.PositiveActivations, seg.TotalActivations, seg.LastPosDutyCycle,
+			seg.LastPosDutyCycleIteration, append(dst.syns[:0], seg.Syns...)}
+	}
+
+	for _, u := range s.SegmentUpdates {
+		var segment *Segment
+		if u.SegId != -1 {
+			idx := tp.segmentIndex(u.ColumnIdx, u.CellIdx, u.SegId)
+			if idx == -1 {
+				continue
+			}
+			segment = &tp.cells[u.ColumnIdx][u.CellIdx][idx]
+		}
+		if tp.segmentUpdates == nil {
+			tp.segmentUpdates = make(map[utils.TupleInt][]UpdateState)
+		}
+		key := utils.TupleInt{u.ColumnIdx, u.CellIdx}
+		tp.segmentUpdates[key] = append(tp.segmentUpdates[key], UpdateState{u.CreationDate,
+			&SegmentUpdate{u.ColumnIdx, u.CellIdx, segment, u.ActiveSynapses,
+				u.SequenceSegment, u.Phase1Flag, u.WeaklyPredicting, u.LrnIterationIdx}})
+	}
+
+	if p := tp.trivialPredictor; p != nil && s.TrivialPredictor != nil {
+		p.InternalStats = s.TrivialPredictor.InternalStats
+		p.State = s.TrivialPredictor.State
+		p.ColumnCount = s.TrivialPredictor.ColumnCount
+		p.AverageDensity = s.TrivialPredictor.AverageDensity
+		p.rand = rand.New(rand.NewSource(int64(s.Params.Seed + s.IterationIdx)))
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package htm
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/nupic-community/htm/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+//Gob encodes src and decodes the result into dst
+func gobRoundTrip(t *testing.T, src, dst interface{}) {
+	var buf bytes.Buffer
+	assert.Nil(t, gob.NewEncoder(&buf).Encode(src))
+	assert.Nil(t, gob.NewDecoder(&buf).Decode(dst))
+}
+
+func TestSerializeRegion(t *testing.T) {
+	params := hierarchyLevel(40)
+	region := NewRegion(params.SP, params.TM, nil)
+	for i := 0; i < 80; i++ {
+		region.Compute(hierarchyInput(i%4), true)
+	}
+
+	var sp SpatialPooler
+	var tm TemporalMemory
+	gobRoundTrip(t, region.SP, &sp)
+	gobRoundTrip(t, region.TM, &tm)
+
+	assert.Equal(t, region.SP.NumColumns(), sp.NumColumns())
+	assert.Equal(t, region.SP.IterationNum, sp.IterationNum)
+	assert.Equal(t, region.TM.Connections.NumberOfSegments(), tm.Connections.NumberOfSegments())
+	assert.Equal(t, region.TM.Connections.NumberOfSynapses(), tm.Connections.NumberOfSynapses())
+	assert.Equal(t, region.TM.PredictiveCells, tm.PredictiveCells)
+
+	//the decoded copies continue the sequence exactly like the originals
+	original := make([]bool, region.SP.NumColumns())
+	decoded := make([]bool, sp.NumColumns())
+	for i := 0; i < 8; i++ {
+		input := hierarchyInput(i % 4)
+		utils.FillSliceBool(original, false)
+		utils.FillSliceBool(decoded, false)
+		region.SP.Compute(input, false, original, region.SP.InhibitColumns)
+		sp.Compute(input, false, decoded, sp.InhibitColumns)
+		assert.Equal(t, original, decoded)
+
+		region.TM.Compute(utils.OnIndices(original), false)
+		tm.Compute(utils.OnIndices(decoded), false)
+		assert.Equal(t, region.TM.ActiveCells, tm.ActiveCells)
+		assert.Equal(t, region.TM.PredictiveCells, tm.PredictiveCells)
+	}
+	assert.True(t, len(tm.PredictiveCells) > 0)
+}
+
+func TestSerializeSDRClassifier(t *testing.T) {
+	params := NewSDRClassifierParams()
+	params.Steps = []int{1, 2}
+	params.Alpha = 0.1
+	c := NewSDRClassifier(params)
+	for i := 0; i < 20; i++ {
+		c.Compute(i, []int{i % 4, 10 + i%4}, i%4, float64(i%4)*10, true, false)
+	}
+
+	var decoded SDRClassifier
+	gobRoundTrip(t, c, &decoded)
+	assert.Equal(t, c.Steps, decoded.Steps)
+
+	for i := 20; i < 24; i++ {
+		pattern := []int{i % 4, 10 + i%4}
+		expected := c.Compute(i, pattern, i%4, float64(i%4)*10, true, true)
+		actual := decoded.Compute(i, pattern, i%4, float64(i%4)*10, true, true)
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func TestSerializeTemporalPooler(t *testing.T) {
+	for _, fixedSize := range []bool{false, true} {
+		tps := NewTemporalPoolerParams()
+		tps.NumberOfCols = 50
+		tps.CellsPerColumn = 2
+		tps.ActivationThreshold = 8
+		tps.MinThreshold = 10
+		tps.InitialPerm = 0.5
+		tps.ConnectedPerm = 0.5
+		tps.NewSynapseCount = 10
+		tps.PermanenceDec = 0.0
+		tps.PermanenceInc = 0.1
+		tps.GlobalDecay = 0
+		tps.BurnIn = 1
+		tps.PamLength = 10
+		tps.CollectStats = true
+		tps.TrivialPredictionMethods = []PredictorMethod{Last, Lots}
+		if fixedSize {
+			tps.MaxAge = 0
+			tps.MaxSegmentsPerCell = 4
+			tps.MaxSynapsesPerSegment = 12
+		}
+		tp := NewTemporalPooler(*tps)
+
+		inputs := make([][]bool, 5)
+		for i := range inputs {
+			inputs[i] = boolRange(i*10, i*10+9, 50)
+		}
+		for i := 0; i < 10; i++ {
+			for p := 0; p < 5; p++ {
+				tp.Compute(inputs[p], true, true)
+			}
+			tp.Reset()
+		}
+		tp.Compute(inputs[0], true, true)
+
+		assert.True(t, len(tp.segmentUpdates) > 0)
+
+		var decoded TemporalPooler
+		gobRoundTrip(t, tp, &decoded)
+		assert.Equal(t, tp.CalcSegmentStats(true), decoded.CalcSegmentStats(true))
+		assert.Equal(t, tp.Stats(), decoded.Stats())
+		assert.Equal(t, len(tp.segmentUpdates), len(decoded.segmentUpdates))
+		for c := range decoded.cells {
+			for i := range decoded.cells[c] {
+				for _, seg := range decoded.cells[c][i] {
+					assert.True(t, seg.tp == &decoded)
+				}
+			}
+		}
+
+		//the decoded copy continues the sequence exactly like the original
+		for p := 1; p < 5; p++ {
+			assert.Equal(t, tp.Compute(inputs[p], false, true), decoded.Compute(inputs[p], false, true))
+			assert.Equal(t, tp.DynamicState.ColConfidence, decoded.DynamicState.ColConfidence)
+		}
+		assert.Equal(t, tp.Stats(), decoded.Stats())
+		decoded.Compute(inputs[0], true, true)
+	}
+}
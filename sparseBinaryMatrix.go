@@ -94,6 +94,16 @@ func NewSparseBinaryMatrixFromInts(values [][]int) *SparseBinaryMatrix {
 // func (sm *SparseBinaryMatrix) Resize(width int, height int) {
 // }
 
+//Returns number of rows
+func (sm *SparseBinaryMatrix) Rows() int {
+	return sm.Height
+}
+
+//Returns number of columns
+func (sm *SparseBinaryMatrix) Cols() int {
+	return sm.Width
+}
+
 //Returns all true/on indices
 func (sm *SparseBinaryMatrix) Entries() []SparseEntry {
 	return sm.entries
@@ -196,6 +206,17 @@ func (sm *SparseBinaryMatrix) GetRowIndices(row int) []int {
 	return result
 }
 
+//Returns a columns "on" indices
+func (sm *SparseBinaryMatrix) GetColIndices(col int) []int {
+	result := []int{}
+	for i := 0; i < len(sm.entries); i++ {
+		if sm.entries[i].Col == col {
+			result = append(result, sm.entries[i].Row)
+		}
+	}
+	return result
+}
+
 //Sets a sparse row from dense representation
 func (sm *SparseBinaryMatrix) SetRowFromDense(row int, denseRow []bool) {
 	sm.validateRowCol(row, len(denseRow))
@@ -260,20 +281,123 @@ func (sm *SparseBinaryMatrix) TotalNonZeroCount() int {
 }
 
 // Ors 2 matrices
-func (sm *SparseBinaryMatrix) Or(sm2 *SparseBinaryMatrix) *SparseBinaryMatrix {
-	result := NewSparseBinaryMatrix(sm.Height, sm.Width)
+func (sm *SparseBinaryMatrix) Or(sm2 BinaryMatrix) *SparseBinaryMatrix {
+	result := sm.Copy()
+	result.OrInPlace(sm2)
+	return result
+}
+
+// Ands 2 matrices
+func (sm *SparseBinaryMatrix) And(sm2 BinaryMatrix) *SparseBinaryMatrix {
+	result := sm.Copy()
+	result.AndInPlace(sm2)
+	return result
+}
+
+// Returns entries that are true in this matrix and false in sm2
+func (sm *SparseBinaryMatrix) AndNot(sm2 BinaryMatrix) *SparseBinaryMatrix {
+	result := sm.Copy()
+	result.AndNotInPlace(sm2)
+	return result
+}
+
+// Xors 2 matrices
+func (sm *SparseBinaryMatrix) Xor(sm2 BinaryMatrix) *SparseBinaryMatrix {
+	result := sm.Copy()
+	result.XorInPlace(sm2)
+	return result
+}
+
+//Sets all entries that are true in sm2
+func (sm *SparseBinaryMatrix) OrInPlace(sm2 BinaryMatrix) {
+	validateSameSize(sm, sm2)
+	own := entrySet(sm)
+	for _, val := range sm2.Entries() {
+		if !own[val] {
+			own[val] = true
+			sm.entries = append(sm.entries, val)
+		}
+	}
+}
 
+//Keeps only entries that are also true in sm2
+func (sm *SparseBinaryMatrix) AndInPlace(sm2 BinaryMatrix) {
+	validateSameSize(sm, sm2)
+	sm.filter(entrySet(sm2), true)
+}
+
+//Clears all entries that are true in sm2
+func (sm *SparseBinaryMatrix) AndNotInPlace(sm2 BinaryMatrix) {
+	validateSameSize(sm, sm2)
+	sm.filter(entrySet(sm2), false)
+}
+
+//Flips all entries that are true in sm2
+func (sm *SparseBinaryMatrix) XorInPlace(sm2 BinaryMatrix) {
+	validateSameSize(sm, sm2)
+	own := entrySet(sm)
+	other := entrySet(sm2)
+	sm.filter(other, false)
+	for _, val := range sm2.Entries() {
+		if !own[val] {
+			sm.entries = append(sm.entries, val)
+		}
+	}
+}
+
+//Keeps entries whose membership in set equals keep
+func (sm *SparseBinaryMatrix) filter(set map[SparseEntry]bool, keep bool) {
+	result := sm.entries[:0]
 	for _, val := range sm.entries {
-		result.Set(val.Row, val.Col, true)
+		if set[val] == keep {
+			result = append(result, val)
+		}
 	}
+	sm.entries = result
+}
 
-	for _, val := range sm2.entries {
-		result.Set(val.Row, val.Col, true)
+//Returns transposed copy of matrix
+func (sm *SparseBinaryMatrix) Transpose() *SparseBinaryMatrix {
+	result := NewSparseBinaryMatrix(sm.Width, sm.Height)
+	result.entries = make([]SparseEntry, len(sm.entries))
+	for idx, val := range sm.entries {
+		result.entries[idx] = SparseEntry{Row: val.Col, Col: val.Row}
+	}
+	return result
+}
+
+//Returns the number of true entries in each row
+func (sm *SparseBinaryMatrix) RowSums() []int {
+	return RowSums(sm)
+}
+
+//Returns the number of true entries in each column
+func (sm *SparseBinaryMatrix) ColSums() []int {
+	return ColSums(sm)
+}
+
+//Returns copy of the rows [rowStart,rowEnd) and cols [colStart,colEnd)
+func (sm *SparseBinaryMatrix) SubMatrix(rowStart, rowEnd, colStart, colEnd int) *SparseBinaryMatrix {
+	if rowStart < 0 || rowEnd > sm.Height || rowStart > rowEnd ||
+		colStart < 0 || colEnd > sm.Width || colStart > colEnd {
+		panic("Sub matrix bounds out of range")
 	}
 
+	result := NewSparseBinaryMatrix(rowEnd-rowStart, colEnd-colStart)
+	for _, val := range sm.entries {
+		if val.Row >= rowStart && val.Row < rowEnd &&
+			val.Col >= colStart && val.Col < colEnd {
+			result.entries = append(result.entries, SparseEntry{Row: val.Row - rowStart, Col: val.Col - colStart})
+		}
+	}
 	return result
 }
 
+//Returns true if sm2 is the same size with the same true entries
+func (sm *SparseBinaryMatrix) Equals(sm2 BinaryMatrix) bool {
+	return BinaryMatrixEqual(sm, sm2)
+}
+
 //Clears  all entries
 func (sm *SparseBinaryMatrix) Clear() {
 	sm.entries = nil
@@ -0,0 +1,59 @@
+package htm
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSparseFloat32GetSet(t *testing.T) {
+	sm := NewSparseFloat32Matrix(4, 6)
+	sm.Set(1, 4, 0.5)
+	sm.Set(1, 2, 0.25)
+	sm.Set(1, 5, 0.75)
+	sm.Set(3, 0, 1.0)
+
+	assert.Equal(t, float32(0.5), sm.Get(1, 4))
+	assert.Equal(t, float32(0), sm.Get(1, 3))
+	assert.Equal(t, []int{2, 4, 5}, sm.GetRowIndices(1))
+	assert.Equal(t, []float32{0, 0, 0.25, 0, 0.5, 0.75}, sm.GetDenseRow(1))
+
+	//setting zero removes the entry
+	sm.Set(1, 4, 0)
+	assert.Equal(t, []int{2, 5}, sm.GetRowIndices(1))
+	assert.Equal(t, 3, sm.TotalNonZeroCount())
+
+	assert.Panics(t, func() { sm.Get(4, 0) })
+	assert.Panics(t, func() { sm.Set(0, 6, 1) })
+}
+
+func TestSparseFloat32RowOperations(t *testing.T) {
+	sm := NewSparseFloat32MatrixFromDense([][]float32{
+		{0, 0.5, 0, 0.25, 0, 0.75},
+		{0, 0, 0, 0, 0, 0},
+	})
+
+	sm.IncrementRowWhere(0, []int{0, 3, 4, 5}, []bool{true, false, false, false, false, false},
+		0.25, -0.25)
+	assert.Equal(t, []int{0, 1, 4, 5}, sm.GetRowIndices(0))
+	assert.Equal(t, []float32{0.25, 0.5, 0, 0, -0.25, 0.5}, sm.GetDenseRow(0))
+
+	sm.AddToRow(0, []int{3, 1}, 0.25)
+	assert.Equal(t, 2, sm.CountRowAbove(0, 0.25))
+
+	sm.ClipRow(0, 0, 0.5)
+	assert.Equal(t, []float32{0.25, 0.5, 0, 0.25, 0, 0.5}, sm.GetDenseRow(0))
+	assert.Equal(t, []int{1, 5}, sm.ThresholdRow(0, 0.5))
+
+	sm.TrimRow(0, 0.25)
+	assert.Equal(t, []int{1, 5}, sm.GetRowIndices(0))
+
+	connected := NewPackedBinaryMatrix(2, 6)
+	connected.Set(1, 2, true)
+	sm.ThresholdToBinary(0.5, connected)
+	assert.Equal(t, []int{1, 5}, connected.GetRowIndices(0))
+	assert.Equal(t, 0, len(connected.GetRowIndices(1)))
+
+	c := sm.Copy()
+	c.Set(0, 0, 0.75)
+	assert.Equal(t, float32(0), sm.Get(0, 0))
+}
@@ -0,0 +1,368 @@
+package htm
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+//Non-zero values of a single row, cols is kept sorted
+type sparseFloatRow struct {
+	cols   []int
+	values []float64
+}
+
+/*
+ Sparse matrix of float64 values, storing only the non-zero entries of
+each row ordered by column. Row reads and row replacement are linear in
+the number of non-zero entries and single entry lookups use a binary
+search. Used to hold the spatial pooler permanences, where every update
+touches a single column (row) at a time.
+*/
+type SparseFloatMatrix struct {
+	Width  int
+	Height int
+	rows   []sparseFloatRow
+}
+
+//Create new sparse float matrix of specified size
+func NewSparseFloatMatrix(height, width int) *SparseFloatMatrix {
+	m := &SparseFloatMatrix{}
+	m.Height = height
+	m.Width = width
+	m.rows = make([]sparseFloatRow, height)
+	return m
+}
+
+//Create sparse float matrix from specified dense matrix
+func NewSparseFloatMatrixFromDense(values [][]float64) *SparseFloatMatrix {
+	if len(values) < 1 {
+		panic("No values specified.")
+	}
+
+	m := NewSparseFloatMatrix(len(values), len(values[0]))
+	for r := 0; r < m.Height; r++ {
+		m.SetRowFromDense(r, values[r])
+	}
+	return m
+}
+
+//Create sparse float matrix from specified flattened dense matrix
+func NewSparseFloatMatrixFromDense1D(values []float64, rows, cols int) *SparseFloatMatrix {
+	if len(values) < 1 {
+		panic("No values specified.")
+	}
+	if len(values) != rows*cols {
+		panic("Invalid size")
+	}
+
+	m := NewSparseFloatMatrix(rows, cols)
+	for r := 0; r < m.Height; r++ {
+		m.SetRowFromDense(r, values[r*cols:(r*cols)+cols])
+	}
+	return m
+}
+
+//Returns number of rows
+func (sm *SparseFloatMatrix) Rows() int {
+	return sm.Height
+}
+
+//Returns number of columns
+func (sm *SparseFloatMatrix) Cols() int {
+	return sm.Width
+}
+
+//Get value at row,col position
+func (sm *SparseFloatMatrix) Get(row int, col int) float64 {
+	sm.validateRowCol(row, col)
+	r := &sm.rows[row]
+	idx := sort.SearchInts(r.cols, col)
+	if idx < len(r.cols) && r.cols[idx] == col {
+		return r.values[idx]
+	}
+	return 0
+}
+
+//Set value at row,col position, setting 0 removes the entry
+func (sm *SparseFloatMatrix) Set(row int, col int, value float64) {
+	sm.validateRowCol(row, col)
+	r := &sm.rows[row]
+	idx := sort.SearchInts(r.cols, col)
+	found := idx < len(r.cols) && r.cols[idx] == col
+
+	switch {
+	case found && value == 0:
+		r.cols = append(r.cols[:idx], r.cols[idx+1:]...)
+		r.values = append(r.values[:idx], r.values[idx+1:]...)
+	case found:
+		r.values[idx] = value
+	case value != 0:
+		r.cols = append(r.cols, 0)
+		copy(r.cols[idx+1:], r.cols[idx:])
+		r.cols[idx] = col
+		r.values = append(r.values, 0)
+		copy(r.values[idx+1:], r.values[idx:])
+		r.values[idx] = value
+	}
+}
+
+//Returns dense row
+func (sm *SparseFloatMatrix) GetDenseRow(row int) []float64 {
+	sm.validateRow(row)
+	result := make([]float64, sm.Width)
+	r := sm.rows[row]
+	for i, col := range r.cols {
+		result[col] = r.values[i]
+	}
+	return result
+}
+
+//Returns the columns of a rows non-zero entries in ascending order
+func (sm *SparseFloatMatrix) GetRowIndices(row int) []int {
+	sm.validateRow(row)
+	result := make([]int, len(sm.rows[row].cols))
+	copy(result, sm.rows[row].cols)
+	return result
+}
+
+//Replaces a row with the non-zero values of a dense row
+func (sm *SparseFloatMatrix) SetRowFromDense(row int, denseRow []float64) {
+	sm.validateRow(row)
+	if len(denseRow) != sm.Width {
+		panic("Row width does not match matrix")
+	}
+
+	r := &sm.rows[row]
+	r.cols = r.cols[:0]
+	r.values = r.values[:0]
+	for col, val := range denseRow {
+		if val != 0 {
+			r.cols = append(r.cols, col)
+			r.values = append(r.values, val)
+		}
+	}
+}
+
+//Adds delta to the specified columns of a row
+func (sm *SparseFloatMatrix) AddToRow(row int, indices []int, delta float64) {
+	sm.incrementRow(row, indices, func(col int) float64 {
+		return delta
+	})
+}
+
+/*
+ Adds onDelta to the specified columns of a row whose input bit is on
+and offDelta to the ones whose input bit is off. Only the columns in
+indices are touched, entries outside of it keep their value.
+*/
+func (sm *SparseFloatMatrix) IncrementRowWhere(row int, indices []int, input []bool,
+	onDelta, offDelta float64) {
+	if len(input) != sm.Width {
+		panic("Input width does not match matrix")
+	}
+
+	sm.incrementRow(row, indices, func(col int) float64 {
+		if input[col] {
+			return onDelta
+		}
+		return offDelta
+	})
+}
+
+/*
+ Adds delta(col) to the specified columns of a row by merging indices
+with the rows non-zero entries. Existing entries are updated in place,
+the row is only reallocated when new entries are needed.
+*/
+func (sm *SparseFloatMatrix) incrementRow(row int, indices []int, delta func(col int) float64) {
+	sm.validateRow(row)
+	if len(indices) == 0 {
+		return
+	}
+	if !sort.IntsAreSorted(indices) {
+		sorted := make([]int, len(indices))
+		copy(sorted, indices)
+		sort.Ints(sorted)
+		indices = sorted
+	}
+	if indices[0] < 0 || indices[len(indices)-1] >= sm.Width {
+		panic("Specified col is out of bounds.")
+	}
+
+	r := &sm.rows[row]
+	missing := 0
+	i := 0
+	for _, col := range indices {
+		for i < len(r.cols) && r.cols[i] < col {
+			i++
+		}
+		if i < len(r.cols) && r.cols[i] == col {
+			r.values[i] += delta(col)
+		} else {
+			missing++
+		}
+	}
+
+	if missing > 0 {
+		cols := make([]int, 0, len(r.cols)+missing)
+		values := make([]float64, 0, len(r.cols)+missing)
+		i = 0
+		for _, col := range indices {
+			for i < len(r.cols) && r.cols[i] < col {
+				cols = append(cols, r.cols[i])
+				values = append(values, r.values[i])
+				i++
+			}
+			if i < len(r.cols) && r.cols[i] == col {
+				continue
+			}
+			cols = append(cols, col)
+			values = append(values, delta(col))
+		}
+		cols = append(cols, r.cols[i:]...)
+		values = append(values, r.values[i:]...)
+		r.cols = cols
+		r.values = values
+	}
+
+	sm.compactRow(row)
+}
+
+//Clips the non-zero values of a row to [min,max]
+func (sm *SparseFloatMatrix) ClipRow(row int, min, max float64) {
+	sm.validateRow(row)
+	r := sm.rows[row]
+	for i, val := range r.values {
+		if val < min {
+			r.values[i] = min
+		} else if val > max {
+			r.values[i] = max
+		}
+	}
+	sm.compactRow(row)
+}
+
+//Clips the non-zero values of every row to [min,max]
+func (sm *SparseFloatMatrix) Clip(min, max float64) {
+	for r := 0; r < sm.Height; r++ {
+		sm.ClipRow(r, min, max)
+	}
+}
+
+//Removes entries that became zero
+func (sm *SparseFloatMatrix) compactRow(row int) {
+	r := &sm.rows[row]
+	n := 0
+	for i, val := range r.values {
+		if val != 0 {
+			r.cols[n] = r.cols[i]
+			r.values[n] = val
+			n++
+		}
+	}
+	r.cols = r.cols[:n]
+	r.values = r.values[:n]
+}
+
+//Removes the entries of a row whose value is <= threshold
+func (sm *SparseFloatMatrix) TrimRow(row int, threshold float64) {
+	sm.validateRow(row)
+	r := sm.rows[row]
+	for i, val := range r.values {
+		if val <= threshold {
+			r.values[i] = 0
+		}
+	}
+	sm.compactRow(row)
+}
+
+//Returns the number of entries of a row whose value is > threshold
+func (sm *SparseFloatMatrix) CountRowAbove(row int, threshold float64) int {
+	sm.validateRow(row)
+	count := 0
+	for _, val := range sm.rows[row].values {
+		if val > threshold {
+			count++
+		}
+	}
+	return count
+}
+
+//Returns the columns of a row whose value is >= threshold, in ascending order
+func (sm *SparseFloatMatrix) ThresholdRow(row int, threshold float64) []int {
+	sm.validateRow(row)
+	var result []int
+	r := sm.rows[row]
+	for i, val := range r.values {
+		if val >= threshold {
+			result = append(result, r.cols[i])
+		}
+	}
+	return result
+}
+
+//Sets every entry of out that is >= threshold in this matrix, other entries
+//are cleared
+func (sm *SparseFloatMatrix) ThresholdToBinary(threshold float64, out BinaryMatrix) {
+	if out.Rows() != sm.Height || out.Cols() != sm.Width {
+		panic("Matrix sizes do not match")
+	}
+	for r := 0; r < sm.Height; r++ {
+		out.ReplaceRowByIndices(r, sm.ThresholdRow(r, threshold))
+	}
+}
+
+//Returns total non-zero entries
+func (sm *SparseFloatMatrix) TotalNonZeroCount() int {
+	count := 0
+	for _, r := range sm.rows {
+		count += len(r.cols)
+	}
+	return count
+}
+
+//Copys a matrix
+func (sm *SparseFloatMatrix) Copy() *SparseFloatMatrix {
+	if sm == nil {
+		return nil
+	}
+
+	result := NewSparseFloatMatrix(sm.Height, sm.Width)
+	for i, r := range sm.rows {
+		result.rows[i].cols = make([]int, len(r.cols))
+		copy(result.rows[i].cols, r.cols)
+		result.rows[i].values = make([]float64, len(r.values))
+		copy(result.rows[i].values, r.values)
+	}
+	return result
+}
+
+func (sm *SparseFloatMatrix) ToString() string {
+	var buffer bytes.Buffer
+
+	for r := 0; r < sm.Height; r++ {
+		for c, val := range sm.GetDenseRow(r) {
+			if c > 0 {
+				buffer.WriteByte(' ')
+			}
+			buffer.WriteString(fmt.Sprintf("%.3f", val))
+		}
+		buffer.WriteByte('\n')
+	}
+
+	return buffer.String()
+}
+
+func (sm *SparseFloatMatrix) validateRow(row int) {
+	if row < 0 || row >= sm.Height {
+		panic("Specified row is out of bounds.")
+	}
+}
+
+func (sm *SparseFloatMatrix) validateRowCol(row int, col int) {
+	sm.validateRow(row)
+	if col < 0 || col >= sm.Width {
+		panic("Specified col is out of bounds.")
+	}
+}
@@ -0,0 +1,226 @@
+package htm
+
+import (
+	"github.com/nupic-community/htm/utils"
+	"github.com/skelterjohn/go.matrix"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestSparseFloatGetSet(t *testing.T) {
+	sm := NewSparseFloatMatrix(4, 6)
+	sm.Set(1, 4, 0.5)
+	sm.Set(1, 2, 0.25)
+	sm.Set(1, 5, 0.75)
+	sm.Set(3, 0, 1.0)
+
+	assert.Equal(t, 0.5, sm.Get(1, 4))
+	assert.Equal(t, 0.0, sm.Get(1, 3))
+	assert.Equal(t, []int{2, 4, 5}, sm.GetRowIndices(1))
+	assert.Equal(t, []float64{0, 0, 0.25, 0, 0.5, 0.75}, sm.GetDenseRow(1))
+
+	//setting zero removes the entry
+	sm.Set(1, 4, 0)
+	assert.Equal(t, []int{2, 5}, sm.GetRowIndices(1))
+	assert.Equal(t, 3, sm.TotalNonZeroCount())
+
+	assert.Panics(t, func() { sm.Get(4, 0) })
+	assert.Panics(t, func() { sm.Set(0, 6, 1) })
+}
+
+func TestSparseFloatRowOperations(t *testing.T) {
+	sm := NewSparseFloatMatrixFromDense([][]float64{
+		{0.1, 0, 0.3, 0, 1.2},
+		{0, 0.05, 0, 0, 0},
+	})
+
+	sm.SetRowFromDense(1, []float64{0, 0.2, 0, 0.4, 0})
+	assert.Equal(t, []int{1, 3}, sm.GetRowIndices(1))
+
+	sm.IncrementRowWhere(0, []int{0, 1, 2, 3}, []bool{true, true, false, false, true}, 0.1, -0.3)
+	dense := sm.GetDenseRow(0)
+	assert.InDelta(t, 0.2, dense[0], 1e-9)
+	assert.InDelta(t, 0.1, dense[1], 1e-9)
+	assert.InDelta(t, 0.0, dense[2], 1e-9)
+	assert.InDelta(t, -0.3, dense[3], 1e-9)
+	//outside of indices is untouched
+	assert.Equal(t, 1.2, dense[4])
+
+	sm.ClipRow(0, 0, 1)
+	assert.Equal(t, []int{0, 1, 4}, sm.GetRowIndices(0))
+	assert.Equal(t, 1.0, sm.Get(0, 4))
+
+	sm.AddToRow(1, []int{0, 1}, 0.3)
+	assert.InDelta(t, 0.3, sm.Get(1, 0), 1e-9)
+	assert.InDelta(t, 0.5, sm.Get(1, 1), 1e-9)
+
+	assert.Equal(t, []int{0, 4}, sm.ThresholdRow(0, 0.15))
+	assert.Equal(t, []int{1, 3}, sm.ThresholdRow(1, 0.4))
+
+	connected := NewPackedBinaryMatrix(2, 5)
+	connected.Set(0, 2, true)
+	sm.ThresholdToBinary(0.3, connected)
+	assert.Equal(t, []int{4}, connected.GetRowIndices(0))
+	assert.Equal(t, []int{0, 1, 3}, connected.GetRowIndices(1))
+
+	c := sm.Copy()
+	c.Set(0, 0, 0.9)
+	assert.NotEqual(t, 0.9, sm.Get(0, 0))
+
+	sm.Clip(0.35, 0.45)
+	assert.Equal(t, []float64{0.35, 0.35, 0, 0, 0.45}, sm.GetDenseRow(0))
+}
+
+func TestSparseFloatIncrementRow(t *testing.T) {
+	sm := NewSparseFloatMatrixFromDense([][]float64{
+		{0, 0.5, 0, 0.25, 0, 0.75},
+	})
+
+	//new entries are merged in, entries that become zero are removed
+	sm.IncrementRowWhere(0, []int{0, 3, 4, 5}, []bool{true, false, false, false, false, false},
+		0.25, -0.25)
+	assert.Equal(t, []int{0, 1, 4, 5}, sm.GetRowIndices(0))
+	assert.Equal(t, []float64{0.25, 0.5, 0, 0, -0.25, 0.5}, sm.GetDenseRow(0))
+
+	//indices don't have to be ordered
+	sm.AddToRow(0, []int{3, 1}, 0.25)
+	assert.Equal(t, []float64{0.25, 0.75, 0, 0.25, -0.25, 0.5}, sm.GetDenseRow(0))
+	assert.Panics(t, func() { sm.AddToRow(0, []int{6}, 0.25) })
+
+	assert.Equal(t, 2, sm.CountRowAbove(0, 0.25))
+	sm.TrimRow(0, 0.25)
+	assert.Equal(t, []int{1, 5}, sm.GetRowIndices(0))
+}
+
+//Spatial pooler and inputs with their winning columns shared by the
+//permanence update benchmarks
+func adaptSynapsesWorkload(numInputs, numColumns int) (*SpatialPooler, [][]bool, [][]int) {
+	spParams := NewSpParams()
+	spParams.InputDimensions = []int{numInputs}
+	spParams.ColumnDimensions = []int{numColumns}
+	spParams.PotentialRadius = numInputs
+	spParams.GlobalInhibition = true
+	spParams.NumActiveColumnsPerInhArea = 20
+	spParams.Seed = 42
+	sp := NewSpatialPooler(spParams)
+
+	r := rand.New(rand.NewSource(42))
+	inputs := make([][]bool, 32)
+	activeColumns := make([][]int, len(inputs))
+	for i := range inputs {
+		inputs[i] = make([]bool, sp.numInputs)
+		for j := range inputs[i] {
+			inputs[i][j] = r.Intn(50) == 0
+		}
+		activeColumns[i] = r.Perm(sp.numColumns)[:20]
+		sort.Ints(activeColumns[i])
+	}
+	return sp, inputs, activeColumns
+}
+
+//Copies the spatial poolers permanences into a go.matrix SparseMatrix
+func goMatrixPermanences(sp *SpatialPooler) *matrix.SparseMatrix {
+	elms := make(map[int]float64, sp.permanences.TotalNonZeroCount())
+	result := matrix.MakeSparseMatrix(elms, sp.numColumns, sp.numInputs)
+	for r := 0; r < sp.numColumns; r++ {
+		for _, c := range sp.permanences.GetRowIndices(r) {
+			result.Set(r, c, sp.permanences.Get(r, c))
+		}
+	}
+	return result
+}
+
+//adaptSynapses as it was when permanences were stored in a go.matrix
+//SparseMatrix
+func adaptSynapsesGoMatrix(sp *SpatialPooler, permanences *matrix.SparseMatrix,
+	inputVector []bool, activeColumns []int) {
+	var inputIndices []int
+	for i, val := range inputVector {
+		if val {
+			inputIndices = append(inputIndices, i)
+		}
+	}
+
+	permChanges := make([]float64, sp.numInputs)
+	utils.FillSliceFloat64(permChanges, -1*sp.SynPermInactiveDec)
+	for _, val := range inputIndices {
+		permChanges[val] = sp.SynPermActiveInc
+	}
+
+	for _, ac := range activeColumns {
+		perm := make([]float64, sp.numInputs)
+		mask := sp.potentialPools.GetRowIndices(ac)
+		for j := 0; j < sp.numInputs; j++ {
+			if utils.ContainsInt(j, mask) {
+				perm[j] = permChanges[j] + permanences.Get(ac, j)
+			} else {
+				perm[j] = permanences.Get(ac, j)
+			}
+
+		}
+
+		sp.raisePermanenceToThreshold(perm, mask)
+		var newConnected []int
+		for i := 0; i < len(perm); i++ {
+			if perm[i] <= sp.SynPermTrimThreshold {
+				perm[i] = 0
+				continue
+			}
+			if perm[i] < sp.SynPermMin {
+				perm[i] = sp.SynPermMin
+			}
+			if perm[i] > sp.SynPermMax {
+				perm[i] = sp.SynPermMax
+			}
+			if perm[i] >= sp.SynPermConnected {
+				newConnected = append(newConnected, i)
+			}
+		}
+
+		for i := 0; i < len(perm); i++ {
+			permanences.Set(ac, i, perm[i])
+		}
+		sp.connectedSynapses.ReplaceRowByIndices(ac, newConnected)
+		sp.connectedCounts[ac] = len(newConnected)
+	}
+}
+
+func TestAdaptSynapsesMatchesGoMatrix(t *testing.T) {
+	sp, inputs, activeColumns := adaptSynapsesWorkload(256, 128)
+	permanences := goMatrixPermanences(sp)
+	sp2, _, _ := adaptSynapsesWorkload(256, 128)
+
+	for i := range inputs {
+		sp.adaptSynapses(inputs[i], activeColumns[i])
+		adaptSynapsesGoMatrix(sp2, permanences, inputs[i], activeColumns[i])
+	}
+
+	for r := 0; r < sp.numColumns; r++ {
+		for c := 0; c < sp.numInputs; c++ {
+			assert.Equal(t, permanences.Get(r, c), sp.permanences.Get(r, c))
+		}
+	}
+	assert.Equal(t, sp2.connectedCounts, sp.connectedCounts)
+}
+
+func BenchmarkAdaptSynapsesGoMatrix(b *testing.B) {
+	sp, inputs, activeColumns := adaptSynapsesWorkload(1024, 1024)
+	permanences := goMatrixPermanences(sp)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		adaptSynapsesGoMatrix(sp, permanences, inputs[i%len(inputs)],
+			activeColumns[i%len(activeColumns)])
+	}
+}
+
+func BenchmarkAdaptSynapsesSparseFloat(b *testing.B) {
+	sp, inputs, activeColumns := adaptSynapsesWorkload(1024, 1024)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sp.adaptSynapses(inputs[i%len(inputs)], activeColumns[i%len(activeColumns)])
+	}
+}
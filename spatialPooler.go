@@ -1,13 +1,21 @@
 package htm
 
 import (
-	"fmt"
 	"github.com/cznic/mathutil"
 	"github.com/nupic-community/htm/utils"
-	"github.com/skelterjohn/go.matrix"
 	"math"
 	"math/rand"
-	"sort"
+)
+
+type BoostMode int
+
+const (
+	//Linear boost between MaxBoost and 1 driven by the min active duty cycles
+	BoostLinear BoostMode = 0
+	//Exponential boost towards the target density scaled by BoostStrength
+	BoostExponential BoostMode = 1
+	//Boosting disabled, all boost factors stay at 1
+	BoostOff BoostMode = 2
 )
 
 type SpatialPooler struct {
@@ -17,6 +25,8 @@ type SpatialPooler struct {
 	InputDimensions            []int
 	PotentialRadius            int
 	PotentialPct               float64
+	WrapAround                 bool
+	Topology                   *Topology
 	GlobalInhibition           bool
 	NumActiveColumnsPerInhArea int
 	LocalAreaDensity           float64
@@ -29,6 +39,8 @@ type SpatialPooler struct {
 	MinPctActiveDutyCycles     float64
 	DutyCyclePeriod            int
 	MaxBoost                   float64
+	BoostMode                  BoostMode
+	BoostStrength              float64
 	SpVerbosity                int
 
 	// Extra parameter settings
@@ -46,11 +58,11 @@ type SpatialPooler struct {
 	//random seed
 	Seed int
 
-	potentialPools *DenseBinaryMatrix
-	permanences    *matrix.SparseMatrix
+	potentialPools *PackedBinaryMatrix
+	permanences    *SparseFloatMatrix
 	tieBreaker     []float64
 
-	connectedSynapses *DenseBinaryMatrix
+	connectedSynapses *PackedBinaryMatrix
 	//redundant
 	connectedCounts []int
 
@@ -62,7 +74,9 @@ type SpatialPooler struct {
 
 	inhibitionRadius int
 
-	spVerbosity int
+	logger   utils.Logger
+	observer Observer
+	rand     *rand.Rand
 }
 
 type SpParams struct {
@@ -70,6 +84,8 @@ type SpParams struct {
 	ColumnDimensions           []int
 	PotentialRadius            int
 	PotentialPct               float64
+	WrapAround                 bool
+	Topology                   *Topology
 	GlobalInhibition           bool
 	LocalAreaDensity           float64
 	NumActiveColumnsPerInhArea int
@@ -81,8 +97,14 @@ type SpParams struct {
 	MinPctActiveDutyCycle      float64
 	DutyCyclePeriod            int
 	MaxBoost                   float64
+	BoostMode                  BoostMode
+	BoostStrength              float64
 	Seed                       int
 	SpVerbosity                int
+	//Receives diagnostic messages, nil discards them
+	Logger utils.Logger
+	//Notified after every compute, may be nil
+	Observer Observer
 }
 
 //Initializes default spatial pooler params
@@ -93,6 +115,7 @@ func NewSpParams() SpParams {
 	sp.ColumnDimensions = []int{64, 64}
 	sp.PotentialRadius = 16
 	sp.PotentialPct = 0.5
+	sp.WrapAround = true
 	sp.GlobalInhibition = false
 	sp.LocalAreaDensity = -1.0
 	sp.NumActiveColumnsPerInhArea = 10.0
@@ -104,6 +127,8 @@ func NewSpParams() SpParams {
 	sp.MinPctActiveDutyCycle = 0.001
 	sp.DutyCyclePeriod = 1000
 	sp.MaxBoost = 10.0
+	sp.BoostMode = BoostLinear
+	sp.BoostStrength = 0.0
 	sp.Seed = -1
 	sp.SpVerbosity = 0
 
@@ -131,6 +156,10 @@ func NewSpatialPooler(spParams SpParams) *SpatialPooler {
 	sp.ColumnDimensions = spParams.ColumnDimensions
 	sp.PotentialRadius = int(mathutil.Min(spParams.PotentialRadius, sp.numInputs))
 	sp.PotentialPct = spParams.PotentialPct
+	sp.WrapAround = spParams.WrapAround
+	if spParams.Topology != nil {
+		sp.Topology = spParams.Topology.normalize(sp.InputDimensions, spParams.PotentialRadius, sp.WrapAround)
+	}
 	sp.GlobalInhibition = spParams.GlobalInhibition
 	sp.LocalAreaDensity = spParams.LocalAreaDensity
 	sp.NumActiveColumnsPerInhArea = spParams.NumActiveColumnsPerInhArea
@@ -143,8 +172,15 @@ func NewSpatialPooler(spParams SpParams) *SpatialPooler {
 	sp.MinPctActiveDutyCycles = spParams.MinPctActiveDutyCycle
 	sp.DutyCyclePeriod = spParams.DutyCyclePeriod
 	sp.MaxBoost = spParams.MaxBoost
+	sp.BoostMode = spParams.BoostMode
+	sp.BoostStrength = spParams.BoostStrength
+	if sp.BoostStrength < 0 {
+		panic("Boost strength must be >= 0")
+	}
 	sp.Seed = spParams.Seed
 	sp.SpVerbosity = spParams.SpVerbosity
+	sp.logger = utils.WithFields(spParams.Logger, utils.Field("component", "sp"))
+	sp.observer = spParams.Observer
 
 	// Extra parameter settings
 	sp.SynPermMin = 0
@@ -177,7 +213,7 @@ func NewSpatialPooler(spParams SpParams) *SpatialPooler {
 		     class, to reduce memory footprint and compuation time of algorithms that
 		     require iterating over the data strcuture.
 	*/
-	sp.potentialPools = NewDenseBinaryMatrix(sp.numColumns, sp.numInputs)
+	sp.potentialPools = NewPackedBinaryMatrix(sp.numColumns, sp.numInputs)
 
 	/*
 			 Initialize the permanences for each column. Similar to the
@@ -190,9 +226,7 @@ func NewSpatialPooler(spParams SpParams) *SpatialPooler {
 		     structure. This permanence matrix is only allowed to have non-zero
 		     elements where the potential pool is non-zero.
 	*/
-	//Assumes 70% sparsity
-	elms := make(map[int]float64, int(float64(sp.numColumns*sp.numInputs)*0.3))
-	sp.permanences = matrix.MakeSparseMatrix(elms, sp.numColumns, sp.numInputs)
+	sp.permanences = NewSparseFloatMatrix(sp.numColumns, sp.numInputs)
 
 	/*
 			 Initialize a tiny random tie breaker. This is used to determine winning
@@ -201,7 +235,7 @@ func NewSpatialPooler(spParams SpParams) *SpatialPooler {
 
 	sp.tieBreaker = make([]float64, sp.numColumns)
 	for i := 0; i < len(sp.tieBreaker); i++ {
-		sp.tieBreaker[i] = 0.01 * rand.Float64()
+		sp.tieBreaker[i] = 0.01 * sp.random().Float64()
 	}
 
 	/*
@@ -212,7 +246,7 @@ func NewSpatialPooler(spParams SpParams) *SpatialPooler {
 		     this information is readily available from the 'permanence' matrix,
 		     it is stored separately for efficiency purposes.
 	*/
-	sp.connectedSynapses = NewDenseBinaryMatrix(sp.numColumns, sp.numInputs)
+	sp.connectedSynapses = NewPackedBinaryMatrix(sp.numColumns, sp.numInputs)
 
 	/*
 			 Stores the number of connected synapses for each column. This is simply
@@ -229,7 +263,12 @@ func NewSpatialPooler(spParams SpParams) *SpatialPooler {
 	*/
 
 	for i := 0; i < sp.numColumns; i++ {
-		potential := sp.mapPotential(i, true)
+		var potential []bool
+		if sp.Topology != nil {
+			potential = sp.mapPotentialTopology(i)
+		} else {
+			potential = sp.mapPotential(i, sp.WrapAround)
+		}
 		sp.potentialPools.ReplaceRow(i, potential)
 		perm := sp.initPermanence(potential, sp.InitConnectedPct)
 		sp.updatePermanencesForColumn(perm, i, true)
@@ -254,8 +293,8 @@ func NewSpatialPooler(spParams SpParams) *SpatialPooler {
 	sp.inhibitionRadius = 0
 	sp.updateInhibitionRadius(sp.avgConnectedSpanForColumnND, sp.avgColumnsPerInput)
 
-	if sp.spVerbosity > 0 {
-		sp.printParameters()
+	if sp.SpVerbosity > 0 {
+		sp.logParameters()
 	}
 
 	return &sp
@@ -288,8 +327,8 @@ what are the indices of the input vector that are located within the
 column's potential pool. The return value is a list containing the indices
 of the input bits. The current implementation of the base class only
 supports a 1 dimensional topology of columsn with a 1 dimensional topology
-of inputs. To extend this class to support 2-D topology you will need to
-override this method. Examples of the expected output of this method:
+of inputs. For other topologies set SpParams.Topology, see
+mapPotentialTopology. Examples of the expected output of this method:
 * If the potentialRadius is greater than or equal to the entire input
 space, (global visibility), then this method returns an array filled with
 all the indices
@@ -339,7 +378,7 @@ func (sp *SpatialPooler) mapPotential(index int, wrapAround bool) []bool {
 
 	//shuffle indices
 	for i := range indices {
-		j := rand.Intn(i + 1)
+		j := sp.random().Intn(i + 1)
 		indices[i], indices[j] = indices[j], indices[i]
 	}
 
@@ -354,6 +393,26 @@ func (sp *SpatialPooler) mapPotential(index int, wrapAround bool) []bool {
 	return mask
 }
 
+//Replaces the logger receiving diagnostic messages, nil discards them
+func (sp *SpatialPooler) SetLogger(logger utils.Logger) {
+	sp.logger = utils.WithFields(logger, utils.Field("component", "sp"))
+}
+
+/*
+ Returns the poolers random number generator, created from Seed on first
+use. A negative seed picks a random one.
+*/
+func (sp *SpatialPooler) random() *rand.Rand {
+	if sp.rand == nil {
+		seed := int64(sp.Seed)
+		if seed < 0 {
+			seed = rand.Int63()
+		}
+		sp.rand = rand.New(rand.NewSource(seed))
+	}
+	return sp.rand
+}
+
 /*
  Returns a randomly generated permanence value for a synapses that is
 initialized in a connected state. The basic idea here is to initialize
@@ -366,7 +425,7 @@ permanence values, but the history for this particular scheme has been lost.
 
 func (sp *SpatialPooler) initPermConnected() float64 {
 
-	p := sp.SynPermConnected + rand.Float64()*sp.SynPermActiveInc/4.0
+	p := sp.SynPermConnected + sp.random().Float64()*sp.SynPermActiveInc/4.0
 
 	// Ensure we don't have too much unnecessary precision. A full 64 bits of
 	// precision causes numerical stability issues across platforms and across
@@ -381,7 +440,7 @@ func (sp *SpatialPooler) initPermConnected() float64 {
 */
 
 func (sp *SpatialPooler) initPermNonConnected() float64 {
-	p := sp.SynPermConnected * rand.Float64()
+	p := sp.SynPermConnected * sp.random().Float64()
 
 	// Ensure we don't have too much unnecessary precision. A full 64 bits of
 	// precision causes numerical stability issues across platforms and across
@@ -419,7 +478,7 @@ func (sp *SpatialPooler) initPermanence(potential []bool, connectedPct float64)
 			continue
 		}
 		var temp float64
-		if utils.RandFloatRange(0.0, 1.0) < connectedPct {
+		if sp.random().Float64() < connectedPct {
 			temp = sp.initPermConnected()
 		} else {
 			temp = sp.initPermNonConnected()
@@ -513,34 +572,38 @@ func (sp *SpatialPooler) updatePermanencesForColumn(perm []float64, index int, r
 		sp.raisePermanenceToThreshold(perm, maskPotential)
 	}
 
-	var newConnected []int
-	for i := 0; i < len(perm); i++ {
-		if perm[i] <= sp.SynPermTrimThreshold {
-			perm[i] = 0
-			continue
-		}
+	sp.permanences.SetRowFromDense(index, perm)
+	sp.updatePermanencesForRow(index, false)
+}
 
-		//TODO: can be simplified if syn min/max are always 1/0
-		if perm[i] < sp.SynPermMin {
-			perm[i] = sp.SynPermMin
-		}
-		if perm[i] > sp.SynPermMax {
-			perm[i] = sp.SynPermMax
-		}
-		if perm[i] >= sp.SynPermConnected {
-			newConnected = append(newConnected, i)
-		}
+/*
+ Sparse counterpart of updatePermanencesForColumn, used when a column's
+new permanence values are already stored in row 'index' of the permanence
+matrix. Raises, trims and clips the row in place and updates the connected
+synapses and counts of the column without expanding it to a dense row.
+*/
+func (sp *SpatialPooler) updatePermanencesForRow(index int, raisePerm bool) {
+	if raisePerm {
+		sp.raiseRowPermanenceToThreshold(index, sp.potentialPools.GetRowIndices(index))
 	}
 
-	//TODO: replace with sparse matrix that indexes by rows
-	//sp.permanences.SetRowFromDense(index, perm)
-	for i := 0; i < len(perm); i++ {
-		sp.permanences.Set(index, i, perm[i])
-	}
+	sp.permanences.TrimRow(index, sp.SynPermTrimThreshold)
+	//TODO: can be skipped if syn min/max are always 0/1
+	sp.permanences.ClipRow(index, sp.SynPermMin, sp.SynPermMax)
+	newConnected := sp.permanences.ThresholdRow(index, sp.SynPermConnected)
 	sp.connectedSynapses.ReplaceRowByIndices(index, newConnected)
 	sp.connectedCounts[index] = len(newConnected)
 }
 
+//Sparse counterpart of raisePermanenceToThreshold, operates on row 'index'
+//of the permanence matrix
+func (sp *SpatialPooler) raiseRowPermanenceToThreshold(index int, mask []int) {
+	sp.permanences.ClipRow(index, sp.SynPermMin, sp.SynPermMax)
+	for sp.permanences.CountRowAbove(index, sp.SynPermConnected) < sp.StimulusThreshold {
+		sp.permanences.AddToRow(index, mask, sp.SynPermBelowStimulusInc)
+	}
+}
+
 /*
  This is the primary public method of the SpatialPooler class. This
  function takes a input vector and outputs the indices of the active columns.
@@ -569,7 +632,7 @@ activeArray: an array whose size is equal to the number of columns.
        with 1's at the indices of the active columns, and 0's
 	   everywhere else.
 */
-func (sp *SpatialPooler) Compute(inputVector []bool, learn bool, activeArray []bool, inhibitColumns inhibitColFunc) {
+func (sp *SpatialPooler) Compute(inputVector []bool, learn bool, activeArray []bool, inhibitColumns InhibitColFunc) {
 	if len(inputVector) != sp.numInputs {
 		panic("input != numimputs")
 	}
@@ -577,10 +640,12 @@ func (sp *SpatialPooler) Compute(inputVector []bool, learn bool, activeArray []b
 	sp.updateBookeepingVars(learn)
 	overlaps := sp.calculateOverlap(inputVector)
 	boostedOverlaps := make([]float64, len(overlaps))
-	// Apply boosting when learning is on
-	if learn {
-		for i, val := range sp.boostFactors {
-			boostedOverlaps[i] = float64(overlaps[i]) * val
+	// Apply boosting when learning is on, inference uses the raw overlaps
+	for i, val := range overlaps {
+		if learn {
+			boostedOverlaps[i] = float64(val) * sp.boostFactors[i]
+		} else {
+			boostedOverlaps[i] = float64(val)
 		}
 	}
 
@@ -611,6 +676,40 @@ func (sp *SpatialPooler) Compute(inputVector []bool, learn bool, activeArray []b
 		}
 	}
 
+	if sp.observer != nil {
+		sp.observer.SpatialPoolerStep(sp.stepEvent(learn, overlaps, activeColumns))
+	}
+
+}
+
+//Summarizes a compute step for the observer
+func (sp *SpatialPooler) stepEvent(learn bool, overlaps []int, activeColumns []int) SpStepEvent {
+	event := SpStepEvent{
+		Iteration:     sp.IterationNum,
+		Learn:         learn,
+		ActiveColumns: len(activeColumns),
+	}
+
+	if len(overlaps) > 0 {
+		event.MinOverlap, event.MaxOverlap = overlaps[0], overlaps[0]
+		sum := 0
+		for _, val := range overlaps {
+			event.MinOverlap = mathutil.Min(event.MinOverlap, val)
+			event.MaxOverlap = mathutil.Max(event.MaxOverlap, val)
+			sum += val
+		}
+		event.MeanOverlap = float64(sum) / float64(len(overlaps))
+	}
+
+	if len(sp.boostFactors) > 0 {
+		event.MinBoost, event.MaxBoost = sp.boostFactors[0], sp.boostFactors[0]
+		for _, val := range sp.boostFactors {
+			event.MinBoost = math.Min(event.MinBoost, val)
+			event.MaxBoost = math.Max(event.MaxBoost, val)
+		}
+	}
+
+	return event
 }
 
 /*
@@ -753,32 +852,10 @@ density: The fraction of columns to survive inhibition.
 func (sp *SpatialPooler) inhibitColumnsGlobal(overlaps []float64, density float64) []int {
 	//calculate num active per inhibition area
 	numActive := int(density * float64(sp.numColumns))
-	ov := make([]utils.TupleInt, len(overlaps))
-	//TODO: if overlaps is assumed to be distinct this can be
-	//  	simplified
-	//a = value, b = original index
-	for i := 0; i < len(ov); i++ {
-		ov[i].A = int(overlaps[i])
-		ov[i].B = i
-	}
-	//insert sort overlaps
-	for i := 1; i < len(ov); i++ {
-		for j := i; j > 0 && ov[j].A < ov[j-1].A; j-- {
-			tmp := ov[j]
-			ov[j] = ov[j-1]
-			ov[j-1] = tmp
-		}
-	}
-
-	result := make([]int, numActive)
-	for i := 0; i < numActive; i++ {
-		result[i] = ov[len(ov)-1-i].B
-	}
-
-	sort.Sort(sort.IntSlice(result))
 
-	//return indexes of active columns
-	return result
+	//select the top numActive columns by their full (boosted and tie broken)
+	//overlap, ties go to the higher column index
+	return utils.TopKFloat64(overlaps, numActive)
 }
 
 /*
@@ -801,11 +878,23 @@ density: The fraction of columns to survive inhibition. This
 */
 
 func (sp *SpatialPooler) inhibitColumnsLocal(overlaps []float64, density float64) []int {
+	return sp.inhibitColumnsNeighborhood(overlaps, density, func(index int) []int {
+		return sp.getNeighborsND(index, sp.ColumnDimensions, sp.inhibitionRadius, false)
+	})
+}
+
+/*
+ Performs local inhibition over an arbitrary neighborhood. Each column is
+compared against the columns returned by neighbors and survives if fewer
+than the local number of active columns beat it. This is the shared body
+of inhibitColumnsLocal and the topology aware inhibitors.
+*/
+func (sp *SpatialPooler) inhibitColumnsNeighborhood(overlaps []float64, density float64, neighbors func(int) []int) []int {
 	var activeColumns []int
 	addToWinners := utils.MaxSliceFloat64(overlaps) / 1000.0
 
 	for i := 0; i < sp.numColumns; i++ {
-		mask := sp.getNeighborsND(i, sp.ColumnDimensions, sp.inhibitionRadius, false)
+		mask := neighbors(i)
 
 		ovSlice := make([]float64, len(mask))
 		for idx, val := range mask {
@@ -829,8 +918,12 @@ func (sp *SpatialPooler) inhibitColumnsLocal(overlaps []float64, density float64
 	return activeColumns
 }
 
-type inhibitColumnsFunc func([]float64, float64) []int
-type inhibitColFunc func(overlaps []float64, inhibitColumnsGlobal, inhibitColumnsLocal inhibitColumnsFunc) []int
+//Picks active columns given a target density
+type InhibitColumnsFunc func(overlaps []float64, density float64) []int
+
+//Picks active columns from the overlaps, the built in global and local
+//strategies are passed in so implementations may delegate to them.
+type InhibitColFunc func(overlaps []float64, inhibitColumnsGlobal, inhibitColumnsLocal InhibitColumnsFunc) []int
 
 /*
  Performs inhibition. This method calculates the necessary values needed to
@@ -846,30 +939,13 @@ that are connected to input bits which are turned on.
 
 */
 
-func (sp *SpatialPooler) InhibitColumns(overlaps []float64, inhibitColumnsGlobal, inhibitColumnsLocal inhibitColumnsFunc) []int {
-	/*
-			 determine how many columns should be selected in the inhibition phase.
-		     This can be specified by either setting the 'numActiveColumnsPerInhArea'
-		     parameter of the 'localAreaDensity' parameter when initializing the class
-	*/
-	density := 0.0
-	if sp.LocalAreaDensity > 0 {
-		density = sp.LocalAreaDensity
-	} else {
-		inhibitionArea := math.Pow(float64(2*sp.inhibitionRadius+1), float64(len(sp.ColumnDimensions)))
-		inhibitionArea = math.Min(float64(sp.numColumns), inhibitionArea)
-		density = float64(sp.NumActiveColumnsPerInhArea) / inhibitionArea
-		density = math.Min(density, 0.5)
-	}
+func (sp *SpatialPooler) InhibitColumns(overlaps []float64, inhibitColumnsGlobal, inhibitColumnsLocal InhibitColumnsFunc) []int {
+	density := sp.inhibitionDensity()
 
 	// Add our fixed little bit of random noise to the scores to help break ties.
-	//overlaps += sp.tieBreaker
-	for i := 0; i < len(overlaps); i++ {
-		overlaps[i] += sp.tieBreaker[i]
-	}
+	sp.addTieBreaker(overlaps)
 
-	if sp.GlobalInhibition ||
-		sp.inhibitionRadius > utils.MaxSliceInt(sp.ColumnDimensions) {
+	if sp.useGlobalInhibition() {
 		return inhibitColumnsGlobal(overlaps, density)
 	} else {
 		return inhibitColumnsLocal(overlaps, density)
@@ -877,6 +953,35 @@ func (sp *SpatialPooler) InhibitColumns(overlaps []float64, inhibitColumnsGlobal
 
 }
 
+/*
+ Determines the fraction of columns that should be selected in the
+inhibition phase. This can be specified by either setting the
+'numActiveColumnsPerInhArea' parameter or the 'localAreaDensity' parameter
+when initializing the class
+*/
+func (sp *SpatialPooler) inhibitionDensity() float64 {
+	if sp.LocalAreaDensity > 0 {
+		return sp.LocalAreaDensity
+	}
+	inhibitionArea := math.Pow(float64(2*sp.inhibitionRadius+1), float64(len(sp.ColumnDimensions)))
+	inhibitionArea = math.Min(float64(sp.numColumns), inhibitionArea)
+	density := float64(sp.NumActiveColumnsPerInhArea) / inhibitionArea
+	return math.Min(density, 0.5)
+}
+
+//Adds the fixed per column tie breaker noise to overlaps in place
+func (sp *SpatialPooler) addTieBreaker(overlaps []float64) {
+	for i := 0; i < len(overlaps); i++ {
+		overlaps[i] += sp.tieBreaker[i]
+	}
+}
+
+//Returns true if the inhibition radius covers the whole region
+func (sp *SpatialPooler) useGlobalInhibition() bool {
+	return sp.GlobalInhibition ||
+		sp.inhibitionRadius > utils.MaxSliceInt(sp.ColumnDimensions)
+}
+
 /*
  The primary method in charge of learning. Adapts the permanence values of
 the synapses based on the input vector, and the chosen columns after
@@ -893,31 +998,11 @@ activeColumns: an array containing the indices of the columns that
 survived inhibition.
 */
 func (sp *SpatialPooler) adaptSynapses(inputVector []bool, activeColumns []int) {
-	var inputIndices []int
-	for i, val := range inputVector {
-		if val {
-			inputIndices = append(inputIndices, i)
-		}
-	}
-
-	permChanges := make([]float64, sp.numInputs)
-	utils.FillSliceFloat64(permChanges, -1*sp.SynPermInactiveDec)
-	for _, val := range inputIndices {
-		permChanges[val] = sp.SynPermActiveInc
-	}
-
 	for _, ac := range activeColumns {
-		perm := make([]float64, sp.numInputs)
 		mask := sp.potentialPools.GetRowIndices(ac)
-		for j := 0; j < sp.numInputs; j++ {
-			if utils.ContainsInt(j, mask) {
-				perm[j] = permChanges[j] + sp.permanences.Get(ac, j)
-			} else {
-				perm[j] = sp.permanences.Get(ac, j)
-			}
-
-		}
-		sp.updatePermanencesForColumn(perm, ac, true)
+		sp.permanences.IncrementRowWhere(ac, mask, inputVector,
+			sp.SynPermActiveInc, -1*sp.SynPermInactiveDec)
+		sp.updatePermanencesForRow(ac, true)
 	}
 
 }
@@ -986,25 +1071,36 @@ func (sp *SpatialPooler) bumpUpWeakColumns() {
 	}
 
 	for _, col := range weakColumns {
-		perm := make([]float64, sp.numInputs)
-		for j := 0; j < sp.numInputs; j++ {
-			perm[j] = sp.permanences.Get(col, j)
-		}
-
 		maskPotential := sp.potentialPools.GetRowIndices(col)
-		for _, mpot := range maskPotential {
-			perm[mpot] += sp.SynPermBelowStimulusInc
-		}
-		sp.updatePermanencesForColumn(perm, col, false)
+		sp.permanences.AddToRow(col, maskPotential, sp.SynPermBelowStimulusInc)
+		sp.updatePermanencesForRow(col, false)
 	}
 
 }
 
 /*
- Update the boost factors for all columns. The boost factors are used to
-increase the overlap of inactive columns to improve their chances of
-becoming active. and hence encourage participation of more columns in the
-learning process. This is a line defined as: y = mx + b boost =
+ Update the boost factors for all columns according to the pooler's
+BoostMode. The boost factors are used to increase the overlap of inactive
+columns to improve their chances of becoming active, and hence encourage
+participation of more columns in the learning process.
+*/
+func (sp *SpatialPooler) updateBoostFactors() {
+	switch sp.BoostMode {
+	case BoostExponential:
+		if sp.useGlobalInhibition() {
+			sp.updateBoostFactorsGlobal()
+		} else {
+			sp.updateBoostFactorsLocal()
+		}
+	case BoostOff:
+		utils.FillSliceFloat64(sp.boostFactors, 1.0)
+	default:
+		sp.updateBoostFactorsLinear()
+	}
+}
+
+/*
+ Linear boosting. This is a line defined as: y = mx + b boost =
 (1-maxBoost)/minDuty * dutyCycle + maxFiringBoost. Intuitively this means
 that columns that have been active enough have a boost factor of 1, meaning
 their overlap is not boosted. Columns whose active duty cycle drops too much
@@ -1025,7 +1121,7 @@ maxBoost _ |
 minActiveDutyCycle
 */
 
-func (sp *SpatialPooler) updateBoostFactors() {
+func (sp *SpatialPooler) updateBoostFactorsLinear() {
 	for i, val := range sp.minActiveDutyCycles {
 		if val > 0 {
 			sp.boostFactors[i] = ((1.0 - sp.MaxBoost) /
@@ -1041,6 +1137,36 @@ func (sp *SpatialPooler) updateBoostFactors() {
 
 }
 
+/*
+ Exponential boosting with a global target density. Columns that are
+active less often than the target density are boosted, columns that are
+active more often are suppressed:
+
+	boostFactor = exp((targetDensity - activeDutyCycle) * boostStrength)
+*/
+func (sp *SpatialPooler) updateBoostFactorsGlobal() {
+	targetDensity := sp.inhibitionDensity()
+	for i, val := range sp.activeDutyCycles {
+		sp.boostFactors[i] = math.Exp((targetDensity - val) * sp.BoostStrength)
+	}
+}
+
+/*
+ Exponential boosting with a local target density. Each column's target
+density is the mean active duty cycle of its neighborhood (including the
+column itself).
+*/
+func (sp *SpatialPooler) updateBoostFactorsLocal() {
+	for i := 0; i < sp.numColumns; i++ {
+		maskNeighbors := sp.getNeighborsND(i, sp.ColumnDimensions, sp.inhibitionRadius, false)
+		maskNeighbors = append(maskNeighbors, i)
+
+		neighborDuty := utils.SubsetSliceFloat64(sp.activeDutyCycles, maskNeighbors)
+		targetDensity := utils.SumSliceFloat64(neighborDuty) / float64(len(maskNeighbors))
+		sp.boostFactors[i] = math.Exp((targetDensity - sp.activeDutyCycles[i]) * sp.BoostStrength)
+	}
+}
+
 /*
  returns true if the enough rounds have passed to warrant updates of
  duty cycles
@@ -1255,10 +1381,13 @@ func (sp *SpatialPooler) stripNeverLearned(activeColumns []int) []int {
 	return result
 }
 
-func (sp *SpatialPooler) printParameters() {
-	fmt.Println("numInputs", sp.numInputs)
-	fmt.Println("numColumns", sp.numColumns)
-
+func (sp *SpatialPooler) logParameters() {
+	sp.logger.Log(utils.Debug, "Spatial pooler parameters",
+		utils.Field("numInputs", sp.numInputs),
+		utils.Field("numColumns", sp.numColumns),
+		utils.Field("potentialRadius", sp.PotentialRadius),
+		utils.Field("globalInhibition", sp.GlobalInhibition),
+		utils.Field("inhibitionRadius", sp.inhibitionRadius))
 }
 
 //----- Helper functions ----
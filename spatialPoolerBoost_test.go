@@ -8,7 +8,7 @@ import (
 	"github.com/zacg/testify/assert"
 	//"math/big"
 	//"github.com/stretchr/testify/mock"
-	//"math"
+	"math"
 	//"math/rand"
 	//"strconv"
 	"testing"
@@ -314,3 +314,159 @@ func BoostTest(t *testing.T) {
 	phase3(t, &bt)
 	phase4(t, &bt)
 }
+
+func TestUpdateBoostFactorsExponentialGlobal(t *testing.T) {
+	sp := SpatialPooler{}
+	sp.BoostMode = BoostExponential
+	sp.BoostStrength = 10.0
+	sp.GlobalInhibition = true
+	sp.LocalAreaDensity = 0.1
+	sp.numColumns = 4
+	sp.ColumnDimensions = []int{4}
+	sp.activeDutyCycles = []float64{0, 0.1, 0.2, 0.05}
+	sp.boostFactors = make([]float64, sp.numColumns)
+
+	sp.updateBoostFactors()
+
+	trueBoostFactors := []float64{math.Exp(1), 1, math.Exp(-1), math.Exp(0.5)}
+	for i := range trueBoostFactors {
+		assert.AlmostEqualFloat(t, trueBoostFactors[i], sp.boostFactors[i])
+	}
+
+	//zero strength disables boosting
+	sp.BoostStrength = 0
+	sp.updateBoostFactors()
+	assert.Equal(t, []float64{1, 1, 1, 1}, sp.boostFactors)
+}
+
+func TestUpdateBoostFactorsExponentialLocal(t *testing.T) {
+	sp := SpatialPooler{}
+	sp.BoostMode = BoostExponential
+	sp.BoostStrength = 10.0
+	sp.GlobalInhibition = false
+	sp.numColumns = 5
+	sp.ColumnDimensions = []int{5}
+	sp.inhibitionRadius = 1
+	sp.activeDutyCycles = []float64{0.1, 0.3, 0.2, 0, 0.4}
+	sp.boostFactors = make([]float64, sp.numColumns)
+
+	sp.updateBoostFactors()
+
+	targetDensity := []float64{0.2, 0.2, 0.5 / 3.0, 0.2, 0.2}
+	for i, val := range sp.activeDutyCycles {
+		expected := math.Exp((targetDensity[i] - val) * sp.BoostStrength)
+		assert.AlmostEqualFloat(t, expected, sp.boostFactors[i])
+	}
+}
+
+func TestUpdateBoostFactorsOff(t *testing.T) {
+	sp := SpatialPooler{}
+	sp.BoostMode = BoostOff
+	sp.MaxBoost = 10
+	sp.minActiveDutyCycles = []float64{0.1, 0.1, 0.1}
+	sp.activeDutyCycles = []float64{0, 0.05, 0.2}
+	sp.boostFactors = []float64{3, 2, 1}
+
+	sp.updateBoostFactors()
+	assert.Equal(t, []float64{1, 1, 1}, sp.boostFactors)
+}
+
+func TestInferenceUsesRawOverlaps(t *testing.T) {
+	spParams := NewSpParams()
+	spParams.InputDimensions = []int{20}
+	spParams.ColumnDimensions = []int{10}
+	spParams.PotentialRadius = 20
+	spParams.GlobalInhibition = true
+	spParams.NumActiveColumnsPerInhArea = 3
+	sp := NewSpatialPooler(spParams)
+
+	input := make([]bool, sp.numInputs)
+	utils.FillSliceRangeBool(input, true, 0, 10)
+	utils.FillSliceFloat64(sp.boostFactors, 2)
+
+	var received []float64
+	inhibitMock := func(overlaps []float64, inhibitColumnsGlobal, inhibitColumnsLocal InhibitColumnsFunc) []int {
+		received = make([]float64, len(overlaps))
+		copy(received, overlaps)
+		return []int{}
+	}
+
+	overlaps := sp.calculateOverlap(input)
+	assert.True(t, utils.MaxSliceInt(overlaps) > 0)
+
+	y := make([]bool, sp.numColumns)
+	sp.Compute(input, false, y, inhibitMock)
+	for i, val := range overlaps {
+		assert.Equal(t, float64(val), received[i])
+	}
+
+	sp.Compute(input, true, y, inhibitMock)
+	for i, val := range overlaps {
+		assert.Equal(t, float64(val)*2, received[i])
+	}
+}
+
+/*
+ Two fixed input patterns with learning of permanences disabled. Without
+boosting the same columns win every time, exponential boosting should
+rotate the winners so many more columns take part while the average duty
+cycle stays at the target density.
+*/
+func exponentialBoostRun(mode BoostMode) *SpatialPooler {
+	spParams := NewSpParams()
+	spParams.InputDimensions = []int{40}
+	spParams.ColumnDimensions = []int{100}
+	spParams.PotentialRadius = 40
+	spParams.PotentialPct = 0.9
+	spParams.GlobalInhibition = true
+	spParams.LocalAreaDensity = 0.05
+	spParams.SynPermActiveInc = 0
+	spParams.SynPermInactiveDec = 0
+	spParams.DutyCyclePeriod = 20
+	spParams.BoostMode = mode
+	spParams.BoostStrength = 100
+	sp := NewSpatialPooler(spParams)
+
+	x := make([][]bool, 2)
+	for i := range x {
+		x[i] = make([]bool, sp.numInputs)
+	}
+	utils.FillSliceRangeBool(x[0], true, 0, 20)
+	utils.FillSliceRangeBool(x[1], true, 20, 20)
+
+	y := make([]bool, sp.numColumns)
+	for i := 0; i < 200; i++ {
+		utils.FillSliceBool(y, false)
+		sp.Compute(x[i%2], true, y, sp.InhibitColumns)
+	}
+	return sp
+}
+
+func TestExponentialBoostDutyCycles(t *testing.T) {
+	off := exponentialBoostRun(BoostOff)
+	boosted := exponentialBoostRun(BoostExponential)
+
+	usedOff := off.numColumns - utils.CountFloat64(off.activeDutyCycles, 0)
+	usedBoosted := boosted.numColumns - utils.CountFloat64(boosted.activeDutyCycles, 0)
+	assert.True(t, usedOff <= 10)
+	assert.True(t, usedBoosted > 2*usedOff)
+
+	for _, val := range off.boostFactors {
+		assert.Equal(t, 1.0, val)
+	}
+
+	//mean duty cycle matches the target density in both modes
+	meanOff := utils.SumSliceFloat64(off.activeDutyCycles) / float64(off.numColumns)
+	meanBoosted := utils.SumSliceFloat64(boosted.activeDutyCycles) / float64(boosted.numColumns)
+	assert.True(t, math.Abs(meanOff-0.05) < 0.005)
+	assert.True(t, math.Abs(meanBoosted-0.05) < 0.005)
+
+	//columns below target are boosted, those above are suppressed
+	for i, val := range boosted.activeDutyCycles {
+		if val < 0.05 {
+			assert.True(t, boosted.boostFactors[i] > 1)
+		} else if val > 0.05 {
+			assert.True(t, boosted.boostFactors[i] < 1)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package htm
+
+import (
+	"github.com/nupic-community/htm/utils"
+)
+
+/*
+ Inhibitor is implemented by inhibition strategies that can be plugged into
+SpatialPooler.Compute. The method signature matches InhibitColFunc so any
+implementation can be passed as a method value, ie:
+
+	sp.Compute(input, true, active, inhibitor.InhibitColumns)
+
+The SpatialPooler itself is an Inhibitor, providing the standard
+global/local inhibition.
+*/
+type Inhibitor interface {
+	InhibitColumns(overlaps []float64, inhibitColumnsGlobal, inhibitColumnsLocal InhibitColumnsFunc) []int
+}
+
+/*
+ Returns the flat indices of the neighbors of a column. Used by
+NeighborhoodInhibitor to describe the topology of the column sheet.
+*/
+type NeighborhoodFunc func(sp *SpatialPooler, columnIndex int) []int
+
+/*
+ K winners inhibition with a density ramp. Starts out activating
+StartDensity of the columns and linearly moves towards the pooler's
+configured density over RampIterations iterations. A high starting density
+lets more columns take part in learning early on, before the representation
+settles at its target sparsity.
+*/
+type KWinnersInhibitor struct {
+	sp             *SpatialPooler
+	StartDensity   float64
+	RampIterations int
+}
+
+//Creates a k winners inhibitor for the specified spatial pooler
+func NewKWinnersInhibitor(sp *SpatialPooler, startDensity float64, rampIterations int) *KWinnersInhibitor {
+	if startDensity <= 0 || startDensity > 1 {
+		panic("Start density must be in the range (0,1]")
+	}
+	if rampIterations < 0 {
+		panic("Ramp iterations must be >= 0")
+	}
+	return &KWinnersInhibitor{
+		sp:             sp,
+		StartDensity:   startDensity,
+		RampIterations: rampIterations,
+	}
+}
+
+//Returns the density used for the current iteration
+func (k *KWinnersInhibitor) Density() float64 {
+	target := k.sp.inhibitionDensity()
+	if k.sp.IterationNum >= k.RampIterations {
+		return target
+	}
+	frac := float64(k.sp.IterationNum) / float64(k.RampIterations)
+	return k.StartDensity + (target-k.StartDensity)*frac
+}
+
+//Picks the top k columns using the ramped density
+func (k *KWinnersInhibitor) InhibitColumns(overlaps []float64, inhibitColumnsGlobal, inhibitColumnsLocal InhibitColumnsFunc) []int {
+	density := k.Density()
+	k.sp.addTieBreaker(overlaps)
+
+	if k.sp.useGlobalInhibition() {
+		return inhibitColumnsGlobal(overlaps, density)
+	}
+	return inhibitColumnsLocal(overlaps, density)
+}
+
+/*
+ Local inhibition over a custom column topology. Each column competes
+with the columns returned by Neighbors, see ToroidalNeighborhood and
+HexNeighborhood. Falls back to global inhibition when the pooler is
+configured for it.
+*/
+type NeighborhoodInhibitor struct {
+	sp        *SpatialPooler
+	Neighbors NeighborhoodFunc
+}
+
+//Creates a neighborhood inhibitor for the specified spatial pooler
+func NewNeighborhoodInhibitor(sp *SpatialPooler, neighbors NeighborhoodFunc) *NeighborhoodInhibitor {
+	return &NeighborhoodInhibitor{
+		sp:        sp,
+		Neighbors: neighbors,
+	}
+}
+
+//Performs local inhibition using the inhibitor's topology
+func (n *NeighborhoodInhibitor) InhibitColumns(overlaps []float64, inhibitColumnsGlobal, inhibitColumnsLocal InhibitColumnsFunc) []int {
+	density := n.sp.inhibitionDensity()
+	n.sp.addTieBreaker(overlaps)
+
+	if n.sp.GlobalInhibition {
+		return inhibitColumnsGlobal(overlaps, density)
+	}
+	return n.sp.inhibitColumnsNeighborhood(overlaps, density, func(index int) []int {
+		return n.Neighbors(n.sp, index)
+	})
+}
+
+/*
+ Neighborhood where every dimension of the column sheet wraps around, so
+columns on opposite borders are adjacent.
+*/
+func ToroidalNeighborhood(sp *SpatialPooler, columnIndex int) []int {
+	return sp.getNeighborsND(columnIndex, sp.ColumnDimensions, sp.inhibitionRadius, true)
+}
+
+/*
+ Neighborhood on a 2D hexagonal grid. Columns are laid out in rows with
+every odd row shifted half a column to the right ("odd-r" layout), the
+neighbors are all columns within inhibitionRadius hex steps. Borders do not
+wrap. Panics if the column dimensions are not 2D.
+*/
+func HexNeighborhood(sp *SpatialPooler, columnIndex int) []int {
+	if len(sp.ColumnDimensions) != 2 {
+		panic("Hex neighborhood requires 2D columns")
+	}
+	rows, cols := sp.ColumnDimensions[0], sp.ColumnDimensions[1]
+	radius := sp.inhibitionRadius
+
+	row, col := columnIndex/cols, columnIndex%cols
+	x, z := hexCube(row, col)
+
+	var neighbors []int
+	for r := row - radius; r <= row+radius; r++ {
+		if r < 0 || r >= rows {
+			continue
+		}
+		for c := col - radius - 1; c <= col+radius+1; c++ {
+			if c < 0 || c >= cols || (r == row && c == col) {
+				continue
+			}
+			nx, nz := hexCube(r, c)
+			if hexDistance(x, z, nx, nz) <= radius {
+				neighbors = append(neighbors, r*cols+c)
+			}
+		}
+	}
+
+	return neighbors
+}
+
+//Converts odd-r offset coordinates to cube coordinates (x,z), y = -x-z
+func hexCube(row, col int) (int, int) {
+	return col - (row-(row&1))/2, row
+}
+
+func hexDistance(x1, z1, x2, z2 int) int {
+	dx, dz := x1-x2, z1-z2
+	dy := -dx - dz
+	result := abs(dx)
+	if abs(dy) > result {
+		result = abs(dy)
+	}
+	if abs(dz) > result {
+		result = abs(dz)
+	}
+	return result
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+/*
+ Inhibition with a separate target density per region of the column
+sheet. Regions maps every column to a region index, and Densities holds the
+fraction of each region's columns that survive inhibition. Every region is
+inhibited globally and independently of the others, so busy areas of the
+input can be given more active columns than quiet ones.
+*/
+type DensityMapInhibitor struct {
+	sp        *SpatialPooler
+	Regions   []int
+	Densities []float64
+}
+
+//Creates a density map inhibitor for the specified spatial pooler
+func NewDensityMapInhibitor(sp *SpatialPooler, regions []int, densities []float64) *DensityMapInhibitor {
+	if len(regions) != sp.numColumns {
+		panic("Regions must contain an entry for every column")
+	}
+	for _, val := range regions {
+		if val < 0 || val >= len(densities) {
+			panic("Region index out of range of densities")
+		}
+	}
+	return &DensityMapInhibitor{
+		sp:        sp,
+		Regions:   regions,
+		Densities: densities,
+	}
+}
+
+//Picks the top columns of each region according to the region's density
+func (d *DensityMapInhibitor) InhibitColumns(overlaps []float64, inhibitColumnsGlobal, inhibitColumnsLocal InhibitColumnsFunc) []int {
+	d.sp.addTieBreaker(overlaps)
+
+	members := make([][]int, len(d.Densities))
+	for col, region := range d.Regions {
+		members[region] = append(members[region], col)
+	}
+
+	active := make([]bool, d.sp.numColumns)
+	for region, cols := range members {
+		numActive := int(d.Densities[region] * float64(len(cols)))
+		top := utils.TopKFloat64(utils.SubsetSliceFloat64(overlaps, cols), numActive)
+		for _, idx := range top {
+			active[cols[idx]] = true
+		}
+	}
+
+	return utils.OnIndices(active)
+}
@@ -0,0 +1,147 @@
+package htm
+
+import (
+	"github.com/nupic-community/htm/utils"
+	"github.com/zacg/testify/assert"
+	"testing"
+)
+
+func TestInhibitorInterface(t *testing.T) {
+	var inhibitors []Inhibitor
+	sp := &SpatialPooler{}
+	inhibitors = append(inhibitors, sp)
+	inhibitors = append(inhibitors, &KWinnersInhibitor{})
+	inhibitors = append(inhibitors, &NeighborhoodInhibitor{})
+	inhibitors = append(inhibitors, &DensityMapInhibitor{})
+	assert.Equal(t, 4, len(inhibitors))
+}
+
+func TestKWinnersInhibitorRamp(t *testing.T) {
+	sp := SpatialPooler{}
+	sp.numColumns = 10
+	sp.ColumnDimensions = []int{10}
+	sp.GlobalInhibition = true
+	sp.LocalAreaDensity = 0.2
+	sp.tieBreaker = make([]float64, sp.numColumns)
+
+	k := NewKWinnersInhibitor(&sp, 0.6, 4)
+
+	sp.IterationNum = 0
+	assert.AlmostEqualFloat(t, 0.6, k.Density())
+	sp.IterationNum = 2
+	assert.AlmostEqualFloat(t, 0.4, k.Density())
+	sp.IterationNum = 4
+	assert.AlmostEqualFloat(t, 0.2, k.Density())
+	sp.IterationNum = 100
+	assert.AlmostEqualFloat(t, 0.2, k.Density())
+
+	sp.IterationNum = 0
+	overlaps := []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	active := k.InhibitColumns(overlaps, sp.inhibitColumnsGlobal, sp.inhibitColumnsLocal)
+	assert.Equal(t, []int{4, 5, 6, 7, 8, 9}, active)
+
+	sp.IterationNum = 4
+	overlaps = []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	active = k.InhibitColumns(overlaps, sp.inhibitColumnsGlobal, sp.inhibitColumnsLocal)
+	assert.Equal(t, []int{8, 9}, active)
+}
+
+func TestToroidalNeighborhood(t *testing.T) {
+	sp := SpatialPooler{}
+	sp.ColumnDimensions = []int{10}
+	sp.inhibitionRadius = 2
+
+	assert.Equal(t, []int{8, 9, 1, 2}, ToroidalNeighborhood(&sp, 0))
+	assert.Equal(t, []int{3, 4, 6, 7}, ToroidalNeighborhood(&sp, 5))
+
+	sp.ColumnDimensions = []int{3, 3}
+	sp.inhibitionRadius = 1
+	neighbors := ToroidalNeighborhood(&sp, 0)
+	assert.Equal(t, 8, len(neighbors))
+	assert.False(t, utils.ContainsInt(0, neighbors))
+}
+
+func TestHexNeighborhood(t *testing.T) {
+	sp := SpatialPooler{}
+	sp.ColumnDimensions = []int{5, 5}
+	sp.inhibitionRadius = 1
+
+	//even row: neighbors lean left in the rows above and below
+	assert.Equal(t, []int{6, 7, 11, 13, 16, 17}, HexNeighborhood(&sp, 12))
+	//odd row: neighbors lean right
+	assert.Equal(t, []int{2, 3, 6, 8, 12, 13}, HexNeighborhood(&sp, 7))
+	//corner
+	assert.Equal(t, []int{1, 5}, HexNeighborhood(&sp, 0))
+
+	sp.inhibitionRadius = 2
+	assert.Equal(t, 18, len(HexNeighborhood(&sp, 12)))
+}
+
+func TestNeighborhoodInhibitor(t *testing.T) {
+	sp := SpatialPooler{}
+	sp.numColumns = 10
+	sp.ColumnDimensions = []int{10}
+	sp.inhibitionRadius = 1
+	sp.LocalAreaDensity = 0.34
+	sp.tieBreaker = make([]float64, sp.numColumns)
+
+	n := NewNeighborhoodInhibitor(&sp, ToroidalNeighborhood)
+	overlaps := []float64{5, 1, 1, 1, 1, 1, 1, 1, 1, 4}
+	active := n.InhibitColumns(overlaps, sp.inhibitColumnsGlobal, sp.inhibitColumnsLocal)
+
+	//column 9 neighbors column 0 when wrapping so it loses, column 1 as well
+	assert.False(t, utils.ContainsInt(9, active))
+	assert.False(t, utils.ContainsInt(1, active))
+	assert.True(t, utils.ContainsInt(0, active))
+
+	//without wrapping column 9 wins its neighborhood
+	overlaps = []float64{5, 1, 1, 1, 1, 1, 1, 1, 1, 4}
+	active = sp.inhibitColumnsLocal(overlaps, sp.LocalAreaDensity)
+	assert.True(t, utils.ContainsInt(9, active))
+}
+
+func TestDensityMapInhibitor(t *testing.T) {
+	sp := SpatialPooler{}
+	sp.numColumns = 8
+	sp.ColumnDimensions = []int{8}
+	sp.tieBreaker = make([]float64, sp.numColumns)
+
+	regions := []int{0, 0, 0, 0, 1, 1, 1, 1}
+	densities := []float64{0.25, 0.5}
+	d := NewDensityMapInhibitor(&sp, regions, densities)
+
+	overlaps := []float64{9, 8, 7, 6, 1, 4, 3, 2}
+	active := d.InhibitColumns(overlaps, sp.inhibitColumnsGlobal, sp.inhibitColumnsLocal)
+	assert.Equal(t, []int{0, 5, 6}, active)
+
+	assert.Panics(t, func() {
+		NewDensityMapInhibitor(&sp, []int{0, 1}, densities)
+	})
+	assert.Panics(t, func() {
+		NewDensityMapInhibitor(&sp, []int{0, 0, 0, 0, 2, 2, 2, 2}, densities)
+	})
+}
+
+func TestComputeWithInhibitor(t *testing.T) {
+	spParams := NewSpParams()
+	spParams.InputDimensions = []int{20}
+	spParams.ColumnDimensions = []int{16}
+	spParams.PotentialRadius = 20
+	spParams.GlobalInhibition = true
+	spParams.NumActiveColumnsPerInhArea = 2
+	sp := NewSpatialPooler(spParams)
+
+	k := NewKWinnersInhibitor(sp, 0.5, 10)
+	input := make([]bool, sp.NumInputs())
+	utils.FillSliceRangeBool(input, true, 0, 10)
+	active := make([]bool, sp.NumColumns())
+
+	sp.Compute(input, true, active, k.InhibitColumns)
+	//first iteration uses nearly the starting density
+	assert.True(t, utils.CountTrue(active) > 2)
+
+	for i := 0; i < 10; i++ {
+		sp.Compute(input, true, active, k.InhibitColumns)
+	}
+	assert.Equal(t, 2, utils.CountTrue(active))
+}
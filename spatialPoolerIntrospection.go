@@ -0,0 +1,171 @@
+package htm
+
+/*
+ Read only access to the spatial pooler's internal state, plus setters for
+seeding a pooler with known permanences and potential pools. All getters
+return copies, modifying the results has no effect on the pooler.
+*/
+
+//Returns the dense permanence values of the specified column
+func (sp *SpatialPooler) ColumnPermanences(column int) []float64 {
+	sp.validateColumn(column)
+	return sp.permanenceRow(column)
+}
+
+//Returns the dense permanence values of every column, indexed [column][input]
+func (sp *SpatialPooler) Permanences() [][]float64 {
+	result := make([][]float64, sp.numColumns)
+	for i := range result {
+		result[i] = sp.permanenceRow(i)
+	}
+	return result
+}
+
+//Returns the potential pool of the specified column as an input mask
+func (sp *SpatialPooler) ColumnPotentialPool(column int) []bool {
+	sp.validateColumn(column)
+	return sp.potentialPools.GetDenseRow(column)
+}
+
+//Returns the potential pools of every column, indexed [column][input]
+func (sp *SpatialPooler) PotentialPools() [][]bool {
+	result := make([][]bool, sp.numColumns)
+	for i := range result {
+		result[i] = sp.potentialPools.GetDenseRow(i)
+	}
+	return result
+}
+
+//Returns the inputs the specified column is connected to as an input mask
+func (sp *SpatialPooler) ColumnConnectedSynapses(column int) []bool {
+	sp.validateColumn(column)
+	return sp.connectedSynapses.GetDenseRow(column)
+}
+
+//Returns the connected synapses of every column, indexed [column][input]
+func (sp *SpatialPooler) ConnectedSynapses() [][]bool {
+	result := make([][]bool, sp.numColumns)
+	for i := range result {
+		result[i] = sp.connectedSynapses.GetDenseRow(i)
+	}
+	return result
+}
+
+//Returns the number of connected synapses of each column
+func (sp *SpatialPooler) ConnectedCounts() []int {
+	result := make([]int, len(sp.connectedCounts))
+	copy(result, sp.connectedCounts)
+	return result
+}
+
+//Returns the overlap duty cycle of each column
+func (sp *SpatialPooler) OverlapDutyCycles() []float64 {
+	return copyFloat64(sp.overlapDutyCycles)
+}
+
+//Returns the active duty cycle of each column
+func (sp *SpatialPooler) ActiveDutyCycles() []float64 {
+	return copyFloat64(sp.activeDutyCycles)
+}
+
+//Returns the minimum overlap duty cycle of each column
+func (sp *SpatialPooler) MinOverlapDutyCycles() []float64 {
+	return copyFloat64(sp.minOverlapDutyCycles)
+}
+
+//Returns the minimum active duty cycle of each column
+func (sp *SpatialPooler) MinActiveDutyCycles() []float64 {
+	return copyFloat64(sp.minActiveDutyCycles)
+}
+
+//Returns the boost factor of each column
+func (sp *SpatialPooler) BoostFactors() []float64 {
+	return copyFloat64(sp.boostFactors)
+}
+
+//Returns the current inhibition radius
+func (sp *SpatialPooler) InhibitionRadius() int {
+	return sp.inhibitionRadius
+}
+
+/*
+ Sets the permanences of the specified column. Values are clipped to
+[SynPermMin, SynPermMax] and values at or below SynPermTrimThreshold are
+trimmed to 0, the connected synapses and counts are updated to match.
+Panics if a non zero permanence is specified for an input outside the
+column's potential pool.
+*/
+func (sp *SpatialPooler) SetColumnPermanences(column int, perm []float64) {
+	sp.validateColumn(column)
+	if len(perm) != sp.numInputs {
+		panic("Permanences must contain an entry for every input")
+	}
+	potential := sp.potentialPools.GetDenseRow(column)
+	for i, val := range perm {
+		if val != 0 && !potential[i] {
+			panic("Permanence specified outside of potential pool")
+		}
+	}
+
+	values := make([]float64, len(perm))
+	copy(values, perm)
+	sp.updatePermanencesForColumn(values, column, false)
+}
+
+//Sets the permanences of every column, see SetColumnPermanences
+func (sp *SpatialPooler) SetPermanences(perms [][]float64) {
+	if len(perms) != sp.numColumns {
+		panic("Permanences must contain an entry for every column")
+	}
+	for i, perm := range perms {
+		sp.SetColumnPermanences(i, perm)
+	}
+}
+
+/*
+ Sets the potential pool of the specified column. Permanences of inputs
+that are no longer part of the pool are set to 0 and the connected synapses
+and counts are updated to match.
+*/
+func (sp *SpatialPooler) SetColumnPotentialPool(column int, potential []bool) {
+	sp.validateColumn(column)
+	if len(potential) != sp.numInputs {
+		panic("Potential pool must contain an entry for every input")
+	}
+	sp.potentialPools.ReplaceRow(column, potential)
+
+	perm := sp.permanenceRow(column)
+	for i, val := range potential {
+		if !val {
+			perm[i] = 0
+		}
+	}
+	sp.updatePermanencesForColumn(perm, column, false)
+}
+
+//Sets the potential pools of every column, see SetColumnPotentialPool
+func (sp *SpatialPooler) SetPotentialPools(potentials [][]bool) {
+	if len(potentials) != sp.numColumns {
+		panic("Potential pools must contain an entry for every column")
+	}
+	for i, potential := range potentials {
+		sp.SetColumnPotentialPool(i, potential)
+	}
+}
+
+//Returns a dense copy of a column's permanences
+func (sp *SpatialPooler) permanenceRow(column int) []float64 {
+	return sp.permanences.GetDenseRow(column)
+}
+
+func (sp *SpatialPooler) validateColumn(column int) {
+	if column < 0 || column >= sp.numColumns {
+		panic("Column index out of range")
+	}
+}
+
+func copyFloat64(values []float64) []float64 {
+	result := make([]float64, len(values))
+	copy(result, values)
+	return result
+}
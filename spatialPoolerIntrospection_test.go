@@ -0,0 +1,121 @@
+package htm
+
+import (
+	"github.com/nupic-community/htm/utils"
+	"github.com/zacg/testify/assert"
+	"testing"
+)
+
+func introspectionSp() *SpatialPooler {
+	spParams := NewSpParams()
+	spParams.InputDimensions = []int{8}
+	spParams.ColumnDimensions = []int{4}
+	spParams.PotentialRadius = 8
+	spParams.PotentialPct = 1
+	spParams.SynPermConnected = 0.1
+	spParams.SynPermActiveInc = 0.1
+	return NewSpatialPooler(spParams)
+}
+
+func TestIntrospectionCopies(t *testing.T) {
+	sp := introspectionSp()
+
+	perms := sp.Permanences()
+	assert.Equal(t, sp.NumColumns(), len(perms))
+	for i, perm := range perms {
+		assert.Equal(t, perm, sp.ColumnPermanences(i))
+		assert.Equal(t, sp.NumInputs(), len(perm))
+	}
+
+	//modifying results does not touch the pooler
+	perms[0][0] = 42
+	assert.NotEqual(t, 42.0, sp.ColumnPermanences(0)[0])
+
+	pools := sp.PotentialPools()
+	pools[1][1] = !pools[1][1]
+	assert.NotEqual(t, pools[1][1], sp.ColumnPotentialPool(1)[1])
+
+	counts := sp.ConnectedCounts()
+	counts[0] = -1
+	assert.True(t, sp.ConnectedCounts()[0] >= 0)
+
+	boost := sp.BoostFactors()
+	boost[0] = 42
+	assert.Equal(t, 1.0, sp.BoostFactors()[0])
+
+	assert.Equal(t, sp.numColumns, len(sp.ActiveDutyCycles()))
+	assert.Equal(t, sp.numColumns, len(sp.OverlapDutyCycles()))
+	assert.Equal(t, sp.numColumns, len(sp.MinActiveDutyCycles()))
+	assert.Equal(t, sp.numColumns, len(sp.MinOverlapDutyCycles()))
+	assert.Equal(t, sp.inhibitionRadius, sp.InhibitionRadius())
+
+	assert.Panics(t, func() { sp.ColumnPermanences(4) })
+	assert.Panics(t, func() { sp.ColumnConnectedSynapses(-1) })
+}
+
+func TestSetColumnPermanences(t *testing.T) {
+	sp := introspectionSp()
+
+	perm := []float64{0.5, 0, 0.11, 0.09, 0.01, 1.5, 0, 0.1}
+	sp.SetColumnPermanences(2, perm)
+
+	//trimmed below threshold and clipped to max
+	expected := []float64{0.5, 0, 0.11, 0.09, 0, 1, 0, 0.1}
+	assert.Equal(t, expected, sp.ColumnPermanences(2))
+	//input not modified
+	assert.Equal(t, 1.5, perm[5])
+
+	connected := utils.Make1DBool([]int{1, 0, 1, 0, 0, 1, 0, 1})
+	assert.Equal(t, connected, sp.ColumnConnectedSynapses(2))
+	assert.Equal(t, 4, sp.ConnectedCounts()[2])
+	assert.Equal(t, connected, sp.ConnectedSynapses()[2])
+
+	//overlap calculation picks up the new connections
+	input := utils.Make1DBool([]int{1, 0, 1, 0, 0, 0, 0, 0})
+	assert.Equal(t, 2, sp.calculateOverlap(input)[2])
+
+	assert.Panics(t, func() { sp.SetColumnPermanences(2, []float64{0.5}) })
+}
+
+func TestSetPotentialPool(t *testing.T) {
+	sp := introspectionSp()
+
+	perm := []float64{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5}
+	sp.SetColumnPermanences(0, perm)
+	assert.Equal(t, 8, sp.ConnectedCounts()[0])
+
+	potential := utils.Make1DBool([]int{1, 1, 0, 0, 0, 0, 1, 1})
+	sp.SetColumnPotentialPool(0, potential)
+
+	assert.Equal(t, potential, sp.ColumnPotentialPool(0))
+	assert.Equal(t, []float64{0.5, 0.5, 0, 0, 0, 0, 0.5, 0.5}, sp.ColumnPermanences(0))
+	assert.Equal(t, potential, sp.ColumnConnectedSynapses(0))
+	assert.Equal(t, 4, sp.ConnectedCounts()[0])
+
+	//permanences outside the pool are rejected
+	assert.Panics(t, func() { sp.SetColumnPermanences(0, perm) })
+}
+
+func TestSetBulk(t *testing.T) {
+	sp := introspectionSp()
+
+	pools := make([][]bool, sp.NumColumns())
+	perms := make([][]float64, sp.NumColumns())
+	for i := range pools {
+		pools[i] = make([]bool, sp.NumInputs())
+		perms[i] = make([]float64, sp.NumInputs())
+		pools[i][i*2] = true
+		pools[i][i*2+1] = true
+		perms[i][i*2] = 0.3
+	}
+
+	sp.SetPotentialPools(pools)
+	sp.SetPermanences(perms)
+
+	assert.Equal(t, pools, sp.PotentialPools())
+	assert.Equal(t, perms, sp.Permanences())
+	assert.Equal(t, []int{1, 1, 1, 1}, sp.ConnectedCounts())
+
+	assert.Panics(t, func() { sp.SetPermanences(perms[:2]) })
+	assert.Panics(t, func() { sp.SetPotentialPools(pools[:2]) })
+}
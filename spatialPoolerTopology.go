@@ -0,0 +1,219 @@
+package htm
+
+import (
+	"github.com/nupic-community/htm/utils"
+	"math"
+)
+
+/*
+ Maps a column, given by its coordinates in the column space, to the
+coordinates of the center of its receptive field in the input space.
+*/
+type CenterMapFunc func(columnCoords, columnDimensions, inputDimensions []int) []int
+
+/*
+ Returns the relative weight of an input bit at the specified per
+dimension offset from the center of a column's receptive field. Inputs
+with a weight of 0 are never part of the potential pool, inputs with
+higher weights are more likely to be sampled into it.
+*/
+type PoolShapeFunc func(offset, radius []int) float64
+
+/*
+ Describes how columns are mapped onto the input space when building
+potential pools. Unlike the default mapping, which treats inputs and
+columns as flat 1D arrays, every dimension of the input is taken into
+account.
+
+Radius: potential radius for each input dimension. Defaults to
+PotentialRadius for every dimension when empty.
+WrapAround: whether each input dimension wraps around at its borders.
+Defaults to SpParams.WrapAround for every dimension when empty.
+CenterMap: maps columns to receptive field centers, defaults to
+UniformCenterMap.
+Shape: weighting of inputs within the receptive field, defaults to
+RectangularPool.
+*/
+type Topology struct {
+	Radius     []int
+	WrapAround []bool
+	CenterMap  CenterMapFunc
+	Shape      PoolShapeFunc
+}
+
+//Returns a copy of the topology with defaults filled in and validated
+//against the specified input dimensions
+func (t *Topology) normalize(inputDims []int, potentialRadius int, wrapAround bool) *Topology {
+	result := &Topology{
+		CenterMap: t.CenterMap,
+		Shape:     t.Shape,
+	}
+
+	if len(t.Radius) == 0 {
+		result.Radius = utils.MakeSliceInt(len(inputDims), potentialRadius)
+	} else if len(t.Radius) != len(inputDims) {
+		panic("Topology radius must have an entry per input dimension")
+	} else {
+		result.Radius = make([]int, len(t.Radius))
+		copy(result.Radius, t.Radius)
+	}
+
+	result.WrapAround = make([]bool, len(inputDims))
+	if len(t.WrapAround) == 0 {
+		utils.FillSliceBool(result.WrapAround, wrapAround)
+	} else if len(t.WrapAround) != len(inputDims) {
+		panic("Topology wrap around must have an entry per input dimension")
+	} else {
+		copy(result.WrapAround, t.WrapAround)
+	}
+
+	for _, val := range result.Radius {
+		if val < 0 {
+			panic("Topology radius must be >= 0")
+		}
+	}
+
+	if result.CenterMap == nil {
+		result.CenterMap = UniformCenterMap
+	}
+	if result.Shape == nil {
+		result.Shape = RectangularPool
+	}
+
+	return result
+}
+
+/*
+ Spreads columns evenly over the input space, each column is mapped to the
+center of the block of inputs it covers. When there are fewer column
+dimensions than input dimensions the missing column dimensions are treated
+as having a size of 1.
+*/
+func UniformCenterMap(columnCoords, columnDimensions, inputDimensions []int) []int {
+	result := make([]int, len(inputDimensions))
+	for i, inputDim := range inputDimensions {
+		colDim, coord := 1, 0
+		if i < len(columnDimensions) {
+			colDim, coord = columnDimensions[i], columnCoords[i]
+		}
+		ratio := float64(inputDim) / float64(colDim)
+		result[i] = int((float64(coord) + 0.5) * ratio)
+	}
+	return result
+}
+
+//Every input within the per dimension radius is equally likely
+func RectangularPool(offset, radius []int) float64 {
+	return 1.0
+}
+
+/*
+ Weights inputs by a gaussian falloff from the receptive field center,
+the standard deviation of each dimension is half its radius.
+*/
+func GaussianPool(offset, radius []int) float64 {
+	exp := 0.0
+	for i, val := range offset {
+		if radius[i] == 0 {
+			continue
+		}
+		sigma := float64(radius[i]) / 2.0
+		exp += (float64(val) * float64(val)) / (sigma * sigma)
+	}
+	return math.Exp(-0.5 * exp)
+}
+
+/*
+ Maps a column to its input bits using the pooler's topology. The
+receptive field is the hyper rectangle of inputs within the per dimension
+radius of the column's center, clipped or wrapped at the borders. A
+PotentialPct fraction of the receptive field is sampled, weighted by the
+topology's shape, to serve as the potential pool.
+*/
+func (sp *SpatialPooler) mapPotentialTopology(index int) []bool {
+	top := sp.Topology
+	inputDims := sp.InputDimensions
+
+	colCoords := coordsFromIndex(index, sp.ColumnDimensions)
+	center := top.CenterMap(colCoords, sp.ColumnDimensions, inputDims)
+	if len(center) != len(inputDims) {
+		panic("Center map must return a coordinate per input dimension")
+	}
+
+	//valid offsets from the center in each dimension
+	offsets := make([][]int, len(inputDims))
+	for i, dim := range inputDims {
+		c := utils.Mod(center[i], dim)
+		if !top.WrapAround[i] {
+			c = center[i]
+			if c < 0 {
+				c = 0
+			} else if c >= dim {
+				c = dim - 1
+			}
+		}
+		center[i] = c
+		for off := -top.Radius[i]; off <= top.Radius[i]; off++ {
+			if !top.WrapAround[i] && (c+off < 0 || c+off >= dim) {
+				continue
+			}
+			offsets[i] = append(offsets[i], off)
+		}
+	}
+
+	seen := make([]bool, sp.numInputs)
+	var candidates []int
+	var weights []float64
+	coords := make([]int, len(inputDims))
+	for _, offset := range utils.CartProductInt(offsets) {
+		for i, dim := range inputDims {
+			coords[i] = utils.Mod(center[i]+offset[i], dim)
+		}
+		idx := indexFromCoords(coords, inputDims)
+		if seen[idx] {
+			continue
+		}
+		seen[idx] = true
+		weight := top.Shape(offset, top.Radius)
+		if weight <= 0 {
+			continue
+		}
+		candidates = append(candidates, idx)
+		weights = append(weights, weight)
+	}
+
+	// Select a weighted subset of the receptive field to serve as the
+	// potential pool. Each candidate gets a key u^(1/w) and the largest
+	// keys are kept, which samples without replacement proportional to w.
+	sampleLen := int(utils.RoundPrec(float64(len(candidates))*sp.PotentialPct, 0))
+	keys := make([]float64, len(candidates))
+	for i, w := range weights {
+		keys[i] = math.Pow(sp.random().Float64(), 1.0/w)
+	}
+
+	mask := make([]bool, sp.numInputs)
+	for _, val := range utils.TopKFloat64(keys, sampleLen) {
+		mask[candidates[val]] = true
+	}
+
+	return mask
+}
+
+//Converts a flat index into coordinates of the specified (row major) space
+func coordsFromIndex(index int, dimensions []int) []int {
+	coords := make([]int, len(dimensions))
+	for i := len(dimensions) - 1; i >= 0; i-- {
+		coords[i] = index % dimensions[i]
+		index /= dimensions[i]
+	}
+	return coords
+}
+
+//Converts coordinates of the specified (row major) space into a flat index
+func indexFromCoords(coords []int, dimensions []int) int {
+	index := 0
+	for i, dim := range dimensions {
+		index = index*dim + coords[i]
+	}
+	return index
+}
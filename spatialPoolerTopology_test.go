@@ -0,0 +1,156 @@
+package htm
+
+import (
+	"github.com/nupic-community/htm/utils"
+	"github.com/zacg/testify/assert"
+	"testing"
+)
+
+func topologySp(inputDims, colDims []int, top *Topology) *SpatialPooler {
+	sp := SpatialPooler{}
+	sp.InputDimensions = inputDims
+	sp.numInputs = utils.ProdInt(inputDims)
+	sp.ColumnDimensions = colDims
+	sp.numColumns = utils.ProdInt(colDims)
+	sp.PotentialPct = 1
+	sp.Topology = top.normalize(inputDims, 1, false)
+	return &sp
+}
+
+func TestCoordsFromIndex(t *testing.T) {
+	dims := []int{3, 4, 5}
+	assert.Equal(t, []int{0, 0, 0}, coordsFromIndex(0, dims))
+	assert.Equal(t, []int{0, 1, 2}, coordsFromIndex(7, dims))
+	assert.Equal(t, []int{2, 3, 4}, coordsFromIndex(59, dims))
+
+	for i := 0; i < 60; i++ {
+		assert.Equal(t, i, indexFromCoords(coordsFromIndex(i, dims), dims))
+	}
+}
+
+func TestUniformCenterMap(t *testing.T) {
+	assert.Equal(t, []int{1, 1}, UniformCenterMap([]int{0, 0}, []int{4, 4}, []int{8, 8}))
+	assert.Equal(t, []int{7, 3}, UniformCenterMap([]int{3, 1}, []int{4, 4}, []int{8, 8}))
+	//missing column dimensions are treated as size 1
+	assert.Equal(t, []int{6, 2}, UniformCenterMap([]int{2}, []int{4}, []int{10, 5}))
+}
+
+func TestMapPotentialTopology2D(t *testing.T) {
+	sp := topologySp([]int{8, 8}, []int{4, 4}, &Topology{Radius: []int{1, 1}})
+
+	//column (0,0) is centered on input (1,1)
+	mask := sp.mapPotentialTopology(0)
+	expected := []int{0, 1, 2, 8, 9, 10, 16, 17, 18}
+	assert.Equal(t, expected, utils.OnIndices(mask))
+
+	//column (3,3) is centered on input (7,7), clipped at the borders
+	mask = sp.mapPotentialTopology(15)
+	expected = []int{54, 55, 62, 63}
+	assert.Equal(t, expected, utils.OnIndices(mask))
+}
+
+func TestMapPotentialTopologyWrapAround(t *testing.T) {
+	//only the second dimension wraps
+	top := &Topology{Radius: []int{1, 1}, WrapAround: []bool{false, true}}
+	sp := topologySp([]int{8, 8}, []int{4, 4}, top)
+
+	mask := sp.mapPotentialTopology(15)
+	expected := []int{48, 54, 55, 56, 62, 63}
+	assert.Equal(t, expected, utils.OnIndices(mask))
+}
+
+func TestMapPotentialTopologyRectangular(t *testing.T) {
+	//tall narrow receptive field
+	sp := topologySp([]int{10, 10}, []int{1, 1}, &Topology{Radius: []int{2, 0}})
+
+	mask := sp.mapPotentialTopology(0)
+	expected := []int{35, 45, 55, 65, 75}
+	assert.Equal(t, expected, utils.OnIndices(mask))
+
+	sp.PotentialPct = 0.6
+	mask = sp.mapPotentialTopology(0)
+	assert.Equal(t, 3, utils.CountTrue(mask))
+	assert.Equal(t, expected, utils.OnIndices(utils.OrBool(mask, utils.Make1DBool([]int{
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 1, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 1, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 1, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 1, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 1, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0}))))
+}
+
+func TestMapPotentialTopologyCustomCenter(t *testing.T) {
+	top := &Topology{
+		Radius: []int{1},
+		CenterMap: func(columnCoords, columnDimensions, inputDimensions []int) []int {
+			//columns read the input in reverse
+			return []int{inputDimensions[0] - 1 - columnCoords[0]*2}
+		},
+	}
+	sp := topologySp([]int{10}, []int{5}, top)
+
+	assert.Equal(t, []int{8, 9}, utils.OnIndices(sp.mapPotentialTopology(0)))
+	assert.Equal(t, []int{4, 5, 6}, utils.OnIndices(sp.mapPotentialTopology(2)))
+}
+
+func TestGaussianPool(t *testing.T) {
+	radius := []int{4, 4}
+	assert.AlmostEqualFloat(t, 1.0, GaussianPool([]int{0, 0}, radius))
+	assert.True(t, GaussianPool([]int{1, 0}, radius) > GaussianPool([]int{2, 0}, radius))
+	assert.AlmostEqualFloat(t, GaussianPool([]int{0, 3}, radius), GaussianPool([]int{-3, 0}, radius))
+
+	//center of a gaussian pool is sampled far more often than the corners
+	top := &Topology{Radius: []int{4, 4}, Shape: GaussianPool}
+	sp := topologySp([]int{9, 9}, []int{1, 1}, top)
+	sp.PotentialPct = 0.2
+
+	centerCount, cornerCount := 0, 0
+	for i := 0; i < 200; i++ {
+		mask := sp.mapPotentialTopology(0)
+		if mask[40] {
+			centerCount++
+		}
+		if mask[0] {
+			cornerCount++
+		}
+	}
+	assert.True(t, centerCount > 4*cornerCount)
+}
+
+func TestTopologyNormalize(t *testing.T) {
+	top := (&Topology{}).normalize([]int{4, 6}, 3, true)
+	assert.Equal(t, []int{3, 3}, top.Radius)
+	assert.Equal(t, []bool{true, true}, top.WrapAround)
+	assert.True(t, top.CenterMap != nil)
+	assert.True(t, top.Shape != nil)
+
+	assert.Panics(t, func() {
+		(&Topology{Radius: []int{1}}).normalize([]int{4, 6}, 3, true)
+	})
+	assert.Panics(t, func() {
+		(&Topology{WrapAround: []bool{true}}).normalize([]int{4, 6}, 3, true)
+	})
+}
+
+func TestNewSpatialPoolerTopology(t *testing.T) {
+	spParams := NewSpParams()
+	spParams.InputDimensions = []int{16, 16}
+	spParams.ColumnDimensions = []int{8, 8}
+	spParams.PotentialPct = 1
+	spParams.Topology = &Topology{Radius: []int{2, 2}}
+	sp := NewSpatialPooler(spParams)
+
+	//every pool is a 5x5 block wrapped around the column's center
+	for i := 0; i < sp.NumColumns(); i++ {
+		assert.Equal(t, 25, len(sp.potentialPools.GetRowIndices(i)))
+	}
+
+	spParams.WrapAround = false
+	sp = NewSpatialPooler(spParams)
+	//corner columns are clipped
+	assert.Equal(t, 16, len(sp.potentialPools.GetRowIndices(0)))
+}
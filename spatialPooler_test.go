@@ -3,13 +3,13 @@ package htm
 import (
 	//"fmt"
 	"github.com/nupic-community/htm/utils"
-	"github.com/skelterjohn/go.matrix"
 	//"github.com/stretchr/testify/assert"
 	"github.com/zacg/testify/assert"
 	//"math/big"
 	//"github.com/stretchr/testify/mock"
 	"math"
 	"math/rand"
+	"sort"
 	"strconv"
 	"testing"
 )
@@ -98,6 +98,23 @@ func TestPermanenceInit(t *testing.T) {
 
 }
 
+func TestPermanenceInitSeed(t *testing.T) {
+	initPerms := func(seed int) []float64 {
+		sp := SpatialPooler{}
+		sp.numInputs = 100
+		sp.SynPermConnected = 0.1
+		sp.SynPermActiveInc = 0.1
+		sp.Seed = seed
+		mask := make([]bool, 100)
+		utils.FillSliceBool(mask, true)
+		return sp.initPermanence(mask, 0.5)
+	}
+
+	//connected synapses are picked by the poolers seeded generator
+	assert.Equal(t, initPerms(7), initPerms(7))
+	assert.NotEqual(t, initPerms(7), initPerms(8))
+}
+
 func TestRaisePermanenceThreshold(t *testing.T) {
 
 	sp := SpatialPooler{}
@@ -111,8 +128,7 @@ func TestRaisePermanenceThreshold(t *testing.T) {
 	sp.SynPermMin = 0
 	sp.SynPermMax = 1
 
-	elms := make(map[int]float64, 25)
-	sp.permanences = matrix.MakeSparseMatrix(elms, 5, 5)
+	sp.permanences = NewSparseFloatMatrix(5, 5)
 
 	p := [][]float64{
 		{0.0, 0.11, 0.095, 0.092, 0.01},
@@ -123,7 +139,7 @@ func TestRaisePermanenceThreshold(t *testing.T) {
 	}
 	AddDenseToSparseHelper(p, sp.permanences)
 
-	sp.connectedSynapses = NewDenseBinaryMatrixFromDense([][]bool{
+	sp.connectedSynapses = NewPackedBinaryMatrixFromDense([][]bool{
 		{false, true, false, false, false},
 		{true, true, false, true, false},
 		{true, false, false, false, true},
@@ -143,7 +159,7 @@ func TestRaisePermanenceThreshold(t *testing.T) {
 	maskPP := []int{0, 1, 2, 3, 4}
 
 	for i := 0; i < sp.numColumns; i++ {
-		perm := SparseMatrixToArray(sp.permanences.GetRowVector(i))
+		perm := sp.permanences.GetDenseRow(i)
 		sp.raisePermanenceToThreshold(perm, maskPP)
 		for j := 0; j < sp.numInputs; j++ {
 			//if truePermanences[i][j] != perm[j] {
@@ -215,7 +231,7 @@ func TestAvgConnectedSpanForColumn2D(t *testing.T) {
 		{0, 0, 1, 0, 1, 0, 0, 0},
 		{1, 1, 1, 1, 1, 1, 1, 1}}
 
-	sp.connectedSynapses = NewDenseBinaryMatrixFromInts(ints)
+	sp.connectedSynapses = NewPackedBinaryMatrixFromInts(ints)
 
 	trueAvgConnectedSpan := []int{7, 5, 1, 5, 0, 2, 3, 3, 8}
 
@@ -233,7 +249,7 @@ func TestAvgConnectedSpanForColumnND(t *testing.T) {
 	sp.numColumns = 5
 	sp.ColumnDimensions = []int{0, 1, 2, 3, 4}
 
-	sp.connectedSynapses = NewDenseBinaryMatrix(sp.numColumns, sp.numInputs)
+	sp.connectedSynapses = NewPackedBinaryMatrix(sp.numColumns, sp.numInputs)
 
 	connected := make([]bool, sp.numInputs)
 	connected[(1*40)+(0*10)+(1*5)+(0*1)] = true
@@ -393,7 +409,7 @@ func TestCalculateOverlap(t *testing.T) {
 	sp.InputDimensions = []int{10}
 	sp.ColumnDimensions = []int{5}
 
-	sp.connectedSynapses = NewDenseBinaryMatrixFromDense([][]bool{
+	sp.connectedSynapses = NewPackedBinaryMatrixFromDense([][]bool{
 		{true, true, true, true, true, true, true, true, true, true},
 		{false, false, true, true, true, true, true, true, true, true},
 		{false, false, false, false, true, true, true, true, true, true},
@@ -435,7 +451,7 @@ func TestCalculateOverlap(t *testing.T) {
 	assert.Equal(t, trueOverlapsPct, overlapsPct)
 
 	//Zig-zag
-	sp.connectedSynapses = NewDenseBinaryMatrixFromDense([][]bool{
+	sp.connectedSynapses = NewPackedBinaryMatrixFromDense([][]bool{
 		{true, false, false, false, false, true, false, false, false, false},
 		{false, true, false, false, false, false, true, false, false, false},
 		{false, false, true, false, false, false, false, true, false, false},
@@ -557,6 +573,64 @@ func TestInhibitColumnsGlobal(t *testing.T) {
 
 }
 
+func TestInhibitColumnsGlobalTieBreaking(t *testing.T) {
+	sp := SpatialPooler{}
+	sp.numColumns = 6
+
+	//equal overlaps favour the higher column index
+	overlaps := []float64{2, 2, 2, 2, 2, 2}
+	active := sp.inhibitColumnsGlobal(overlaps, 0.5)
+	assert.Equal(t, []int{3, 4, 5}, active)
+
+	overlaps = []float64{3, 1, 3, 1, 3, 1}
+	active = sp.inhibitColumnsGlobal(overlaps, 0.34)
+	assert.Equal(t, []int{2, 4}, active)
+
+	//fractional parts (boosting, tie breaker) decide the winners
+	overlaps = []float64{1.2, 1.9, 1.5, 1.1, 1.001, 1.0}
+	active = sp.inhibitColumnsGlobal(overlaps, 0.5)
+	assert.Equal(t, []int{0, 1, 2}, active)
+
+	overlaps = []float64{4.004, 4.001, 4.003, 4.002, 0, 0}
+	active = sp.inhibitColumnsGlobal(overlaps, 0.34)
+	assert.Equal(t, []int{0, 2}, active)
+
+	//no winners when density rounds down to zero
+	active = sp.inhibitColumnsGlobal(overlaps, 0.1)
+	assert.Equal(t, 0, len(active))
+}
+
+func TestInhibitColumnsGlobalMatchesSort(t *testing.T) {
+	sp := SpatialPooler{}
+	sp.numColumns = 500
+	density := 0.04
+
+	for trial := 0; trial < 20; trial++ {
+		overlaps := make([]float64, sp.numColumns)
+		for i := range overlaps {
+			//small integer range to force plenty of ties
+			overlaps[i] = float64(rand.Intn(10))
+		}
+
+		ov := make([]utils.TupleFloat, len(overlaps))
+		for i, val := range overlaps {
+			ov[i].A = val
+			ov[i].B = float64(i)
+		}
+		sort.SliceStable(ov, func(i, j int) bool { return ov[i].A < ov[j].A })
+
+		numActive := int(density * float64(sp.numColumns))
+		expected := make([]int, numActive)
+		for i := 0; i < numActive; i++ {
+			expected[i] = int(ov[len(ov)-1-i].B)
+		}
+		sort.Ints(expected)
+
+		active := sp.inhibitColumnsGlobal(overlaps, density)
+		assert.Equal(t, expected, active)
+	}
+}
+
 func TestGetNeighborsND(t *testing.T) {
 	sp := SpatialPooler{}
 
@@ -966,10 +1040,9 @@ func TestUpdatePermanencesForColumn(t *testing.T) {
 	sp.SynPermConnected = 0.1
 	sp.SynPermTrimThreshold = 0.05
 	sp.connectedCounts = make([]int, sp.numColumns)
-	elms := make(map[int]float64, 25)
-	sp.permanences = matrix.MakeSparseMatrix(elms, sp.numColumns, sp.numInputs)
-	sp.potentialPools = NewDenseBinaryMatrix(sp.numColumns, sp.numInputs)
-	sp.connectedSynapses = NewDenseBinaryMatrix(sp.numColumns, sp.numInputs)
+	sp.permanences = NewSparseFloatMatrix(sp.numColumns, sp.numInputs)
+	sp.potentialPools = NewPackedBinaryMatrix(sp.numColumns, sp.numInputs)
+	sp.connectedSynapses = NewPackedBinaryMatrix(sp.numColumns, sp.numInputs)
 	sp.SynPermMax = 1
 	sp.SynPermMin = 0
 
@@ -1022,7 +1095,7 @@ func TestAdaptSynapses(t *testing.T) {
 	sp.SynPermInactiveDec = 0.01
 	sp.SynPermActiveInc = 0.1
 	sp.SynPermTrimThreshold = 0.05
-	sp.connectedSynapses = NewDenseBinaryMatrix(sp.numColumns, sp.numInputs)
+	sp.connectedSynapses = NewPackedBinaryMatrix(sp.numColumns, sp.numInputs)
 	sp.connectedCounts = make([]int, sp.numColumns)
 	sp.SynPermMax = 1
 	sp.SynPermMin = 0
@@ -1031,7 +1104,7 @@ func TestAdaptSynapses(t *testing.T) {
 		{1, 0, 0, 0, 1, 1, 0, 1},
 		{0, 0, 1, 0, 0, 0, 1, 0},
 		{1, 0, 0, 0, 0, 0, 1, 0}}
-	sp.potentialPools = NewDenseBinaryMatrixFromInts(ints)
+	sp.potentialPools = NewPackedBinaryMatrixFromInts(ints)
 
 	inputVector := []bool{true, false, false, true, true, false, true, false}
 	activeColumns := []int{0, 1, 2}
@@ -1040,11 +1113,7 @@ func TestAdaptSynapses(t *testing.T) {
 		0.150, 0.000, 0.000, 0.000, 0.180, 0.120, 0.000, 0.450,
 		0.000, 0.000, 0.014, 0.000, 0.000, 0.000, 0.110, 0.000,
 		0.040, 0.000, 0.000, 0.000, 0.000, 0.000, 0.178, 0.000}
-	elms := make(map[int]float64, len(floats))
-	for i, val := range floats {
-		elms[i] = val
-	}
-	sp.permanences = matrix.MakeSparseMatrix(elms, 4, 8)
+	sp.permanences = NewSparseFloatMatrixFromDense1D(floats, 4, 8)
 
 	truePermanences := [][]float64{
 		{0.300, 0.110, 0.080, 0.140, 0.000, 0.000, 0.000, 0.000},
@@ -1071,7 +1140,7 @@ func TestAdaptSynapses(t *testing.T) {
 		{0, 0, 1, 1, 1, 0, 0, 0},
 		{1, 0, 0, 0, 0, 0, 1, 0}}
 
-	sp.potentialPools = NewDenseBinaryMatrixFromInts(ints)
+	sp.potentialPools = NewPackedBinaryMatrixFromInts(ints)
 
 	inputVector = []bool{true, false, false, true, true, false, true, false}
 	activeColumns = []int{0, 1, 2}
@@ -1080,10 +1149,7 @@ func TestAdaptSynapses(t *testing.T) {
 		0.000, 0.017, 0.232, 0.400, 0.000, 0.000, 0.000, 0.000,
 		0.000, 0.000, 0.014, 0.051, 0.730, 0.000, 0.000, 0.000,
 		0.170, 0.000, 0.000, 0.000, 0.000, 0.000, 0.380, 0.000}
-	for i, val := range floats {
-		elms[i] = val
-	}
-	sp.permanences = matrix.MakeSparseMatrix(elms, 4, 8)
+	sp.permanences = NewSparseFloatMatrixFromDense1D(floats, 4, 8)
 
 	truePermanences = [][]float64{
 		{0.30, 0.110, 0.080, 0.000, 0.000, 0.000, 0.000, 0.000},
@@ -1268,7 +1334,7 @@ func TestBumpUpWeakColumns(t *testing.T) {
 	sp.minOverlapDutyCycles = utils.MakeSliceFloat64(5, 0.01)
 	sp.SynPermInactiveDec = 0.01
 	sp.SynPermActiveInc = 0.1
-	sp.connectedSynapses = NewDenseBinaryMatrix(sp.numColumns, sp.numInputs)
+	sp.connectedSynapses = NewPackedBinaryMatrix(sp.numColumns, sp.numInputs)
 	sp.connectedCounts = make([]int, sp.numColumns)
 	sp.SynPermMax = 1
 	sp.SynPermMin = 0
@@ -1286,18 +1352,14 @@ func TestBumpUpWeakColumns(t *testing.T) {
 		{0, 0, 1, 0, 1, 1, 1, 0},
 		{1, 1, 1, 0, 0, 0, 1, 0},
 		{1, 1, 1, 1, 1, 1, 1, 1}}
-	sp.potentialPools = NewDenseBinaryMatrixFromInts(ints)
+	sp.potentialPools = NewPackedBinaryMatrixFromInts(ints)
 
 	floats := []float64{0.200, 0.120, 0.090, 0.040, 0.000, 0.000, 0.000, 0.000,
 		0.150, 0.000, 0.000, 0.000, 0.180, 0.120, 0.000, 0.450,
 		0.000, 0.000, 0.014, 0.000, 0.032, 0.044, 0.110, 0.000,
 		0.041, 0.000, 0.000, 0.000, 0.000, 0.000, 0.178, 0.000,
 		0.100, 0.738, 0.045, 0.002, 0.050, 0.008, 0.208, 0.034}
-	elms := make(map[int]float64, len(floats))
-	for i, val := range floats {
-		elms[i] = val
-	}
-	sp.permanences = matrix.MakeSparseMatrix(elms, 5, 8)
+	sp.permanences = NewSparseFloatMatrixFromDense1D(floats, 5, 8)
 
 	truePermanences := [][]float64{
 		{0.210, 0.130, 0.100, 0.000, 0.000, 0.000, 0.000, 0.000},
@@ -1398,14 +1460,14 @@ func TestCompute1(t *testing.T) {
 	spParams.MaxBoost = 10.0
 	sp := NewSpatialPooler(spParams)
 
-	sp.potentialPools = NewDenseBinaryMatrix(sp.numColumns, sp.numInputs)
+	sp.potentialPools = NewPackedBinaryMatrix(sp.numColumns, sp.numInputs)
 	for i := 0; i < sp.numColumns; i++ {
 		for j := 0; j < sp.numInputs; j++ {
 			sp.potentialPools.Set(i, j, true)
 		}
 	}
 
-	inhibitColumnsMock := func(overlaps []float64, inhibitColumnsGlobal, inhibitColumnsLocal inhibitColumnsFunc) []int {
+	inhibitColumnsMock := func(overlaps []float64, inhibitColumnsGlobal, inhibitColumnsLocal InhibitColumnsFunc) []int {
 		return []int{0, 1, 2, 3, 4}
 	}
 
@@ -1416,7 +1478,7 @@ func TestCompute1(t *testing.T) {
 		sp.Compute(inputVector, true, activeArray, inhibitColumnsMock)
 	}
 
-	for i := 0; i < 20; i++ {
+	for i := 0; i < sp.numColumns; i++ {
 		perm := Float64SliceToInt(GetRowFromSM(sp.permanences, i))
 		assert.Equal(t, inputVector, utils.Make1DBool(perm))
 	}
@@ -1447,7 +1509,7 @@ func TestCompute2(t *testing.T) {
 	spParams.MaxBoost = 10.0
 	sp := NewSpatialPooler(spParams)
 
-	inhibitColumnsMock := func(overlaps []float64, inhibitColumnsGlobal, inhibitColumnsLocal inhibitColumnsFunc) []int {
+	inhibitColumnsMock := func(overlaps []float64, inhibitColumnsGlobal, inhibitColumnsLocal InhibitColumnsFunc) []int {
 		return []int{0, 1, 2, 3, 4}
 	}
 
@@ -1542,12 +1604,8 @@ func Float64SliceToInt(values []float64) []int {
 	return result
 }
 
-func GetRowFromSM(mat *matrix.SparseMatrix, row int) []float64 {
-	result := make([]float64, mat.Cols())
-	for i := 0; i < mat.Cols(); i++ {
-		result[i] = mat.Get(row, i)
-	}
-	return result
+func GetRowFromSM(mat *SparseFloatMatrix, row int) []float64 {
+	return mat.GetDenseRow(row)
 }
 
 func AlmostEqualFloat(a, b float64) bool {
@@ -1556,18 +1614,49 @@ func AlmostEqualFloat(a, b float64) bool {
 	return ar == br
 }
 
-func SparseMatrixToArray(m *matrix.SparseMatrix) []float64 {
-	result := make([]float64, m.Cols())
-	for i := 0; i < m.Cols(); i++ {
-		result[i] = m.Get(0, i)
-	}
-	return result
-}
-
-func AddDenseToSparseHelper(dense [][]float64, m *matrix.SparseMatrix) {
+func AddDenseToSparseHelper(dense [][]float64, m *SparseFloatMatrix) {
 	for r := 0; r < len(dense); r++ {
 		for c := 0; c < len(dense[r]); c++ {
 			m.Set(r, c, dense[r][c])
 		}
 	}
 }
+
+func BenchmarkInhibitColumnsGlobal(b *testing.B) {
+	sp := SpatialPooler{}
+	sp.numColumns = 4096
+	overlaps := utils.RandomSample(sp.numColumns)
+	for i := range overlaps {
+		overlaps[i] *= 40
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sp.inhibitColumnsGlobal(overlaps, 0.02)
+	}
+}
+
+func BenchmarkSpatialPoolerCompute(b *testing.B) {
+	spParams := NewSpParams()
+	spParams.InputDimensions = []int{1024}
+	spParams.ColumnDimensions = []int{512}
+	spParams.PotentialRadius = 1024
+	spParams.PotentialPct = 0.5
+	spParams.GlobalInhibition = true
+	spParams.NumActiveColumnsPerInhArea = 10
+	sp := NewSpatialPooler(spParams)
+
+	inputs := make([][]bool, 10)
+	for i := range inputs {
+		inputs[i] = make([]bool, 1024)
+		for j := range inputs[i] {
+			inputs[i][j] = rand.Intn(50) == 0
+		}
+	}
+	activeArray := make([]bool, 512)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sp.Compute(inputs[i%len(inputs)], true, activeArray, sp.InhibitColumns)
+	}
+}
@@ -0,0 +1,443 @@
+/*
+ Package swarm searches model params for the configuration that best fits
+a data set. Candidate configs are derived from a base config by setting
+the searched params, run over the records in parallel and ranked by their
+score.
+
+	params := swarm.NewSearchParams()
+	params.Ranges = []swarm.Range{
+		{Param: "SP.PotentialPct", Min: 0.2, Max: 0.8, Step: 0.2},
+		{Param: "TM.ActivationThreshold", Min: 8, Max: 16, Step: 4, Integer: true},
+		{Param: "Fields.consumption.Params.Width", Min: 15, Max: 31, Step: 8, Integer: true},
+	}
+	results, err := swarm.Run(base, records, params)
+	best, _ := results.Best()
+	results.WriteTable(os.Stdout)
+*/
+package swarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/nupic-community/htm/model"
+	"io"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+//Scores candidate models are ranked by, lower scores are better
+type Metric int
+
+const (
+	//Mean absolute error of the predicted fields value Steps records ahead
+	MeanAbsoluteError Metric = iota
+	//Mean raw anomaly score
+	MeanAnomalyScore
+)
+
+func (m Metric) String() string {
+	switch m {
+	case MeanAbsoluteError:
+		return "mean absolute error"
+	case MeanAnomalyScore:
+		return "mean anomaly score"
+	}
+	return "unknown"
+}
+
+//Values searched for a single param
+type Range struct {
+	//Dotted path of the param in the model config, e.g. "SP.PotentialPct".
+	//Encoder params are addressed by field name, e.g.
+	//"Fields.consumption.Params.Width"
+	Param string
+	Min   float64
+	Max   float64
+	//Spacing of the grid values, ignored when candidates are sampled
+	Step float64
+	//Round values to integers
+	Integer bool
+}
+
+//Returns the grid values of the range
+func (r Range) values() []float64 {
+	if r.Max == r.Min {
+		return []float64{r.round(r.Min)}
+	}
+
+	var result []float64
+	for i := 0; ; i++ {
+		val := r.Min + float64(i)*r.Step
+		// Allow for accumulated float error on the last step
+		if val > r.Max+r.Step*1e-9 {
+			break
+		}
+		val = r.round(math.Min(val, r.Max))
+		if len(result) == 0 || result[len(result)-1] != val {
+			result = append(result, val)
+		}
+	}
+	return result
+}
+
+//Returns a random value of the range
+func (r Range) sample(rng *rand.Rand) float64 {
+	return r.round(r.Min + rng.Float64()*(r.Max-r.Min))
+}
+
+func (r Range) round(val float64) float64 {
+	if r.Integer {
+		return math.Floor(val + 0.5)
+	}
+	return val
+}
+
+type SearchParams struct {
+	Ranges []Range
+	Metric Metric
+	//Steps ahead scored by MeanAbsoluteError, 0 uses the first classifier step
+	Steps int
+	//Number of records run before scoring starts
+	BurnIn int
+	//Number of randomly sampled candidates, 0 searches the full grid
+	Candidates int
+	//Number of candidates run in parallel
+	Workers int
+	//Seeds candidate sampling, candidate i seeds its models with Seed+i
+	Seed int
+}
+
+//Initializes default search params
+func NewSearchParams() SearchParams {
+	p := SearchParams{}
+	p.Metric = MeanAbsoluteError
+	p.Workers = runtime.NumCPU()
+	p.Seed = 42
+	return p
+}
+
+//Score of a single candidate
+type Result struct {
+	//Searched param values of the candidate, keyed by path
+	Params map[string]float64
+	Config *model.Config
+	Score  float64
+	//Set when the candidate could not be built or run
+	Err error
+}
+
+//Results ranked best first, failed candidates last
+type Results []Result
+
+//Returns the best candidate, false if every candidate failed
+func (r Results) Best() (Result, bool) {
+	if len(r) == 0 || r[0].Err != nil {
+		return Result{}, false
+	}
+	return r[0], true
+}
+
+//Writes the ranked results as an aligned text table
+func (r Results) WriteTable(w io.Writer) error {
+	var params []string
+	if len(r) > 0 {
+		for param := range r[0].Params {
+			params = append(params, param)
+		}
+		sort.Strings(params)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "rank\tscore\t%v\n", strings.Join(params, "\t"))
+	for idx, result := range r {
+		score := strconv.FormatFloat(result.Score, 'g', 6, 64)
+		if result.Err != nil {
+			score = "error: " + result.Err.Error()
+		}
+		values := make([]string, len(params))
+		for i, param := range params {
+			values[i] = strconv.FormatFloat(result.Params[param], 'g', 6, 64)
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", idx+1, score, strings.Join(values, "\t"))
+	}
+	return tw.Flush()
+}
+
+/*
+ Runs every candidate derived from base over the records and returns the
+ranked results. Candidates learn as they go, so every candidate starts
+from scratch. Loggers, observers and SP topologies of the base config are
+shared by all candidates and must be safe for concurrent use.
+*/
+func Run(base *model.Config, records []model.Record, params SearchParams) (Results, error) {
+	if err := validate(base, records, &params); err != nil {
+		return nil, err
+	}
+
+	candidates := candidateValues(params)
+	results := make(Results, len(candidates))
+	jobs := make(chan int)
+	done := make(chan bool)
+
+	workers := params.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	for w := 0; w < workers; w++ {
+		go func() {
+			for idx := range jobs {
+				results[idx] = runCandidate(base, records, params, candidates[idx], params.Seed+idx)
+			}
+			done <- true
+		}()
+	}
+
+	for idx := range candidates {
+		jobs <- idx
+	}
+	close(jobs)
+	for w := 0; w < workers; w++ {
+		<-done
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if (results[i].Err == nil) != (results[j].Err == nil) {
+			return results[i].Err == nil
+		}
+		return results[i].Score < results[j].Score
+	})
+
+	return results, nil
+}
+
+func validate(base *model.Config, records []model.Record, params *SearchParams) error {
+	if len(records) == 0 {
+		return fmt.Errorf("no records to search with")
+	}
+	if len(params.Ranges) == 0 {
+		return fmt.Errorf("no param ranges specified")
+	}
+	for _, r := range params.Ranges {
+		if len(r.Param) == 0 {
+			return fmt.Errorf("range param not specified")
+		}
+		if r.Min > r.Max {
+			return fmt.Errorf("range %v: min is greater than max", r.Param)
+		}
+		if params.Candidates == 0 && r.Min != r.Max && r.Step <= 0 {
+			return fmt.Errorf("range %v: step must be greater than 0 for a grid search", r.Param)
+		}
+	}
+	if params.Candidates < 0 {
+		return fmt.Errorf("candidates must be >= 0")
+	}
+	if params.BurnIn >= len(records) {
+		return fmt.Errorf("burn in leaves no records to score")
+	}
+
+	switch params.Metric {
+	case MeanAbsoluteError:
+		if len(base.PredictedField) == 0 {
+			return fmt.Errorf("%v requires a predicted field", params.Metric)
+		}
+		if params.Steps == 0 && len(base.Classifier.Steps) > 0 {
+			params.Steps = base.Classifier.Steps[0]
+		}
+	case MeanAnomalyScore:
+	default:
+		return fmt.Errorf("unknown metric %v", int(params.Metric))
+	}
+
+	return nil
+}
+
+//Returns the param values of every candidate
+func candidateValues(params SearchParams) []map[string]float64 {
+	var result []map[string]float64
+
+	if params.Candidates > 0 {
+		rng := rand.New(rand.NewSource(int64(params.Seed)))
+		for i := 0; i < params.Candidates; i++ {
+			values := make(map[string]float64, len(params.Ranges))
+			for _, r := range params.Ranges {
+				values[r.Param] = r.sample(rng)
+			}
+			result = append(result, values)
+		}
+		return result
+	}
+
+	// Cartesian product of the grid values
+	result = []map[string]float64{{}}
+	for _, r := range params.Ranges {
+		var next []map[string]float64
+		for _, values := range result {
+			for _, val := range r.values() {
+				candidate := make(map[string]float64, len(values)+1)
+				for k, v := range values {
+					candidate[k] = v
+				}
+				candidate[r.Param] = val
+				next = append(next, candidate)
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+//Builds, runs and scores a single candidate
+func runCandidate(base *model.Config, records []model.Record, params SearchParams,
+	values map[string]float64, seed int) (result Result) {
+
+	result.Params = values
+	defer func() {
+		if r := recover(); r != nil {
+			result.Err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	cfg, err := candidateConfig(base, values)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	cfg.SP.Seed = seed
+	cfg.TM.Seed = seed
+	cfg.TP.Seed = seed
+	result.Config = cfg
+
+	pipeline, err := model.NewPipeline(cfg)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Score, result.Err = score(pipeline, records, params)
+	return result
+}
+
+//Runs the records through a pipeline and returns its score
+func score(pipeline *model.Pipeline, records []model.Record, params SearchParams) (float64, error) {
+	// Predictions made for each record, NaN when there was none
+	predictions := make([]float64, len(records))
+	total, count := 0.0, 0
+
+	for idx, record := range records {
+		result, err := pipeline.Compute(record, true)
+		if err != nil {
+			return 0, fmt.Errorf("record %v: %v", idx, err)
+		}
+
+		switch params.Metric {
+		case MeanAbsoluteError:
+			predictions[idx] = math.NaN()
+			if val, ok := result.Classification.BestPrediction(params.Steps); ok {
+				predictions[idx] = val
+			}
+			prev := idx - params.Steps
+			if idx >= params.BurnIn && prev >= 0 && !math.IsNaN(predictions[prev]) {
+				total += math.Abs(result.ActualValue - predictions[prev])
+				count++
+			}
+		case MeanAnomalyScore:
+			if idx >= params.BurnIn {
+				total += result.AnomalyScore
+				count++
+			}
+		}
+	}
+
+	if count == 0 {
+		return 0, fmt.Errorf("no records scored")
+	}
+	return total / float64(count), nil
+}
+
+/*
+ Returns a copy of base with the param values set. The config is round
+tripped through JSON so the values get the same strict validation as a
+config file.
+*/
+func candidateConfig(base *model.Config, values map[string]float64) (*model.Config, error) {
+	// Fields that can't be serialized are carried over as is
+	serializable := *base
+	serializable.SP.Logger, serializable.SP.Observer, serializable.SP.Topology = nil, nil, nil
+	serializable.TM.Observer = nil
+	serializable.TP.Logger, serializable.TP.Observer = nil, nil
+
+	data, err := json.Marshal(&serializable)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	for param, val := range values {
+		if err := setParam(doc, param, val); err != nil {
+			return nil, err
+		}
+	}
+
+	if data, err = json.Marshal(doc); err != nil {
+		return nil, err
+	}
+	cfg, err := model.ParseJSONConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.SP.Logger, cfg.SP.Observer, cfg.SP.Topology = base.SP.Logger, base.SP.Observer, base.SP.Topology
+	cfg.TM.Observer = base.TM.Observer
+	cfg.TP.Logger, cfg.TP.Observer = base.TP.Logger, base.TP.Observer
+	return cfg, nil
+}
+
+//Sets a dotted path in a decoded JSON config
+func setParam(doc map[string]interface{}, param string, val float64) error {
+	path := strings.Split(param, ".")
+	current := doc
+
+	for idx := 0; idx < len(path)-1; idx++ {
+		key := path[idx]
+
+		// Fields are addressed by name rather than index
+		if key == "Fields" && idx+1 < len(path)-1 {
+			fields, _ := current[key].([]interface{})
+			var found map[string]interface{}
+			for _, field := range fields {
+				if f, ok := field.(map[string]interface{}); ok && f["Name"] == path[idx+1] {
+					found = f
+				}
+			}
+			if found == nil {
+				return fmt.Errorf("param %v: field %v not found", param, path[idx+1])
+			}
+			current = found
+			idx++
+			continue
+		}
+
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			// Encoder params may be left out of the base config
+			if current[key] != nil {
+				return fmt.Errorf("param %v: %v is not an object", param, key)
+			}
+			next = make(map[string]interface{})
+			current[key] = next
+		}
+		current = next
+	}
+
+	// Unknown params are rejected when the config is decoded
+	current[path[len(path)-1]] = val
+	return nil
+}
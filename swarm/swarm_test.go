@@ -0,0 +1,157 @@
+package swarm
+
+import (
+	"bytes"
+	"github.com/nupic-community/htm/model"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const testConfig = `{
+	"Fields": [
+		{"Name": "value", "Type": "scalar",
+		 "Params": {"Width": 21, "MinVal": 0, "MaxVal": 100, "N": 200, "ClipInput": true}}
+	],
+	"PredictedField": "value",
+	"SP": {"ColumnDimensions": [1024], "PotentialRadius": 500, "GlobalInhibition": true,
+		"NumActiveColumnsPerInhArea": 20},
+	"TM": {"CellsPerColumn": 4, "ActivationThreshold": 12, "MinThreshold": 8,
+		"MaxNewSynapseCount": 20, "InitialPermanence": 0.51},
+	"Classifier": {"Steps": [1], "Alpha": 0.1}
+}`
+
+func testRecords(n int) []model.Record {
+	records := make([]model.Record, n)
+	for idx := range records {
+		records[idx] = model.Record{"value": float64((idx % 4) * 25)}
+	}
+	return records
+}
+
+func testSearchParams() SearchParams {
+	params := NewSearchParams()
+	params.Workers = 3
+	params.BurnIn = 150
+	params.Ranges = []Range{
+		{Param: "SP.PotentialPct", Min: 0.4, Max: 0.8, Step: 0.4},
+		{Param: "Fields.value.Params.Width", Min: 15, Max: 21, Step: 6, Integer: true},
+	}
+	return params
+}
+
+func TestRangeValues(t *testing.T) {
+	assert.Equal(t, []float64{0.1, 0.2, 0.3}, Range{Min: 0.1, Max: 0.3, Step: 0.1}.values())
+	assert.Equal(t, []float64{1, 3, 4}, Range{Min: 1, Max: 4, Step: 1.5, Integer: true}.values())
+	assert.Equal(t, []float64{2}, Range{Min: 2, Max: 2}.values())
+}
+
+func TestCandidateConfig(t *testing.T) {
+	base, err := model.ParseJSONConfig([]byte(testConfig))
+	assert.Nil(t, err)
+
+	cfg, err := candidateConfig(base, map[string]float64{
+		"SP.PotentialPct":           0.3,
+		"TM.ActivationThreshold":    5,
+		"Fields.value.Params.Width": 15,
+		"Classifier.Alpha":          0.2,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 0.3, cfg.SP.PotentialPct)
+	assert.Equal(t, 5, cfg.TM.ActivationThreshold)
+	assert.Equal(t, 0.2, cfg.Classifier.Alpha)
+	assert.True(t, strings.Contains(string(cfg.Fields[0].Params), `"Width":15`))
+	//the base config is left untouched
+	assert.Equal(t, 0.5, base.SP.PotentialPct)
+	assert.Equal(t, 12, base.TM.ActivationThreshold)
+
+	_, err = candidateConfig(base, map[string]float64{"SP.PotentialPtc": 0.3})
+	assert.NotNil(t, err)
+	_, err = candidateConfig(base, map[string]float64{"TM.ActivationThreshold": 5.5})
+	assert.NotNil(t, err)
+	_, err = candidateConfig(base, map[string]float64{"Fields.missing.Params.Width": 15})
+	assert.Equal(t, "param Fields.missing.Params.Width: field missing not found", err.Error())
+}
+
+func TestGridSearch(t *testing.T) {
+	base, err := model.ParseJSONConfig([]byte(testConfig))
+	assert.Nil(t, err)
+
+	results, err := Run(base, testRecords(200), testSearchParams())
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(results))
+
+	for idx, result := range results {
+		assert.Nil(t, result.Err)
+		assert.Equal(t, result.Params["SP.PotentialPct"], result.Config.SP.PotentialPct)
+		if idx > 0 {
+			assert.True(t, results[idx-1].Score <= result.Score)
+		}
+	}
+
+	best, ok := results.Best()
+	assert.True(t, ok)
+	assert.Equal(t, results[0].Score, best.Score)
+	//the repeating sequence is learned
+	assert.True(t, best.Score < 10)
+
+	//runs are reproducible regardless of scheduling
+	again, err := Run(base, testRecords(200), testSearchParams())
+	assert.Nil(t, err)
+	for idx := range results {
+		assert.Equal(t, results[idx].Params, again[idx].Params)
+		assert.Equal(t, results[idx].Score, again[idx].Score)
+	}
+
+	var table bytes.Buffer
+	assert.Nil(t, results.WriteTable(&table))
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	assert.Equal(t, 5, len(lines))
+	assert.Equal(t, []string{"rank", "score", "Fields.value.Params.Width", "SP.PotentialPct"}, strings.Fields(lines[0]))
+	assert.Equal(t, "1", strings.Fields(lines[1])[0])
+}
+
+func TestRandomSearch(t *testing.T) {
+	base, err := model.ParseJSONConfig([]byte(testConfig))
+	assert.Nil(t, err)
+
+	params := testSearchParams()
+	params.Metric = MeanAnomalyScore
+	params.Candidates = 3
+	params.Ranges = append(params.Ranges, Range{Param: "Classifier.Alpha", Min: -1, Max: -0.5})
+
+	results, err := Run(base, testRecords(160), params)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results))
+	for _, result := range results {
+		assert.True(t, result.Params["SP.PotentialPct"] >= 0.4 && result.Params["SP.PotentialPct"] <= 0.8)
+		width := result.Params["Fields.value.Params.Width"]
+		assert.True(t, width == 15 || width == 17 || width == 19 || width == 21 ||
+			width == 16 || width == 18 || width == 20)
+		//the classifier learning rates are invalid
+		assert.NotNil(t, result.Err)
+	}
+	_, ok := results.Best()
+	assert.False(t, ok)
+}
+
+func TestSearchErrors(t *testing.T) {
+	base, err := model.ParseJSONConfig([]byte(testConfig))
+	assert.Nil(t, err)
+
+	params := testSearchParams()
+	_, err = Run(base, nil, params)
+	assert.Equal(t, "no records to search with", err.Error())
+
+	params.Ranges = []Range{{Param: "SP.PotentialPct", Min: 0.4, Max: 0.8}}
+	_, err = Run(base, testRecords(50), params)
+	assert.Equal(t, "range SP.PotentialPct: step must be greater than 0 for a grid search", err.Error())
+
+	params = testSearchParams()
+	_, err = Run(base, testRecords(150), params)
+	assert.Equal(t, "burn in leaves no records to score", err.Error())
+
+	base.PredictedField = ""
+	_, err = Run(base, testRecords(200), testSearchParams())
+	assert.Equal(t, "mean absolute error requires a predicted field", err.Error())
+}
@@ -9,7 +9,7 @@ import (
 	//"github.com/zacg/ints"
 	"math"
 	"math/rand"
-	// 	//"sort"
+	"sort"
 )
 
 /*
@@ -38,6 +38,8 @@ type TemporalMemoryParams struct {
 	PermanenceDecrement float64
 	//rand seed
 	Seed int
+	//Notified after every compute, may be nil
+	Observer Observer
 }
 
 //Create default temporal memory params
@@ -70,6 +72,8 @@ type TemporalMemory struct {
 	ActiveSynapsesForSegment map[int][]int
 	WinnerCells              []int
 	Connections              *TemporalMemoryConnections
+	iteration                int
+	rand                     *rand.Rand
 }
 
 //Create new temporal memory
@@ -78,14 +82,16 @@ func NewTemporalMemory(params *TemporalMemoryParams) *TemporalMemory {
 	tm.params = params
 	tm.Connections = NewTemporalMemoryConnections(params.MaxNewSynapseCount,
 		params.CellsPerColumn, params.ColumnDimensions)
-	//TODO: refactor into encapsulated RNG
-	rand.Seed(int64(params.Seed))
+	tm.rand = rand.New(rand.NewSource(int64(params.Seed)))
 	return tm
 }
 
 //Feeds input record through TM, performing inference and learning.
 //Updates member variables with new state.
 func (tm *TemporalMemory) Compute(activeColumns []int, learn bool) {
+	tm.iteration++
+	numSegments := tm.Connections.NumberOfSegments()
+	numSynapses := tm.Connections.NumberOfSynapses()
 
 	activeCells, winnerCells, activeSynapsesForSegment, activeSegments, predictiveCells := tm.computeFn(activeColumns,
 		tm.PredictiveCells,
@@ -101,6 +107,34 @@ func (tm *TemporalMemory) Compute(activeColumns []int, learn bool) {
 	tm.ActiveSegments = activeSegments
 	tm.PredictiveCells = predictiveCells
 
+	if tm.params.Observer != nil {
+		tm.params.Observer.TemporalMemoryStep(TmStepEvent{
+			Iteration:       tm.iteration,
+			Learn:           learn,
+			ActiveColumns:   len(activeColumns),
+			BurstingColumns: tm.countBurstingColumns(),
+			ActiveCells:     len(tm.ActiveCells),
+			PredictiveCells: len(tm.PredictiveCells),
+			ActiveSegments:  len(tm.ActiveSegments),
+			SegmentsCreated: tm.Connections.NumberOfSegments() - numSegments,
+			SynapsesCreated: tm.Connections.NumberOfSynapses() - numSynapses,
+		})
+	}
+
+}
+
+//Returns the number of columns whose cells are all active
+func (tm *TemporalMemory) countBurstingColumns() int {
+	counts := make(map[int]int)
+	result := 0
+	for _, cell := range tm.ActiveCells {
+		col := tm.Connections.ColumnForCell(cell)
+		counts[col]++
+		if counts[col] == tm.params.CellsPerColumn {
+			result++
+		}
+	}
+	return result
 }
 
 // helper for compute().
@@ -129,8 +163,10 @@ func (tm *TemporalMemory) computeFn(activeColumns []int,
 		prevActiveSynapsesForSegment,
 		connections)
 
-	utils.Add(activeCells, _activeCells)
-	utils.Add(winnerCells, _winnerCells)
+	// Cells of bursting columns are active too, and their best matching
+	// cells are winners
+	activeCells = utils.Add(activeCells, _activeCells)
+	winnerCells = utils.Add(winnerCells, _winnerCells)
 
 	if learn {
 		tm.learnOnSegments(prevActiveSegments,
@@ -282,7 +318,7 @@ func (tm *TemporalMemory) lrnOnSegments(segments []int,
 			n := tm.params.MaxNewSynapseCount - len(activeSynapses)
 			for _, sourceCell := range tm.pickCellsToLearnOn(n,
 				segment,
-				winnerCells,
+				prevWinnerCells,
 				connections) {
 				connections.CreateSynapse(segment, sourceCell, tm.params.InitialPermanence)
 			}
@@ -305,7 +341,14 @@ Pseudocode:
 func (tm *TemporalMemory) computePredictiveCells(activeSynapsesForSegment map[int][]int,
 	connections *TemporalMemoryConnections) (activeSegments []int, predictiveCells []int) {
 
-	for segment, _ := range activeSynapsesForSegment {
+	// Visit segments in order so results don't depend on map iteration
+	segments := make([]int, 0, len(activeSynapsesForSegment))
+	for segment := range activeSynapsesForSegment {
+		segments = append(segments, segment)
+	}
+	sort.Ints(segments)
+
+	for _, segment := range segments {
 		synapses := tm.getConnectedActiveSynapsesForSegment(segment,
 			activeSynapsesForSegment,
 			tm.params.ConnectedPermanence,
@@ -412,7 +455,7 @@ func (tm *TemporalMemory) getLeastUsedCell(column int, connections *TemporalMemo
 	}
 
 	//pick random cell
-	return leastUsedCells[rand.Intn(len(leastUsedCells))]
+	return leastUsedCells[tm.rand.Intn(len(leastUsedCells))]
 }
 
 //Returns the synapses on a segment that are active due to lateral input
@@ -477,10 +520,11 @@ func (tm *TemporalMemory) pickCellsToLearnOn(n int, segment int,
 
 	//Shuffle candidates
 	for i := range candidates {
-		j := rand.Intn(i + 1)
+		j := tm.rand.Intn(i + 1)
 		candidates[i], candidates[j] = candidates[j], candidates[i]
 	}
 
-	n = mathutil.Min(n, len(candidates))
+	// Segments can already have more active synapses than new ones allowed
+	n = mathutil.Max(0, mathutil.Min(n, len(candidates)))
 	return candidates[:n]
 }
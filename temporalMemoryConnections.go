@@ -159,6 +159,16 @@ func (tmc *TemporalMemoryConnections) NumberOfcells() int {
 	return tmc.NumberOfColumns() * tmc.CellsPerColumn
 }
 
+//Returns the number of segments created
+func (tmc *TemporalMemoryConnections) NumberOfSegments() int {
+	return len(tmc.segments)
+}
+
+//Returns the number of synapses created
+func (tmc *TemporalMemoryConnections) NumberOfSynapses() int {
+	return len(tmc.synapses)
+}
+
 //Validation
 
 func (tmc *TemporalMemoryConnections) validatePermanence(permanence float64) {
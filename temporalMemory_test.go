@@ -2,6 +2,7 @@ package htm
 
 import (
 	//"fmt"
+	"github.com/nupic-community/htm/utils"
 	"github.com/stretchr/testify/assert"
 	"sort"
 	"testing"
@@ -314,6 +315,7 @@ func TestComputePredictiveCells(t *testing.T) {
 
 func TestLearnOnSegments(t *testing.T) {
 	tmp := NewTemporalMemoryParams()
+	tmp.MaxNewSynapseCount = 2
 	tm := NewTemporalMemory(tmp)
 	connections := tm.Connections
 	connections.CreateSegment(0)
@@ -358,11 +360,67 @@ func TestLearnOnSegments(t *testing.T) {
 	assert.Equal(t, 0.9, connections.DataForSynapse(4).Permanence)
 	assert.Equal(t, 1, len(connections.synapsesForSegment[2]))
 
-	// Check segment 3
-	assert.Equal(t, 1, len(connections.synapsesForSegment[3]))
+	// Check segment 3, synapses are grown to previous winner cells
+	assert.Equal(t, 2, len(connections.synapsesForSegment[3]))
+	for _, syn := range connections.synapsesForSegment[3] {
+		assert.True(t, utils.ContainsInt(connections.DataForSynapse(syn).SourceCell, prevWinnerCells))
+	}
 
 }
 
+func TestLearnOnSegmentsGrowsToPrevWinnerCells(t *testing.T) {
+	tmp := NewTemporalMemoryParams()
+	tm := NewTemporalMemory(tmp)
+	connections := tm.Connections
+	connections.CreateSegment(0)
+
+	tm.learnOnSegments(nil, []int{0}, map[int][]int{}, []int{0, 1}, []int{10, 11}, connections)
+
+	// New synapses come from the cells that were winners a step earlier
+	var sources []int
+	for _, syn := range connections.synapsesForSegment[0] {
+		sources = append(sources, connections.DataForSynapse(syn).SourceCell)
+	}
+	sort.Ints(sources)
+	assert.Equal(t, []int{10, 11}, sources)
+}
+
+func TestLearnOnSegmentsFullSegment(t *testing.T) {
+	tmp := NewTemporalMemoryParams()
+	tmp.MaxNewSynapseCount = 1
+	tm := NewTemporalMemory(tmp)
+	connections := tm.Connections
+	connections.CreateSegment(0)
+	connections.CreateSynapse(0, 23, 0.6)
+	connections.CreateSynapse(0, 37, 0.6)
+
+	// More active synapses than new ones allowed, nothing is grown
+	assert.Equal(t, []int{}, tm.pickCellsToLearnOn(-1, 0, []int{10, 11}, connections))
+	tm.learnOnSegments(nil, []int{0}, map[int][]int{0: []int{0, 1}}, []int{0}, []int{10, 11}, connections)
+	assert.Equal(t, 2, len(connections.synapsesForSegment[0]))
+}
+
+func TestComputePredictiveCellsOrder(t *testing.T) {
+	tmp := NewTemporalMemoryParams()
+	tmp.ActivationThreshold = 1
+	tm := NewTemporalMemory(tmp)
+	connections := tm.Connections
+
+	activeSynapsesForSegment := make(map[int][]int)
+	for cell := 50; cell > 0; cell-- {
+		segment := connections.CreateSegment(cell)
+		connections.CreateSynapse(segment, 0, 0.9)
+		activeSynapsesForSegment[segment] = connections.synapsesForSegment[segment]
+	}
+
+	// Segments are visited in order, whatever the map iteration order
+	activeSegments, predictiveCells := tm.computePredictiveCells(activeSynapsesForSegment, connections)
+	assert.True(t, sort.IntsAreSorted(activeSegments))
+	assert.Equal(t, 50, len(activeSegments))
+	assert.Equal(t, 50, predictiveCells[0])
+	assert.Equal(t, 1, predictiveCells[49])
+}
+
 func TestBurstColumnsEmpty(t *testing.T) {
 	tmp := NewTemporalMemoryParams()
 	tm := NewTemporalMemory(tmp)
@@ -423,6 +481,26 @@ func TestBurstColumns(t *testing.T) {
 
 }
 
+func TestComputeBurstingColumns(t *testing.T) {
+	tmp := NewTemporalMemoryParams()
+	tmp.ColumnDimensions = []int{8}
+	tmp.CellsPerColumn = 4
+	tm := NewTemporalMemory(tmp)
+
+	// Nothing is predicted, so every cell of an active column is active
+	tm.Compute([]int{2, 5}, true)
+	activeCells := append([]int{}, tm.ActiveCells...)
+	sort.Ints(activeCells)
+	assert.Equal(t, []int{8, 9, 10, 11, 20, 21, 22, 23}, activeCells)
+
+	// and each column has one winner cell
+	assert.Equal(t, 2, len(tm.WinnerCells))
+	winnerColumns := []int{tm.Connections.ColumnForCell(tm.WinnerCells[0]),
+		tm.Connections.ColumnForCell(tm.WinnerCells[1])}
+	sort.Ints(winnerColumns)
+	assert.Equal(t, []int{2, 5}, winnerColumns)
+}
+
 func TestActivateCorrectlyPredictiveCellsEmpty(t *testing.T) {
 	tmp := NewTemporalMemoryParams()
 	tmp.CellsPerColumn = 4
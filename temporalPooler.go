@@ -1,22 +1,29 @@
 package htm
 
 import (
-	"fmt"
 	"github.com/cznic/mathutil"
 	"github.com/nupic-community/htm/utils"
 	"github.com/zacg/floats"
 	"github.com/zacg/go.matrix"
+	"io"
 	//"math"
 	"math/rand"
-	//"sort"
+	"sort"
 )
 
 type TpOutputType int
 
 const (
-	Normal                 TpOutputType = 0
-	ActiveState            TpOutputType = 1
+	//Union of the active and predicted cells
+	Normal TpOutputType = 0
+	//Active cells only
+	ActiveState TpOutputType = 1
+	//The most confident cell of each active column
 	ActiveState1CellPerCol TpOutputType = 2
+	//Active cells that were predicted on the previous time step, the
+	//cells whose context was recognized. Use OutputIndices to feed them
+	//to the next level of a hierarchy.
+	PredictedActiveCells TpOutputType = 3
 )
 
 type ProcessAction int
@@ -43,8 +50,16 @@ type TemporalPoolerParams struct {
 	SegUpdateValidDuration int
 	BurnIn                 int
 	CollectStats           bool
-	//Seed                   int
+	//Accumulate a per sequence confidence histogram, requires CollectStats
+	CollectSequenceStats bool
+	//Seed for segment learning and the random trivial predictor
+	Seed int
+	//Detail of the debug messages sent to Logger, 0 is silent
 	Verbosity int
+	//Receives diagnostic messages, nil discards them
+	Logger utils.Logger
+	//Notified after every compute, may be nil
+	Observer Observer
 	//checkSynapseConsistency=False, # for cpp only -- ignored
 	TrivialPredictionMethods []PredictorMethod
 	PamLength                int
@@ -54,7 +69,7 @@ type TemporalPoolerParams struct {
 	MaxSeqLength             int
 	MaxSegmentsPerCell       int
 	MaxSynapsesPerSegment    int
-	outputType               TpOutputType
+	OutputType               TpOutputType
 }
 
 type DynamicState struct {
@@ -129,16 +144,23 @@ type TemporalPooler struct {
 	iterationIdx    int
 	segId           int
 	CurrentOutput   *SparseBinaryMatrix
-	pamCounter      int
-	avgInputDensity float64
+	//When set, compute diagnostics are written here based on Verbosity
+	DiagnosticWriter io.Writer
+	pamCounter       int
+	avgInputDensity  float64
 	// Keeps track of the moving average of all learned sequence length.
 	avgLearnedSeqLength float64
 	resetCalled         bool
 	// Keeps track of the length of the sequence currently being learned.
 	learnedSeqLength     int
 	trivialPredictor     *TrivialPredictor
+	rand                 *rand.Rand
 	collectSequenceStats bool
 	internalStats        *TpStats
+	logger               utils.Logger
+	// Structural changes made during the current compute, reported to the
+	// observer
+	stepChanges TpStepEvent
 
 	//ephemeral state
 
@@ -160,6 +182,13 @@ type TemporalPooler struct {
 	*/
 	prevLrnPatterns [][]int
 
+	// Predictions made on the previous time step, unlike InfPredictedStateLast
+	// this is not replaced when backtracking
+	prevPredictedState *SparseBinaryMatrix
+
+	// Scratch state used by multi-step prediction
+	predictionScratch *predictionScratch
+
 	DynamicState *DynamicState
 }
 
@@ -182,7 +211,8 @@ func NewTemporalPoolerParams() *TemporalPoolerParams {
 	tps.SegUpdateValidDuration = 5
 	tps.BurnIn = 2
 	tps.CollectStats = false
-	tps.Verbosity = 3
+	tps.Seed = 42
+	tps.Verbosity = 0
 	//tps.TrivialPredictionMethods =
 	tps.PamLength = 1
 	tps.MaxInfBacktrack = 10
@@ -191,7 +221,7 @@ func NewTemporalPoolerParams() *TemporalPoolerParams {
 	tps.MaxSeqLength = 32
 	tps.MaxSegmentsPerCell = -1
 	tps.MaxSynapsesPerSegment = -1
-	tps.outputType = Normal
+	tps.OutputType = Normal
 
 	return tps
 }
@@ -200,6 +230,8 @@ func NewTemporalPoolerParams() *TemporalPoolerParams {
 func NewTemporalPooler(tParams TemporalPoolerParams) *TemporalPooler {
 	tp := new(TemporalPooler)
 	tp.params = tParams
+	tp.logger = utils.LoggerOrNop(tParams.Logger)
+	tp.rand = rand.New(rand.NewSource(int64(tParams.Seed)))
 
 	//validate args
 	if tParams.PamLength <= 0 {
@@ -237,13 +269,16 @@ func NewTemporalPooler(tParams TemporalPoolerParams) *TemporalPooler {
 	// Every self.cells[column][index] contains a list of segments
 	// Each segment is a structure of class Segment
 
-	//TODO: initialize cells
-	tp.cells = make([][][]Segment, tParams.NumberOfCols)
-	for c := 0; c < tParams.NumberOfCols; c++ {
-		tp.cells[c] = make([][]Segment, tParams.CellsPerColumn)
-
-		// for i := 0; i < tParams.CellsPerColumn; i++ {
-		// }
+	// In fixed size mode all segments and synapses are preallocated
+	if tParams.MaxSegmentsPerCell > 0 {
+		storage := newFixedSegmentStorage(tParams.NumberOfCols, tParams.CellsPerColumn,
+			tParams.MaxSegmentsPerCell, tParams.MaxSynapsesPerSegment)
+		tp.cells = storage.cells(tParams.NumberOfCols, tParams.CellsPerColumn)
+	} else {
+		tp.cells = make([][][]Segment, tParams.NumberOfCols)
+		for c := 0; c < tParams.NumberOfCols; c++ {
+			tp.cells[c] = make([][]Segment, tParams.CellsPerColumn)
+		}
 	}
 
 	tp.lrnIterationIdx = 0
@@ -261,13 +296,15 @@ func NewTemporalPooler(tParams TemporalPoolerParams) *TemporalPooler {
 	// Trivial prediction algorithms
 
 	if len(tParams.TrivialPredictionMethods) > 0 {
-		tp.trivialPredictor = MakeTrivialPredictor(tParams.NumberOfCols, tParams.TrivialPredictionMethods)
+		tp.trivialPredictor = MakeTrivialPredictor(tParams.NumberOfCols, tParams.TrivialPredictionMethods, tParams.Seed)
+		tp.trivialPredictor.Verbosity = tParams.Verbosity
+		tp.trivialPredictor.Logger = utils.WithFields(tParams.Logger, utils.Field("component", "trivialPredictor"))
 	} else {
 		tp.trivialPredictor = nil
 	}
 
 	// If True, the TP will compute a signature for each sequence
-	tp.collectSequenceStats = false
+	tp.collectSequenceStats = tParams.CollectSequenceStats
 
 	// This gets set when we receive a reset and cleared on the first compute
 	// following a reset.
@@ -286,6 +323,62 @@ func NewTemporalPooler(tParams TemporalPoolerParams) *TemporalPooler {
 	return tp
 }
 
+//Summarizes a compute step for the observer
+func (tp *TemporalPooler) stepEvent(activeColumns int, enableLearn, computeInfOutput bool) TpStepEvent {
+	event := tp.stepChanges
+	event.Iteration = tp.iterationIdx
+	event.Learn = enableLearn
+	event.ActiveColumns = activeColumns
+
+	activeState, predictedState := tp.DynamicState.InfActiveState, tp.DynamicState.InfPredictedState
+	if !computeInfOutput {
+		activeState, predictedState = tp.DynamicState.LrnActiveState, tp.DynamicState.LrnPredictedState
+	}
+	event.BurstingColumns = countBurstingColumns(activeState)
+	event.ActiveCells = activeState.TotalNonZeroCount()
+	event.PredictedCells = predictedState.TotalNonZeroCount()
+
+	return event
+}
+
+//Replaces the logger receiving diagnostic messages, nil discards them
+func (tp *TemporalPooler) SetLogger(logger utils.Logger) {
+	tp.params.Logger = logger
+	tp.logger = utils.LoggerOrNop(logger)
+	if tp.trivialPredictor != nil {
+		tp.trivialPredictor.Logger = utils.WithFields(logger, utils.Field("component", "trivialPredictor"))
+	}
+}
+
+//Returns true if debug messages of the specified verbosity should be logged
+func (tp *TemporalPooler) debugEnabled(verbosity int) bool {
+	return tp.params.Verbosity >= verbosity && tp.logger.Enabled(utils.Debug)
+}
+
+//Logs a debug message tagged with the pooler and current iteration
+func (tp *TemporalPooler) debug(msg string, fields ...utils.LogField) {
+	tp.logger.Log(utils.Debug, msg, append([]utils.LogField{utils.Field("component", "tp"),
+		utils.Field("iteration", tp.iterationIdx)}, fields...)...)
+}
+
+//Returns a copy of the stats collected when CollectStats is set
+func (tp *TemporalPooler) Stats() TpStats {
+	return *tp.internalStats.Copy()
+}
+
+//Returns a copy of the stats collected for each trivial prediction method,
+//nil if no TrivialPredictionMethods were specified
+func (tp *TemporalPooler) TrivialPredictorStats() map[PredictorMethod]TpStats {
+	if tp.trivialPredictor == nil {
+		return nil
+	}
+	result := make(map[PredictorMethod]TpStats, len(tp.trivialPredictor.Methods))
+	for _, method := range tp.trivialPredictor.Methods {
+		result[method] = *tp.trivialPredictor.InternalStats[method].Copy()
+	}
+	return result
+}
+
 //Returns new unique segment id
 func (tp *TemporalPooler) GetSegId() int {
 	result := tp.segId
@@ -325,76 +418,12 @@ func (tp *TemporalPooler) topDownCompute() []float64 {
 	return tp.columnConfidences()
 }
 
-/*
- This function gives the future predictions for <nSteps> timesteps starting
-from the current TP state. The TP is returned to its original state at the
-end before returning.
-
-- We save the TP state.
-- Loop for nSteps
-- Turn-on with lateral support from the current active cells
-- Set the predicted cells as the next step's active cells. This step
-in learn and infer methods use input here to correct the predictions.
-We don't use any input here.
-- Revert back the TP state to the time before prediction
-
-param nSteps The number of future time steps to be predicted
-returns all the future predictions - floating point matrix and
-shape (nSteps, numberOfCols).
-The ith row gives the tp prediction for each column at
-a future timestep (t+i+1).
-*/
-func (tp *TemporalPooler) Predict(nSteps int) *matrix.DenseMatrix {
-	// Save the TP dynamic state, we will use to revert back in the end
-	pristineTPDynamicState := tp.DynamicState.Copy()
-
-	if nSteps <= 0 {
-		panic("nSteps must be greater than zero")
-	}
-
-	// multiStepColumnPredictions holds all the future prediction.
-	elements := make([]float64, nSteps*tp.params.NumberOfCols)
-	multiStepColumnPredictions := matrix.MakeDenseMatrix(elements, nSteps, tp.params.NumberOfCols)
-
-	// This is a (nSteps-1)+half loop. Phase 2 in both learn and infer methods
-	// already predicts for timestep (t+1). We use that prediction for free and
-	// save the half-a-loop of work.
-
-	step := 0
-	for {
-		multiStepColumnPredictions.FillRow(step, tp.topDownCompute())
-		if step == nSteps-1 {
-			break
-		}
-		step += 1
-
-		//Copy t-1 into t
-		tp.DynamicState.InfActiveState = tp.DynamicState.InfActiveStateLast
-		tp.DynamicState.InfPredictedState = tp.DynamicState.InfPredictedStateLast
-		tp.DynamicState.CellConfidence = tp.DynamicState.CellConfidenceLast
-
-		// Predicted state at "t-1" becomes the active state at "t"
-		tp.DynamicState.InfActiveState = tp.DynamicState.InfPredictedState
-
-		// Predicted state and confidence are set in phase2.
-		tp.DynamicState.InfPredictedState.Clear()
-		tp.DynamicState.CellConfidence.Fill(0.0)
-		tp.inferPhase2()
-	}
-
-	// Revert the dynamic state to the saved state
-	tp.DynamicState = pristineTPDynamicState
-
-	return multiStepColumnPredictions
-
-}
-
 /*
  This routine computes the activity level of a segment given activeState.
 It can tally up only connected synapses (permanence >= connectedPerm), or
 all the synapses of the segment, at either t or t-1.
 */
-func (tp *TemporalPooler) getSegmentActivityLevel(seg Segment, activeState *SparseBinaryMatrix,
+func (tp *TemporalPooler) getSegmentActivityLevel(seg Segment, activeState BinaryMatrix,
 	connectedSynapsesOnly bool) int {
 	activity := 0
 	//fmt.Println("syn count", len(seg.syns))
@@ -422,7 +451,7 @@ func (tp *TemporalPooler) getSegmentActivityLevel(seg Segment, activeState *Spar
 	 A segment is active if it has >= activationThreshold connected
 	synapses that are active due to activeState.
 */
-func (tp *TemporalPooler) isSegmentActive(seg Segment, activeState *SparseBinaryMatrix) bool {
+func (tp *TemporalPooler) isSegmentActive(seg Segment, activeState BinaryMatrix) bool {
 
 	if len(seg.syns) < tp.params.ActivationThreshold {
 		return false
@@ -467,10 +496,29 @@ This modifies:
 -  CellConfidence
 */
 func (tp *TemporalPooler) inferPhase2() bool {
+	tp.computePredictedState(tp.DynamicState.InfActiveState, tp.DynamicState.InfPredictedState,
+		tp.DynamicState.CellConfidence, tp.DynamicState.ColConfidence)
+
+	// Are we predicting the required minimum number of columns?
+	numPredictedCols := float64(tp.DynamicState.InfPredictedState.TotalNonZeroCount())
+	return numPredictedCols >= (0.5 * tp.avgInputDensity)
+
+}
+
+/*
+ Computes the cells predicted from activeState and the column confidences,
+the normalized sum of the duty cycles of the segments with enough active
+synapses. Cell confidences are only updated when cellConfidence is not nil.
+predictedState and the confidences are overwritten.
+*/
+func (tp *TemporalPooler) computePredictedState(activeState, predictedState BinaryMatrix,
+	cellConfidence *matrix.DenseMatrix, colConfidence []float64) {
 	// Init to zeros to start
-	tp.DynamicState.InfPredictedState.Clear()
-	tp.DynamicState.CellConfidence.Fill(0)
-	utils.FillSliceFloat64(tp.DynamicState.ColConfidence, 0)
+	predictedState.Clear()
+	if cellConfidence != nil {
+		cellConfidence.Fill(0)
+	}
+	utils.FillSliceFloat64(colConfidence, 0)
 
 	// Phase 2 - Compute new predicted state and update cell and column
 	// confidences
@@ -479,25 +527,27 @@ func (tp *TemporalPooler) inferPhase2() bool {
 			// For each segment in the cell
 			for _, seg := range tp.cells[c][i] {
 				// Check if it has the min number of active synapses
-				numActiveSyns := tp.getSegmentActivityLevel(seg, tp.DynamicState.InfActiveState, false)
+				numActiveSyns := tp.getSegmentActivityLevel(seg, activeState, false)
 				//fmt.Println("active syns", numActiveSyns)
 				if numActiveSyns < tp.params.ActivationThreshold {
 					continue
 				}
 
 				//Incorporate the confidence into the owner cell and column
-				if tp.params.Verbosity >= 6 {
-					fmt.Printf("incorporating DC from cell[%v,%v]: \n", c, i)
+				if tp.debugEnabled(6) {
+					tp.debug("Incorporating DC from cell", utils.Field("column", c), utils.Field("cell", i))
 				}
 
 				dc := seg.dutyCycle(false, false)
-				tp.DynamicState.CellConfidence.Set(c, i, tp.DynamicState.CellConfidence.Get(c, i)+dc)
-				tp.DynamicState.ColConfidence[c] += dc
+				if cellConfidence != nil {
+					cellConfidence.Set(c, i, cellConfidence.Get(c, i)+dc)
+				}
+				colConfidence[c] += dc
 
 				// If we reach threshold on the connected synapses, predict it
 				// If not active, skip over it
-				if tp.isSegmentActive(seg, tp.DynamicState.InfActiveState) {
-					tp.DynamicState.InfPredictedState.Set(c, i, true)
+				if tp.isSegmentActive(seg, activeState) {
+					predictedState.Set(c, i, true)
 				}
 			}
 		}
@@ -505,27 +555,26 @@ func (tp *TemporalPooler) inferPhase2() bool {
 	}
 
 	// Normalize column and cell confidences
-	sumConfidences := utils.SumSliceFloat64(tp.DynamicState.ColConfidence)
+	sumConfidences := utils.SumSliceFloat64(colConfidence)
 
 	if sumConfidences > 0 {
-		floats.DivConst(sumConfidences, tp.DynamicState.ColConfidence)
-		tp.DynamicState.CellConfidence.DivScaler(sumConfidences)
+		floats.DivConst(sumConfidences, colConfidence)
+		if cellConfidence != nil {
+			cellConfidence.DivScaler(sumConfidences)
+		}
 	}
 
-	// Are we predicting the required minimum number of columns?
-	numPredictedCols := float64(tp.DynamicState.InfPredictedState.TotalNonZeroCount())
-	return numPredictedCols >= (0.5 * tp.avgInputDensity)
-
 }
 
 /*
 Computes output for both learning and inference. In both cases, the
-output is the boolean OR of activeState and predictedState at t.
+output is selected by the OutputType param, by default the boolean OR of
+activeState and predictedState at t.
 Stores currentOutput for checkPrediction.
 */
 func (tp *TemporalPooler) computeOutput() []bool {
 
-	switch tp.params.outputType {
+	switch tp.params.OutputType {
 	case ActiveState1CellPerCol:
 		// Fire only the most confident cell in columns that have 2 or more
 		// active cells
@@ -550,6 +599,14 @@ func (tp *TemporalPooler) computeOutput() []bool {
 	case Normal:
 		tp.CurrentOutput = tp.DynamicState.InfPredictedState.Or(tp.DynamicState.InfActiveState)
 		break
+	case PredictedActiveCells:
+		tp.CurrentOutput = tp.DynamicState.InfActiveState.Copy()
+		if tp.prevPredictedState != nil {
+			tp.CurrentOutput.AndInPlace(tp.prevPredictedState)
+		} else {
+			tp.CurrentOutput.Clear()
+		}
+		break
 	default:
 		panic("Unknown output type")
 	}
@@ -557,6 +614,24 @@ func (tp *TemporalPooler) computeOutput() []bool {
 	return tp.CurrentOutput.Flatten()
 }
 
+/*
+ Returns the cells of the last output as a sorted list of indices into the
+flattened output (column * CellsPerColumn + cell), which is compact for
+sparse outputs.
+*/
+func (tp *TemporalPooler) OutputIndices() []int {
+	if tp.CurrentOutput == nil {
+		return nil
+	}
+
+	result := make([]int, 0, tp.CurrentOutput.TotalNonZeroCount())
+	for _, val := range tp.CurrentOutput.Entries() {
+		result = append(result, val.Row*tp.CurrentOutput.Width+val.Col)
+	}
+	sort.Ints(result)
+	return result
+}
+
 /*
 Update our moving average of learned sequence length.
 */
@@ -781,9 +856,10 @@ func (tp *TemporalPooler) inferBacktrack(activeColumns []int) {
 			break
 		}
 
-		if tp.params.Verbosity >= 3 {
-			fmt.Printf("Trying to lock-on using startCell state from %v steps ago: %v \n",
-				(numPrevPatterns - 1 - startOffset), tp.prevInfPatterns[startOffset])
+		if tp.debugEnabled(3) {
+			tp.debug("Trying to lock-on using startCell state",
+				utils.Field("stepsAgo", numPrevPatterns-1-startOffset),
+				utils.Field("columns", tp.prevInfPatterns[startOffset]))
 		}
 
 		// Play through starting from starting point 'startOffset'
@@ -809,8 +885,8 @@ func (tp *TemporalPooler) inferBacktrack(activeColumns []int) {
 				break
 			}
 
-			if tp.params.Verbosity >= 3 {
-				fmt.Println("Backtrack: computing predictions from", tp.prevInfPatterns[offset])
+			if tp.debugEnabled(3) {
+				tp.debug("Backtrack: computing predictions", utils.Field("columns", tp.prevInfPatterns[offset]))
 			}
 
 			// Compute predictedState at t given activeState at t
@@ -834,9 +910,11 @@ func (tp *TemporalPooler) inferBacktrack(activeColumns []int) {
 		candConfidence = totalConfidence
 		candStartOffset = startOffset
 
-		if tp.params.Verbosity >= 3 &&
+		if tp.debugEnabled(3) &&
 			startOffset != currentTimeStepsOffset {
-			fmt.Printf("Prediction confidence of current input after starting %v steps ago: %v \n", (numPrevPatterns - 1 - startOffset), totalConfidence)
+			tp.debug("Prediction confidence of current input",
+				utils.Field("stepsAgo", numPrevPatterns-1-startOffset),
+				utils.Field("confidence", totalConfidence))
 		}
 
 		if candStartOffset == currentTimeStepsOffset { // no more to try
@@ -855,20 +933,19 @@ func (tp *TemporalPooler) inferBacktrack(activeColumns []int) {
 	// If we failed to lock on at any starting point, fall back to the original
 	// active state that we had on entry
 	if candStartOffset == -1 {
-		if tp.params.Verbosity >= 3 {
-			fmt.Println("Failed to lock on. Falling back to bursting all unpredicted.")
+		if tp.debugEnabled(3) {
+			tp.debug("Failed to lock on. Falling back to bursting all unpredicted.")
 		}
 		tp.DynamicState.InfActiveState = tp.DynamicState.InfActiveStateBackup
 		tp.inferPhase2()
 	} else {
 
-		if tp.params.Verbosity >= 3 {
-			fmt.Printf("Locked on to current input by using start cells from %v steps ago: %v \n",
-				(numPrevPatterns - 1 - candStartOffset), tp.prevInfPatterns[candStartOffset])
+		if tp.debugEnabled(3) {
+			tp.debug("Locked on to current input by using start cells",
+				utils.Field("stepsAgo", numPrevPatterns-1-candStartOffset),
+				utils.Field("columns", tp.prevInfPatterns[candStartOffset]))
 		}
 
-		fmt.Printf("candstart=%v currentTimeStepoff=%v \n", candStartOffset, currentTimeStepsOffset)
-
 		// Install the candidate state, if it wasn't the last one we evaluated.
 		if candStartOffset != currentTimeStepsOffset {
 			tp.DynamicState.InfActiveState = tp.DynamicState.InfActiveStateCandidate.Copy()
@@ -883,8 +960,10 @@ func (tp *TemporalPooler) inferBacktrack(activeColumns []int) {
 	// queue.
 	for i := 0; i < numPrevPatterns; i++ {
 		if utils.ContainsInt(i, badPatterns) || (candStartOffset != -1 && i <= candStartOffset) {
-			if tp.params.Verbosity >= 3 {
-				fmt.Printf("Removing useless pattern: 1 of: %v from history: %v \n", len(tp.prevInfPatterns), tp.prevInfPatterns[0])
+			if tp.debugEnabled(3) {
+				tp.debug("Removing useless pattern from inference history",
+					utils.Field("historyLength", len(tp.prevInfPatterns)),
+					utils.Field("columns", tp.prevInfPatterns[0]))
 			}
 			//pop prev pattern
 			tp.prevInfPatterns = tp.prevInfPatterns[:len(tp.prevInfPatterns)-1]
@@ -906,6 +985,7 @@ func (tp *TemporalPooler) updateInferenceState(activeColumns []int) {
 	// Copy t to t-1
 	tp.DynamicState.InfActiveStateLast = tp.DynamicState.InfActiveState.Copy()
 	tp.DynamicState.InfPredictedStateLast = tp.DynamicState.InfPredictedState.Copy()
+	tp.prevPredictedState = tp.DynamicState.InfPredictedStateLast
 	tp.DynamicState.CellConfidenceLast = tp.DynamicState.CellConfidence.Copy()
 	tp.DynamicState.ColConfidenceLast = make([]float64, len(tp.DynamicState.ColConfidence))
 	copy(tp.DynamicState.ColConfidenceLast, tp.DynamicState.ColConfidence)
@@ -930,8 +1010,8 @@ func (tp *TemporalPooler) updateInferenceState(activeColumns []int) {
 	// replay the recent inputs from start cells and see if we can lock onto
 	// this current set of inputs that way.
 	if !inSequence {
-		if tp.params.Verbosity >= 3 {
-			fmt.Println("Too much unpredicted input, re-tracing back to try and lock on at an earlier timestep.")
+		if tp.debugEnabled(3) {
+			tp.debug("Too much unpredicted input, re-tracing back to try and lock on at an earlier timestep.")
 		}
 
 		// inferBacktrack() will call inferPhase2() for us.
@@ -943,8 +1023,8 @@ func (tp *TemporalPooler) updateInferenceState(activeColumns []int) {
 	inSequence = tp.inferPhase2()
 
 	if !inSequence {
-		if tp.params.Verbosity >= 3 {
-			fmt.Println("Not enough predictions going forward, re-tracing back to try and lock on at an earlier timestep.")
+		if tp.debugEnabled(3) {
+			tp.debug("Not enough predictions going forward, re-tracing back to try and lock on at an earlier timestep.")
 		}
 
 		// inferBacktrack() will call inferPhase2() for us.
@@ -1007,67 +1087,38 @@ func (tp *TemporalPooler) trimSegmentsInCell(colIdx, cellIdx int, segList []Segm
 
 	// Loop through all segments
 	nSegsRemoved, nSynsRemoved := 0, 0
-	var segsToDel []Segment // collect and remove segments outside the loop
 
 	for _, segment := range segList {
-		// List if synapses to delete
-		var synsToDel []Synapse
+		// segList may hold copies, trim the cell's own segment
+		idx := tp.segmentIndex(colIdx, cellIdx, segment.segId)
+		if idx == -1 {
+			continue
+		}
+		seg := &tp.cells[colIdx][cellIdx][idx]
 
-		for _, syn := range segment.syns {
+		// Number of synapses to delete
+		numToDel := 0
+		for _, syn := range seg.syns {
 			if syn.Permanence < minPermanence {
-				synsToDel = append(synsToDel, syn)
+				numToDel++
 			}
 		}
 
-		nSynsRemoved := 0
-		if len(synsToDel) == len(segment.syns) {
-			segsToDel = append(segsToDel, segment) // will remove the whole segment
-		} else {
-			if len(synsToDel) > 0 {
-				var temp []Synapse
-				for _, osyn := range segment.syns {
-					found := false
-					for _, syn := range synsToDel {
-						if syn == osyn {
-							found = true
-							break
-						}
-						nSynsRemoved++
-					}
-					if !found {
-						temp = append(temp, osyn)
-					}
-				}
-				segment.syns = temp
-				nSynsRemoved += len(synsToDel)
-
-				//for syn in synsToDel: // remove some synapses on segment
-				//	segment.syns.remove(syn)
-
-			}
-
-			if len(segment.syns) < minNumSyns {
-				segsToDel = append(segsToDel, segment)
-			}
+		// Remove segments that don't have enough synapses, this also takes
+		// them out of the segment update list
+		if numToDel == len(seg.syns) || len(seg.syns)-numToDel < minNumSyns {
+			nSegsRemoved++
+			nSynsRemoved += len(seg.syns)
+			tp.removeSegment(colIdx, cellIdx, idx)
+			continue
 		}
-	}
-
-	// Remove segments that don't have enough synapses and also take them
-	// out of the segment update list, if they are in there
-	nSegsRemoved += len(segsToDel)
 
-	// remove some segments of this cell
-	for _, seg := range segsToDel {
-		tp.cleanUpdatesList(colIdx, cellIdx, seg)
-		for idx, val := range tp.cells[colIdx][cellIdx] {
-			if val.Equals(&seg) {
-				copy(tp.cells[colIdx][cellIdx][idx:], tp.cells[colIdx][cellIdx][idx+1:])
-				tp.cells[colIdx][cellIdx][len(tp.cells[colIdx][cellIdx])-1] = Segment{}
-				tp.cells[colIdx][cellIdx] = tp.cells[colIdx][cellIdx][:len(tp.cells[colIdx][cellIdx])-1]
-				break
-			}
+		// Otherwise remove some synapses on segment, the lowest permanences
+		// go first
+		if numToDel > 0 {
+			seg.freeNSynapses(numToDel, nil)
+			nSynsRemoved += numToDel
 		}
-		nSynsRemoved += len(seg.syns)
 	}
 
 	return nSegsRemoved, nSynsRemoved
@@ -1111,9 +1162,9 @@ func (tp *TemporalPooler) processSegmentUpdates(activeColumns []int) {
 		if action != Remove {
 			for _, updateState := range updateList {
 
-				if tp.params.Verbosity >= 4 {
-					fmt.Println("nLrnIterations=", tp.lrnIterationIdx)
-					fmt.Println(updateState)
+				if tp.debugEnabled(4) {
+					tp.debug("Processing segment update", utils.Field("lrnIteration", tp.lrnIterationIdx),
+						utils.Field("update", updateState))
 				}
 
 				// If this segment has expired. Ignore this update (and hence remove it
@@ -1123,16 +1174,16 @@ func (tp *TemporalPooler) processSegmentUpdates(activeColumns []int) {
 				}
 
 				if action == Update {
-					if tp.params.Verbosity >= 5 {
-						fmt.Println("Updating segment...")
+					if tp.debugEnabled(5) {
+						tp.debug("Updating segment")
 					}
 					trimSegment := updateState.Update.adaptSegments(tp)
 					if trimSegment {
 						trimSegments = append(trimSegments, updateState)
 					}
 				} else {
-					if tp.params.Verbosity >= 5 {
-						fmt.Println("keeping segment...")
+					if tp.debugEnabled(5) {
+						tp.debug("Keeping segment")
 					}
 					// Keep segments that haven't expired yet (the cell is still being
 					// predicted)
@@ -1261,7 +1312,7 @@ func (tp *TemporalPooler) chooseCellsToLearnFrom(s *Segment, n int,
 
 	//if only one is required pick a random candidate
 	if n == 1 {
-		idx := rand.Intn(len(candidates))
+		idx := tp.rand.Intn(len(candidates))
 		return []SparseEntry{candidates[idx]} // col and cell idx in col
 	}
 
@@ -1270,7 +1321,7 @@ func (tp *TemporalPooler) chooseCellsToLearnFrom(s *Segment, n int,
 
 	//Shuffle candidates
 	for i := range candidates {
-		j := rand.Intn(i + 1)
+		j := tp.rand.Intn(i + 1)
 		candidates[i], candidates[j] = candidates[j], candidates[i]
 	}
 
@@ -1294,7 +1345,7 @@ func (tp *TemporalPooler) getCellForNewSegment(colIdx int) int {
 		i := 0
 		if tp.params.CellsPerColumn > 1 {
 			// Don't ever choose the start cell (cell # 0) in each column
-			i = rand.Intn(tp.params.CellsPerColumn-1) + 1
+			i = tp.rand.Intn(tp.params.CellsPerColumn-1) + 1
 		}
 		return i
 	}
@@ -1326,45 +1377,38 @@ func (tp *TemporalPooler) getCellForNewSegment(colIdx int) int {
 	// If we found one, return with it. Note we need to use _random to maintain
 	// correspondence with CPP code.
 	if len(candidateCellIdxs) > 0 {
-		cellIdx := rand.Intn(len(candidateCellIdxs))
-		if tp.params.Verbosity >= 5 {
-			fmt.Printf("Cell [%v,%v] chosen for new segment, # of segs is %v \n",
-				colIdx, candidateCellIdxs[cellIdx], len(tp.cells[colIdx][cellIdx]))
+		cellIdx := tp.rand.Intn(len(candidateCellIdxs))
+		if tp.debugEnabled(5) {
+			tp.debug("Cell chosen for new segment", utils.Field("column", colIdx),
+				utils.Field("cell", candidateCellIdxs[cellIdx]),
+				utils.Field("segments", len(tp.cells[colIdx][candidateCellIdxs[cellIdx]])))
 		}
 		return candidateCellIdxs[cellIdx]
 	}
 
-	// All cells in the column are full, find a segment to free up
-	var candidateSegment Segment
-	candidateSegmentDC := 1.0
+	// All cells in the column are full, free up the least recently used
+	// segment
 	candidateCellIdx := -1
 	candidateSegIdx := -1
-	// For each cell in this column
 	for i := minIdx; i <= maxIdx; i++ {
-		for idx, s := range tp.cells[colIdx][i] {
-			dc := s.dutyCycle(false, false)
-			if dc < candidateSegmentDC {
-				candidateCellIdx = i
-				candidateSegmentDC = dc
-				candidateSegment = s
-				candidateSegIdx = idx
-			}
+		idx := tp.leastRecentlyUsedSegment(colIdx, i)
+		if idx == -1 {
+			continue
+		}
+		if candidateCellIdx == -1 || tp.cells[colIdx][i][idx].lastActiveIteration <
+			tp.cells[colIdx][candidateCellIdx][candidateSegIdx].lastActiveIteration {
+			candidateCellIdx = i
+			candidateSegIdx = idx
 		}
 	}
 
-	// Free up the least used segment
-	if tp.params.Verbosity >= 5 {
-		fmt.Printf("Deleting segment #%v for cell[%v] to make room for new %v segment \n",
-			candidateSegment.segId, colIdx, candidateCellIdx)
-		//fmt.Print(candidateSegment.ToString())
+	if tp.debugEnabled(5) {
+		tp.debug("Deleting segment to make room for new segment",
+			utils.Field("segment", tp.cells[colIdx][candidateCellIdx][candidateSegIdx].segId),
+			utils.Field("column", colIdx), utils.Field("cell", candidateCellIdx))
 	}
 
-	tp.cleanUpdatesList(colIdx, candidateCellIdx, candidateSegment)
-
-	//delete segment from cells
-	copy(tp.cells[colIdx][candidateCellIdx][candidateSegIdx:], tp.cells[colIdx][candidateCellIdx][candidateSegIdx+1:])
-	tp.cells[colIdx][candidateCellIdx][len(tp.cells[colIdx][candidateCellIdx])-1] = Segment{}
-	tp.cells[colIdx][candidateCellIdx] = tp.cells[colIdx][candidateCellIdx][:len(tp.cells[colIdx][candidateCellIdx])-1]
+	tp.removeSegment(colIdx, candidateCellIdx, candidateSegIdx)
 
 	return candidateCellIdx
 }
@@ -1450,8 +1494,8 @@ func (tp *TemporalPooler) learnPhase1(activeColumns []int, readOnly bool) bool {
 		i, s, _ := tp.getBestMatchingCell(c, tp.DynamicState.LrnActiveStateLast, tp.params.MinThreshold)
 
 		if s != nil && s.isSequenceSeg {
-			if tp.params.Verbosity >= 4 {
-				fmt.Printf("Learn branch 0, found segment match. Learning on col= %v \n", c)
+			if tp.debugEnabled(4) {
+				tp.debug("Learn branch 0, found segment match", utils.Field("column", c))
 			}
 
 			tp.DynamicState.LrnActiveState.Set(c, i, true)
@@ -1469,8 +1513,8 @@ func (tp *TemporalPooler) learnPhase1(activeColumns []int, readOnly bool) bool {
 			// If no close match exists, create a new one
 			// Choose a cell in this column to add a new segment to
 			i = tp.getCellForNewSegment(c)
-			if tp.params.Verbosity >= 4 {
-				fmt.Printf("Learn branch 1, no match. Learning on col=%v , newCellIdxInCol=%v \n", c, i)
+			if tp.debugEnabled(4) {
+				tp.debug("Learn branch 1, no match", utils.Field("column", c), utils.Field("cell", i))
 			}
 
 			tp.DynamicState.LrnActiveState.Set(c, i, true)
@@ -1516,8 +1560,8 @@ func (tp *TemporalPooler) learnPhase2(readOnly bool) {
 	// phase 2, we predict at most one cell per column (the one with the best
 	// matching segment).
 
-	if tp.params.Verbosity >= 5 {
-		fmt.Println("LearningPhase2...")
+	if tp.debugEnabled(5) {
+		tp.debug("LearningPhase2")
 	}
 
 	for c := 0; c < tp.params.NumberOfCols; c++ {
@@ -1596,14 +1640,13 @@ func (tp *TemporalPooler) learnBacktrackFrom(startOffset int, readOnly bool) boo
 	}
 
 	// Status message
-	if tp.params.Verbosity >= 3 {
+	if tp.debugEnabled(3) {
+		msg := "Locking on using startCell state"
 		if readOnly {
-			fmt.Printf("Trying to lock-on using startCell state from %v steps ago: %v \n",
-				(numPrevPatterns - 1 - startOffset), tp.prevLrnPatterns[startOffset])
-		} else {
-			fmt.Printf("Locking on using startCell state from %v steps ago: %v \n",
-				(numPrevPatterns - 1 - startOffset), tp.prevLrnPatterns[startOffset])
+			msg = "Trying to lock-on using startCell state"
 		}
+		tp.debug(msg, utils.Field("stepsAgo", numPrevPatterns-1-startOffset),
+			utils.Field("columns", tp.prevLrnPatterns[startOffset]))
 	}
 
 	// Play through up to the current time step
@@ -1641,8 +1684,8 @@ func (tp *TemporalPooler) learnBacktrackFrom(startOffset int, readOnly bool) boo
 			break
 		}
 
-		if tp.params.Verbosity >= 3 {
-			fmt.Println("backtrack: computing predictions from:", inputColumns)
+		if tp.debugEnabled(3) {
+			tp.debug("Learn backtrack: computing predictions", utils.Field("columns", inputColumns))
 		}
 
 		// Phase 2:
@@ -1701,8 +1744,8 @@ func (tp *TemporalPooler) learnBacktrack() int {
 	// index -1), and is not a valid startingOffset to evaluate.
 	numPrevPatterns := len(tp.prevLrnPatterns) - 1
 	if numPrevPatterns <= 0 {
-		if tp.params.Verbosity >= 3 {
-			fmt.Println("lrnBacktrack: No available history to backtrack from")
+		if tp.debugEnabled(3) {
+			tp.debug("Learn backtrack: No available history to backtrack from")
 		}
 		return -1
 	}
@@ -1743,8 +1786,8 @@ func (tp *TemporalPooler) learnBacktrack() int {
 	// If we failed to lock on at any starting point, return failure. The caller
 	// will start over again on start cells
 	if !inSequence {
-		if tp.params.Verbosity >= 3 {
-			fmt.Println("Failed to lock on. Falling back to start cells on current time step.")
+		if tp.debugEnabled(3) {
+			tp.debug("Failed to lock on. Falling back to start cells on current time step.")
 		}
 
 		// Nothing in our input history was a valid starting point, so get rid
@@ -1756,9 +1799,10 @@ func (tp *TemporalPooler) learnBacktrack() int {
 	// We did find a valid starting point in the past. Now, we need to
 	// re-enforce all segments that became active when following this path.
 
-	if tp.params.Verbosity >= 3 {
-		fmt.Printf("Discovered path to current input by using start cells from %v steps ago: %v \n",
-			numPrevPatterns-startOffset, tp.prevLrnPatterns[startOffset])
+	if tp.debugEnabled(3) {
+		tp.debug("Discovered path to current input by using start cells",
+			utils.Field("stepsAgo", numPrevPatterns-startOffset),
+			utils.Field("columns", tp.prevLrnPatterns[startOffset]))
 	}
 
 	tp.learnBacktrackFrom(startOffset, false)
@@ -1767,8 +1811,10 @@ func (tp *TemporalPooler) learnBacktrack() int {
 	// queue.
 	for i := 0; i < numPrevPatterns; i++ {
 		if utils.ContainsInt(i, badPatterns) || i <= startOffset {
-			if tp.params.Verbosity >= 3 {
-				fmt.Printf("Removing useless pattern 1 of: %v from history: %v \n", len(tp.prevLrnPatterns), tp.prevLrnPatterns[0])
+			if tp.debugEnabled(3) {
+				tp.debug("Removing useless pattern from learning history",
+					utils.Field("historyLength", len(tp.prevLrnPatterns)),
+					utils.Field("columns", tp.prevLrnPatterns[0]))
 			}
 			tp.prevLrnPatterns = append(tp.prevLrnPatterns[:0], tp.prevLrnPatterns[1:]...)
 		} else {
@@ -1793,8 +1839,8 @@ func (tp *TemporalPooler) updateLearningState(activeColumns []int) {
 			tp.prevLrnPatterns = append(tp.prevLrnPatterns[:0], tp.prevLrnPatterns[1:]...)
 		}
 		tp.prevLrnPatterns = append(tp.prevLrnPatterns, activeColumns)
-		if tp.params.Verbosity >= 4 {
-			fmt.Printf("Previous learn patterns %v \n", tp.prevLrnPatterns)
+		if tp.debugEnabled(4) {
+			tp.debug("Previous learn patterns", utils.Field("patterns", tp.prevLrnPatterns))
 		}
 	}
 
@@ -1823,8 +1869,9 @@ func (tp *TemporalPooler) updateLearningState(activeColumns []int) {
 	}
 
 	// Print status of PAM counter, learned sequence length
-	if tp.params.Verbosity >= 3 {
-		fmt.Printf("pamCounter=%v seqLength=%v \n", tp.pamCounter, tp.learnedSeqLength)
+	if tp.debugEnabled(3) {
+		tp.debug("Learning state", utils.Field("pamCounter", tp.pamCounter),
+			utils.Field("seqLength", tp.learnedSeqLength))
 	}
 
 	// Start over on start cells if any of the following occur:
@@ -1844,14 +1891,14 @@ func (tp *TemporalPooler) updateLearningState(activeColumns []int) {
 		(tp.params.MaxSeqLength != 0 &&
 			tp.learnedSeqLength >= tp.params.MaxSeqLength) {
 
-		if tp.params.Verbosity >= 3 {
+		if tp.debugEnabled(3) {
+			reason := "reached maxSeqLength"
 			if tp.resetCalled {
-				fmt.Printf("Starting over: %v (reset was called) \n", activeColumns)
+				reason = "reset was called"
 			} else if tp.pamCounter == 0 {
-				fmt.Printf("Starting over: %v (PAM counter expired) \n", activeColumns)
-			} else {
-				fmt.Printf("Starting over: %v (reached maxSeqLength) \n", activeColumns)
+				reason = "PAM counter expired"
 			}
+			tp.debug("Starting over", utils.Field("reason", reason), utils.Field("columns", activeColumns))
 		}
 
 		// Update average learned sequence length - this is a diagnostic statistic
@@ -1861,8 +1908,8 @@ func (tp *TemporalPooler) updateLearningState(activeColumns []int) {
 		} else {
 			seqLength = tp.learnedSeqLength
 		}
-		if tp.params.Verbosity >= 3 {
-			fmt.Printf("Learned sequence length was: %v \n", tp.learnedSeqLength)
+		if tp.debugEnabled(3) {
+			tp.debug("Learned sequence", utils.Field("seqLength", tp.learnedSeqLength))
 		}
 		tp.updateAvgLearnedSeqLength(float64(seqLength))
 
@@ -1928,10 +1975,10 @@ func (tp *TemporalPooler) Compute(bottomUpInput []bool, enableLearn bool, comput
 		tp.lrnIterationIdx++
 	}
 	tp.iterationIdx++
+	tp.stepChanges = TpStepEvent{}
 
-	if tp.params.Verbosity >= 3 {
-		fmt.Printf("\n==== Iteration: %v \n ", tp.iterationIdx)
-		fmt.Println("Active cols:", activeColumns)
+	if tp.debugEnabled(3) {
+		tp.debug("Iteration", utils.Field("columns", activeColumns))
 	}
 
 	// Update segment duty cycles if we are crossing a "tier"
@@ -1977,42 +2024,35 @@ func (tp *TemporalPooler) Compute(bottomUpInput []bool, enableLearn bool, comput
 		// do global decay only episodically.
 
 		if tp.params.GlobalDecay > 0.0 && (tp.lrnIterationIdx%tp.params.MaxAge) == 0 {
-			for _, col := range tp.cells {
-				for _, cell := range col {
-
-					si := 0 //New end of segment slice
-					for _, segment := range cell {
+			for c := range tp.cells {
+				for i := range tp.cells[c] {
+					// Iterate backwards so removing a segment doesn't shift
+					// the ones still to visit
+					for idx := len(tp.cells[c][i]) - 1; idx >= 0; idx-- {
+						segment := &tp.cells[c][i][idx]
 						age := tp.lrnIterationIdx - segment.lastActiveIteration
 						if age <= tp.params.MaxAge {
-							//keep segment but skip syn trimming
-							cell[si] = segment
-							si++
 							continue
 						}
 
-						w := 0
-						for _, synapse := range segment.syns {
+						numToFree := 0
+						for s := range segment.syns {
 							// decrease permanence
-							synapse.Permanence = synapse.Permanence - float64(tp.params.GlobalDecay)
-							//remove synapse if permanence too low
-							if synapse.Permanence <= 0 {
-								continue
+							segment.syns[s].Permanence -= tp.params.GlobalDecay
+							//flag synapse for removal if permanence too low
+							if segment.syns[s].Permanence <= 0 {
+								segment.syns[s].Permanence = 0
+								numToFree++
 							}
-							//otherwise keep synapse and increment synapse end of slice
-							segment.syns[w] = synapse
-							w++
 						}
-						segment.syns = segment.syns[:w]
 
-						//trim segment if no synapses remaining
-						if len(segment.syns) == 0 {
-							continue
+						//remove segment if no synapses remaining
+						if numToFree == len(segment.syns) {
+							tp.removeSegment(c, i, idx)
+						} else if numToFree > 0 {
+							segment.freeNSynapses(numToFree, nil)
 						}
-						//otherwise keep segment increment end  of slice counter
-						cell[si] = segment
-						si++
 					}
-
 				}
 			}
 
@@ -2022,37 +2062,39 @@ func (tp *TemporalPooler) Compute(bottomUpInput []bool, enableLearn bool, comput
 		if tp.trivialPredictor != nil {
 			tp.trivialPredictor.learn(activeColumns)
 		}
+	}
 
-		// Update the prediction score stats
-		// Learning always includes inference
-		var predictedState *SparseBinaryMatrix
-		if tp.params.CollectStats {
-			if computeInfOutput {
-				predictedState = tp.DynamicState.InfPredictedStateLast.Copy()
-			} else {
-				predictedState = tp.DynamicState.LrnPredictedStateLast.Copy()
-			}
+	// Update the prediction score stats
+	// Learning always includes inference
+	var predictedState *SparseBinaryMatrix
+	if tp.params.CollectStats {
+		if computeInfOutput {
+			predictedState = tp.DynamicState.InfPredictedStateLast.Copy()
+		} else {
+			predictedState = tp.DynamicState.LrnPredictedStateLast.Copy()
+		}
 
-			tp.updateStatsInferEnd(tp.internalStats, activeColumns,
-				predictedState, tp.DynamicState.ColConfidenceLast)
+		tp.updateStatsInferEnd(tp.internalStats, activeColumns,
+			predictedState, tp.DynamicState.ColConfidenceLast)
 
-			// Make trivial predictions and collect stats
+		// Make trivial predictions and collect stats
 
-			if tp.trivialPredictor != nil {
-				for _, method := range tp.trivialPredictor.Methods {
-					if computeInfOutput {
-						tp.trivialPredictor.infer(activeColumns)
-					}
+		if tp.trivialPredictor != nil {
+			// Learning already stepped the trivial predictors
+			if computeInfOutput && !enableLearn {
+				tp.trivialPredictor.infer(activeColumns)
+			}
 
-					temp := NewSparseBinaryMatrixFromDense([][]bool{tp.trivialPredictor.State[method].PredictedStateLast})
+			for _, method := range tp.trivialPredictor.Methods {
+				// Trivial predictions are per column, one cell each
+				temp := NewSparseBinaryMatrixFromDense1D(tp.trivialPredictor.State[method].PredictedStateLast,
+					tp.params.NumberOfCols, 1)
 
-					tp.updateStatsInferEnd(tp.trivialPredictor.InternalStats[method],
-						activeColumns,
-						temp,
-						tp.trivialPredictor.State[method].ConfidenceLast)
-				}
+				tp.updateStatsInferEnd(tp.trivialPredictor.InternalStats[method],
+					activeColumns,
+					temp,
+					tp.trivialPredictor.State[method].ConfidenceLast)
 			}
-
 		}
 
 	}
@@ -2061,7 +2103,13 @@ func (tp *TemporalPooler) Compute(bottomUpInput []bool, enableLearn bool, comput
 	result := tp.computeOutput()
 
 	// Print diagnostic information based on the current verbosity level
-	//tp.printComputeEnd(result, enableLearn)
+	if tp.DiagnosticWriter != nil {
+		tp.PrintComputeEnd(tp.DiagnosticWriter, result, enableLearn)
+	}
+
+	if tp.params.Observer != nil {
+		tp.params.Observer.TemporalPoolerStep(tp.stepEvent(len(activeColumns), enableLearn, computeInfOutput))
+	}
 
 	tp.resetCalled = false
 	return result
@@ -2076,8 +2124,8 @@ func (tp *TemporalPooler) Compute(bottomUpInput []bool, enableLearn bool, comput
 */
 func (tp *TemporalPooler) Reset() {
 
-	if tp.params.Verbosity >= 3 {
-		fmt.Println("==== RESET =====")
+	if tp.debugEnabled(3) {
+		tp.debug("Reset")
 	}
 
 	tp.DynamicState.LrnActiveStateLast.Clear()
@@ -2094,6 +2142,7 @@ func (tp *TemporalPooler) Reset() {
 	if tp.DynamicState.InfPredictedStateLast != nil {
 		tp.DynamicState.InfPredictedStateLast.Clear()
 	}
+	tp.prevPredictedState = nil
 
 	if tp.DynamicState.CellConfidenceLast != nil {
 		tp.DynamicState.CellConfidenceLast.Fill(0)
@@ -2123,21 +2172,19 @@ func (tp *TemporalPooler) Reset() {
 	// When a reset occurs, set prevSequenceSignature to the signature of the
 	// just-completed sequence and start accumulating histogram for the next
 	// sequence.
-
-	//self._internalStats['prevSequenceSignature'] = None
-
-	// if tp.collectSequenceStats {
-	// 	tp.internalStats.ConfHistogram.Sum() > 0 {
-
-	// 	}
-	// }
-
-	//    if self.collectSequenceStats:
-	//      if self._internalStats['confHistogram'].sum() > 0:
-	//        sig = self._internalStats['confHistogram'].copy()
-	//        sig.reshape(self.numberOfCols * self.cellsPerColumn)
-	//        self._internalStats['prevSequenceSignature'] = sig
-	//      self._internalStats['confHistogram'].fill(0)
+	tp.internalStats.PrevSequenceSignature = nil
+	if tp.collectSequenceStats && tp.internalStats.ConfHistogram != nil {
+		signature := make([]float64, 0, tp.numberOfCells)
+		sum := 0.0
+		for _, col := range tp.internalStats.ConfHistogram {
+			signature = append(signature, col...)
+			sum += utils.SumSliceFloat64(col)
+			utils.FillSliceFloat64(col, 0)
+		}
+		if sum > 0 {
+			tp.internalStats.PrevSequenceSignature = signature
+		}
+	}
 
 	tp.resetCalled = true
 
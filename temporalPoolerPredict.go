@@ -0,0 +1,137 @@
+//
+// Multi-step prediction for the temporal pooler
+//
+
+package htm
+
+import (
+	"github.com/nupic-community/htm/utils"
+)
+
+//Selects the predicted columns to report given the column confidences,
+//returned indices must be in ascending order
+type ColumnSelector func(confidences []float64) []int
+
+//Selects every column whose confidence is >= threshold
+func ThresholdSelector(threshold float64) ColumnSelector {
+	return func(confidences []float64) []int {
+		var result []int
+		for idx, val := range confidences {
+			if val > 0 && val >= threshold {
+				result = append(result, idx)
+			}
+		}
+		return result
+	}
+}
+
+//Selects the k columns with the highest non-zero confidence
+func TopKSelector(k int) ColumnSelector {
+	return func(confidences []float64) []int {
+		var result []int
+		for _, idx := range utils.TopKFloat64(confidences, k) {
+			if confidences[idx] > 0 {
+				result = append(result, idx)
+			}
+		}
+		return result
+	}
+}
+
+//Columns predicted for a future time step
+type StepPrediction struct {
+	//Number of steps ahead, 1 is the next time step
+	Step int
+	//Predicted columns in ascending order
+	Columns []int
+	//Confidence of each of the predicted columns
+	Confidences []float64
+}
+
+//Working state reused between predictions so simulating forward does
+//not touch, or copy, the pooler's dynamic state
+type predictionScratch struct {
+	active        *DenseBinaryMatrix
+	predicted     *DenseBinaryMatrix
+	colConfidence []float64
+}
+
+func (tp *TemporalPooler) getPredictionScratch() *predictionScratch {
+	if tp.predictionScratch == nil {
+		tp.predictionScratch = &predictionScratch{
+			active:        NewDenseBinaryMatrix(tp.params.NumberOfCols, tp.params.CellsPerColumn),
+			predicted:     NewDenseBinaryMatrix(tp.params.NumberOfCols, tp.params.CellsPerColumn),
+			colConfidence: make([]float64, tp.params.NumberOfCols),
+		}
+	}
+	return tp.predictionScratch
+}
+
+//Panics unless at least one step is to be predicted
+func validateSteps(nSteps int) {
+	if nSteps <= 0 {
+		panic("nSteps must be greater than zero")
+	}
+}
+
+/*
+ This function gives the future predictions for <nSteps> timesteps starting
+from the current TP state. The first step is the prediction made by the last
+compute, every following step treats the previously predicted cells as active
+and predicts from them, no input is used. The TP state is left untouched.
+
+param nSteps The number of future time steps to be predicted
+param fn called with the step (starting at 1), the predicted cells and the
+column confidences for that step. Both are only valid during the call.
+*/
+func (tp *TemporalPooler) predictSteps(nSteps int, fn func(step int, predicted BinaryMatrix, colConfidence []float64)) {
+	validateSteps(nSteps)
+
+	scratch := tp.getPredictionScratch()
+	scratch.predicted.Clear()
+	scratch.predicted.OrInPlace(tp.DynamicState.InfPredictedState)
+	copy(scratch.colConfidence, tp.DynamicState.ColConfidence)
+
+	for step := 1; ; step++ {
+		fn(step, scratch.predicted, scratch.colConfidence)
+		if step == nSteps {
+			break
+		}
+
+		// Predicted state at "t-1" becomes the active state at "t"
+		scratch.active, scratch.predicted = scratch.predicted, scratch.active
+		tp.computePredictedState(scratch.active, scratch.predicted, nil, scratch.colConfidence)
+	}
+}
+
+/*
+ Returns the column confidences for each of the next nSteps time steps,
+the ith row is the prediction for time step t+i+1.
+*/
+func (tp *TemporalPooler) Predict(nSteps int) [][]float64 {
+	validateSteps(nSteps)
+	result := make([][]float64, nSteps)
+	tp.predictSteps(nSteps, func(step int, predicted BinaryMatrix, colConfidence []float64) {
+		result[step-1] = make([]float64, len(colConfidence))
+		copy(result[step-1], colConfidence)
+	})
+	return result
+}
+
+/*
+ Returns the columns chosen by selector for each of the next nSteps time
+steps along with their confidences.
+*/
+func (tp *TemporalPooler) PredictColumns(nSteps int, selector ColumnSelector) []StepPrediction {
+	validateSteps(nSteps)
+	result := make([]StepPrediction, nSteps)
+	tp.predictSteps(nSteps, func(step int, predicted BinaryMatrix, colConfidence []float64) {
+		columns := selector(colConfidence)
+		confidences := make([]float64, len(columns))
+		for idx, col := range columns {
+			confidences[idx] = colConfidence[col]
+		}
+		result[step-1] = StepPrediction{step, columns, confidences}
+	})
+	return result
+}
@@ -5,25 +5,76 @@
 package htm
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"sort"
 	//"github.com/cznic/mathutil"
 	//"github.com/zacg/go.matrix"
 	//"math"
 	//"math/rand"
-	//"sort"
 	//"github.com/gonum/floats"
 	//"github.com/zacg/ints"
 	"github.com/nupic-community/htm/utils"
 )
 
+//Number of segments whose age falls in [Start,End] learning iterations
+type AgeBucket struct {
+	Start int
+	End   int
+	Count int
+}
+
 type SegmentStats struct {
-	NumSegments        int
-	NumSynapses        int
-	NumActiveSynapses  int
-	DistSegSizes       float64
-	DistNumSegsPerCell float64
-	DistPermValues     float64
-	DistAges           float64
+	//Number of segments, active or not
+	NumSegments int
+	NumSynapses int
+	//Number of segments active given InfActiveState, only counted when
+	//active data is collected. NuPIC adds these to NumSegments instead.
+	NumActiveSegments int
+	NumActiveSynapses int
+	//Number of segments keyed by their number of synapses
+	DistSegSizes map[int]int
+	//Number of cells keyed by their number of segments
+	DistNumSegsPerCell map[int]int
+	//Number of synapses keyed by permanence bucket, bucket i holds
+	//permanences in [i/10,(i+1)/10)
+	DistPermValues map[int]int
+	//Number of segments by iterations since they were last active
+	DistAges []AgeBucket
+}
+
+func (s SegmentStats) ToString() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString(fmt.Sprintf("numSegments %v \n", s.NumSegments))
+	buffer.WriteString(fmt.Sprintf("numSynapses %v \n", s.NumSynapses))
+	buffer.WriteString(fmt.Sprintf("numActiveSegments %v \n", s.NumActiveSegments))
+	buffer.WriteString(fmt.Sprintf("numActiveSynapses %v \n", s.NumActiveSynapses))
+
+	writeDist := func(name string, dist map[int]int) {
+		keys := make([]int, 0, len(dist))
+		for key := range dist {
+			keys = append(keys, key)
+		}
+		sort.Ints(keys)
+		buffer.WriteString(name)
+		for _, key := range keys {
+			buffer.WriteString(fmt.Sprintf(" %v:%v", key, dist[key]))
+		}
+		buffer.WriteString(" \n")
+	}
+	writeDist("distSegSizes", s.DistSegSizes)
+	writeDist("distNumSegsPerCell", s.DistNumSegsPerCell)
+	writeDist("distPermValues", s.DistPermValues)
+
+	buffer.WriteString("distAges")
+	for _, bucket := range s.DistAges {
+		buffer.WriteString(fmt.Sprintf(" %v-%v:%v", bucket.Start, bucket.End, bucket.Count))
+	}
+	buffer.WriteString(" \n")
+
+	return buffer.String()
 }
 
 /*
@@ -32,25 +83,29 @@ permanence values in the current TP. If requested, also returns information
 regarding the number of currently active segments and synapses.
 
 */
-func (tp *TemporalPooler) calcSegmentStats(collectActiveData bool) SegmentStats {
+func (tp *TemporalPooler) CalcSegmentStats(collectActiveData bool) SegmentStats {
 	result := SegmentStats{}
 
-	var distNSegsPerCell map[int]int
-	var distSegSizes map[int]int
-	var distPermValues map[int]int
-
 	numAgeBuckets := 20
 	ageBucketSize := int((tp.lrnIterationIdx + 20) / 20)
 
-	distAges := make(map[int]int, numAgeBuckets)
-	distAgesLabels := make([]string, numAgeBuckets)
-	for i := 0; i < numAgeBuckets; i++ {
-		distAgesLabels[i] = fmt.Sprintf("%v-%v", i*ageBucketSize, (i+1)*ageBucketSize-1)
+	result.DistAges = make([]AgeBucket, numAgeBuckets)
+	for i := range result.DistAges {
+		result.DistAges[i].Start = i * ageBucketSize
+		result.DistAges[i].End = (i+1)*ageBucketSize - 1
 	}
 
-	distNSegsPerCell = make(map[int]int, 1000)
-	distSegSizes = make(map[int]int, 1000)
-	distPermValues = make(map[int]int, 1000)
+	result.DistNumSegsPerCell = make(map[int]int)
+	result.DistSegSizes = make(map[int]int)
+	result.DistPermValues = make(map[int]int)
+
+	// Dense copy of the active state for constant time synapse lookups
+	var activeState *DenseBinaryMatrix
+	if collectActiveData {
+		activeState = NewDenseBinaryMatrix(tp.DynamicState.InfActiveState.Height,
+			tp.DynamicState.InfActiveState.Width)
+		activeState.OrInPlace(tp.DynamicState.InfActiveState)
+	}
 
 	for _, col := range tp.cells {
 		for _, cell := range col {
@@ -58,44 +113,35 @@ func (tp *TemporalPooler) calcSegmentStats(collectActiveData bool) SegmentStats
 			nSegmentsThisCell := len(cell)
 			result.NumSegments += nSegmentsThisCell
 
-			if _, ok := distNSegsPerCell[nSegmentsThisCell]; ok {
-				distNSegsPerCell[nSegmentsThisCell]++
-			} else {
-				distNSegsPerCell[nSegmentsThisCell] = 1
-			}
+			result.DistNumSegsPerCell[nSegmentsThisCell]++
 
 			for _, seg := range cell {
 				nSynapsesThisSeg := len(seg.syns)
 				result.NumSynapses += nSynapsesThisSeg
 
-				if _, ok := distSegSizes[nSynapsesThisSeg]; ok {
-					distSegSizes[nSynapsesThisSeg]++
-				} else {
-					distSegSizes[nSynapsesThisSeg] = 1
-				}
+				result.DistSegSizes[nSynapsesThisSeg]++
 
 				// Accumulate permanence value histogram
 				for _, syn := range seg.syns {
 					p := int(syn.Permanence * 10)
-					if _, ok := distPermValues[p]; ok {
-						distPermValues[p]++
-					} else {
-						distPermValues[p] = 1
-					}
+					result.DistPermValues[p]++
 				}
 
 				// Accumulate segment age histogram
 				age := tp.lrnIterationIdx - seg.lastActiveIteration
 				ageBucket := int(age / ageBucketSize)
-				distAges[ageBucket]++
+				if ageBucket >= numAgeBuckets {
+					ageBucket = numAgeBuckets - 1
+				}
+				result.DistAges[ageBucket].Count++
 
 				// Get active synapse statistics if requested
 				if collectActiveData {
 					if tp.isSegmentActive(seg, tp.DynamicState.InfActiveState) {
-						result.NumSegments++
+						result.NumActiveSegments++
 					}
 					for _, syn := range seg.syns {
-						if tp.DynamicState.InfActiveState.Get(syn.SrcCellIdx, syn.SrcCellCol) {
+						if activeState.Get(syn.SrcCellCol, syn.SrcCellIdx) {
 							result.NumActiveSynapses++
 						}
 					}
@@ -112,25 +158,25 @@ func (tp *TemporalPooler) calcSegmentStats(collectActiveData bool) SegmentStats
  Print the list of [column, cellIdx] indices for each of the active
 cells in state.
 */
-func (tp *TemporalPooler) printActiveIndices(state *SparseBinaryMatrix, andValues bool) {
+func (tp *TemporalPooler) PrintActiveIndices(w io.Writer, state *SparseBinaryMatrix) {
 	if state.TotalNonZeroCount() == 0 {
-		fmt.Println("None")
+		fmt.Fprintln(w, "None")
 		return
 	}
 
-	fmt.Println(state.Entries())
+	fmt.Fprintln(w, state.Entries())
 
 }
 
 /*
 	Prints a cels information
 */
-func (tp *TemporalPooler) printCell(c int, i int, onlyActiveSegments bool) {
+func (tp *TemporalPooler) PrintCell(w io.Writer, c int, i int, onlyActiveSegments bool) {
 
 	cell := tp.cells[c][i]
 
 	if len(cell) > 0 {
-		fmt.Printf("Column: %v Cell: %v - %v segment(s)", c, i, len(cell))
+		fmt.Fprintf(w, "Column: %v Cell: %v - %v segment(s)\n", c, i, len(cell))
 		for idx, seg := range cell {
 			isActive := tp.isSegmentActive(seg, tp.DynamicState.InfActiveState)
 			if !onlyActiveSegments || isActive {
@@ -138,8 +184,8 @@ func (tp *TemporalPooler) printCell(c int, i int, onlyActiveSegments bool) {
 				if isActive {
 					str = "*"
 				}
-				fmt.Printf("%vSeg: %v", str, idx)
-				fmt.Println(seg.ToString())
+				fmt.Fprintf(w, "%vSeg: %v ", str, idx)
+				fmt.Fprint(w, seg.ToString())
 			}
 		}
 	}
@@ -149,22 +195,22 @@ func (tp *TemporalPooler) printCell(c int, i int, onlyActiveSegments bool) {
 /*
  Print all cell information
 */
-func (tp *TemporalPooler) printCells(predictedOnly bool) {
+func (tp *TemporalPooler) PrintCells(w io.Writer, predictedOnly bool) {
 
 	if predictedOnly {
-		fmt.Println("--- PREDICTED CELLS ---")
+		fmt.Fprintln(w, "--- PREDICTED CELLS ---")
 	} else {
-		fmt.Println("--- ALL CELLS ---")
+		fmt.Fprintln(w, "--- ALL CELLS ---")
 	}
 
-	fmt.Println("Activation threshold:", tp.params.ActivationThreshold)
-	fmt.Println("min threshold:", tp.params.MinThreshold)
-	fmt.Println("connected perm:", tp.params.ConnectedPerm)
+	fmt.Fprintln(w, "Activation threshold:", tp.params.ActivationThreshold)
+	fmt.Fprintln(w, "min threshold:", tp.params.MinThreshold)
+	fmt.Fprintln(w, "connected perm:", tp.params.ConnectedPerm)
 
 	for c, col := range tp.cells {
 		for i := range col {
 			if !predictedOnly || tp.DynamicState.InfPredictedState.Get(c, i) {
-				tp.printCell(c, i, predictedOnly)
+				tp.PrintCell(w, c, i, predictedOnly)
 			}
 		}
 	}
@@ -173,22 +219,23 @@ func (tp *TemporalPooler) printCells(predictedOnly bool) {
 
 /*
  Called at the end of inference to print out various diagnostic
-information based on the current verbosity level.
+information based on the current verbosity level. Returns the segment
+stats when they were computed (verbosity >= 3), nil otherwise.
 */
-func (tp *TemporalPooler) printComputeEnd(output []bool, learn bool) {
+func (tp *TemporalPooler) PrintComputeEnd(w io.Writer, output []bool, learn bool) *SegmentStats {
 
 	if tp.params.Verbosity < 3 {
 		if tp.params.Verbosity >= 1 {
-			fmt.Println("TP: learn:", learn)
-			fmt.Printf("TP: active outputs(%v):\n", utils.CountTrue(output))
-			fmt.Print(NewSparseBinaryMatrixFromDense1D(output,
+			fmt.Fprintln(w, "TP: learn:", learn)
+			fmt.Fprintf(w, "TP: active outputs(%v):\n", utils.CountTrue(output))
+			fmt.Fprint(w, NewSparseBinaryMatrixFromDense1D(output,
 				tp.params.NumberOfCols, tp.params.CellsPerColumn).ToString())
 		}
-		return
+		return nil
 	}
 
-	fmt.Println("----- computeEnd summary: ")
-	fmt.Println("learn:", learn)
+	fmt.Fprintln(w, "----- computeEnd summary: ")
+	fmt.Fprintln(w, "learn:", learn)
 	bursting := 0
 	counts := make([]int, tp.DynamicState.InfActiveState.Height)
 	for _, val := range tp.DynamicState.InfActiveState.Entries() {
@@ -197,74 +244,74 @@ func (tp *TemporalPooler) printComputeEnd(output []bool, learn bool) {
 			bursting++
 		}
 	}
-	fmt.Println("numBurstingCols:", bursting)
-	fmt.Println("curPredScore2:", tp.internalStats.CurPredictionScore2)
-	fmt.Println("curFalsePosScore", tp.internalStats.CurFalsePositiveScore)
-	fmt.Println("1-curFalseNegScore", 1-tp.internalStats.CurFalseNegativeScore)
-	fmt.Println("avgLearnedSeqLength", tp.avgLearnedSeqLength)
-
-	stats := tp.calcSegmentStats(true)
-	fmt.Println("numSegments", stats.NumSegments)
+	fmt.Fprintln(w, "numBurstingCols:", bursting)
+	fmt.Fprintln(w, "curPredScore2:", tp.internalStats.CurPredictionScore2)
+	fmt.Fprintln(w, "curFalsePosScore", tp.internalStats.CurFalsePositiveScore)
+	fmt.Fprintln(w, "1-curFalseNegScore", 1-tp.internalStats.CurFalseNegativeScore)
+	fmt.Fprintln(w, "avgLearnedSeqLength", tp.avgLearnedSeqLength)
 
-	fmt.Printf("----- InfActiveState (%v on) ------\n", tp.DynamicState.InfActiveState.TotalNonZeroCount())
-	tp.printActiveIndices(tp.DynamicState.InfActiveState, false)
+	stats := tp.CalcSegmentStats(true)
+	fmt.Fprint(w, stats.ToString())
 
+	fmt.Fprintf(w, "----- InfActiveState (%v on) ------\n", tp.DynamicState.InfActiveState.TotalNonZeroCount())
+	tp.PrintActiveIndices(w, tp.DynamicState.InfActiveState)
 	if tp.params.Verbosity >= 6 {
-		//tp.printState(tp.InfActiveState['t'])
-		//fmt.Println(tp.DynamicState.InfActiveState.ToString())
+		fmt.Fprint(w, tp.DynamicState.InfActiveState.ToString())
 	}
 
-	fmt.Printf("----- InfPredictedState (%v on)-----\n", tp.DynamicState.InfPredictedState.TotalNonZeroCount())
-	tp.printActiveIndices(tp.DynamicState.InfPredictedState, false)
+	fmt.Fprintf(w, "----- InfPredictedState (%v on)-----\n", tp.DynamicState.InfPredictedState.TotalNonZeroCount())
+	tp.PrintActiveIndices(w, tp.DynamicState.InfPredictedState)
 	if tp.params.Verbosity >= 6 {
-		//fmt.Println(tp.DynamicState.InfPredictedState.ToString())
+		fmt.Fprint(w, tp.DynamicState.InfPredictedState.ToString())
 	}
 
-	fmt.Printf("----- LrnActiveState (%v on) ------\n", tp.DynamicState.LrnActiveState.TotalNonZeroCount())
-	tp.printActiveIndices(tp.DynamicState.LrnActiveState, false)
+	fmt.Fprintf(w, "----- LrnActiveState (%v on) ------\n", tp.DynamicState.LrnActiveState.TotalNonZeroCount())
+	tp.PrintActiveIndices(w, tp.DynamicState.LrnActiveState)
 	if tp.params.Verbosity >= 6 {
-		//fmt.Println(tp.DynamicState.LrnActiveState.ToString())
+		fmt.Fprint(w, tp.DynamicState.LrnActiveState.ToString())
 	}
 
-	fmt.Printf("----- LrnPredictedState (%v on)-----\n", tp.DynamicState.LrnPredictedState.TotalNonZeroCount())
-	tp.printActiveIndices(tp.DynamicState.LrnPredictedState, false)
+	fmt.Fprintf(w, "----- LrnPredictedState (%v on)-----\n", tp.DynamicState.LrnPredictedState.TotalNonZeroCount())
+	tp.PrintActiveIndices(w, tp.DynamicState.LrnPredictedState)
 	if tp.params.Verbosity >= 6 {
-		//fmt.Println(tp.DynamicState.LrnPredictedState.ToString())
+		fmt.Fprint(w, tp.DynamicState.LrnPredictedState.ToString())
 	}
 
-	fmt.Println("----- CellConfidence -----")
-	//tp.printActiveIndices(tp.DynamicState.CellConfidence, true)
-
 	if tp.params.Verbosity >= 6 {
-		//TODO: this
-		//tp.printConfidence(tp.DynamicState.CellConfidence)
+		fmt.Fprintln(w, "----- CellConfidence -----")
 		for r := 0; r < tp.DynamicState.CellConfidence.Rows(); r++ {
-			for c := 0; c < tp.DynamicState.CellConfidenceLast.Cols(); c++ {
+			for c := 0; c < tp.DynamicState.CellConfidence.Cols(); c++ {
 				if tp.DynamicState.CellConfidence.Get(r, c) != 0 {
-					fmt.Printf("[%v,%v,%v]", r, c, tp.DynamicState.CellConfidence.Get(r, c))
+					fmt.Fprintf(w, "[%v,%v,%v]", r, c, tp.DynamicState.CellConfidence.Get(r, c))
 				}
 			}
 		}
-
+		fmt.Fprintln(w)
 	}
 
-	fmt.Println("----- ColConfidence -----")
-	//tp.printActiveIndices(tp.DynamicState.ColConfidence, true)
-	fmt.Println("----- CellConfidence[t-1] for currently active cells -----")
-	//cc := matrix.ZerosSparse(tp.DynamicState.CellConfidence.Rows(), tp.DynamicState.CellConfidence.Cols())
-	for _, val := range tp.DynamicState.InfActiveState.Entries() {
-		//cc.Set(val.Row, val.Col, tp.DynamicState.CellConfidence.Get(val.Row, val.Col))
-		fmt.Printf("[%v,%v,%v]", val.Row, val.Col, tp.DynamicState.CellConfidence.Get(val.Row, val.Col))
+	fmt.Fprintln(w, "----- ColConfidence -----")
+	for c, val := range tp.DynamicState.ColConfidence {
+		if val != 0 {
+			fmt.Fprintf(w, "[%v,%v]", c, val)
+		}
+	}
+	fmt.Fprintln(w)
 
+	fmt.Fprintln(w, "----- CellConfidence[t-1] for currently active cells -----")
+	if tp.DynamicState.CellConfidenceLast != nil {
+		for _, val := range tp.DynamicState.InfActiveState.Entries() {
+			fmt.Fprintf(w, "[%v,%v,%v]", val.Row, val.Col, tp.DynamicState.CellConfidenceLast.Get(val.Row, val.Col))
+		}
 	}
-	//fmt.Println(cc.String())
+	fmt.Fprintln(w)
 
 	if tp.params.Verbosity == 4 {
-		fmt.Println("Cells, predicted segments only:")
-		tp.printCells(true)
+		fmt.Fprintln(w, "Cells, predicted segments only:")
+		tp.PrintCells(w, true)
 	} else if tp.params.Verbosity >= 5 {
-		fmt.Println("Cells, all segments:")
-		tp.printCells(true)
+		fmt.Fprintln(w, "Cells, all segments:")
+		tp.PrintCells(w, false)
 	}
 
+	return &stats
 }
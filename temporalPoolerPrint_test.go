@@ -0,0 +1,132 @@
+package htm
+
+import (
+	"bytes"
+	"github.com/nupic-community/htm/utils"
+	"github.com/zacg/testify/assert"
+	"strings"
+	"testing"
+)
+
+func trainedDiagnosticsTp() (*TemporalPooler, [][]bool) {
+	tps := NewTemporalPoolerParams()
+	tps.Verbosity = 0
+	tps.NumberOfCols = 50
+	tps.CellsPerColumn = 2
+	tps.ActivationThreshold = 8
+	tps.MinThreshold = 10
+	tps.InitialPerm = 0.5
+	tps.ConnectedPerm = 0.5
+	tps.NewSynapseCount = 10
+	tps.PermanenceDec = 0.0
+	tps.PermanenceInc = 0.1
+	tps.GlobalDecay = 0
+	tps.BurnIn = 1
+	tps.PamLength = 10
+	tp := NewTemporalPooler(*tps)
+
+	inputs := make([][]bool, 5)
+	for i := range inputs {
+		inputs[i] = boolRange(i*10, i*10+9, 50)
+	}
+	for i := 0; i < 10; i++ {
+		for p := 0; p < 5; p++ {
+			tp.Compute(inputs[p], true, false)
+		}
+		tp.Reset()
+	}
+
+	return tp, inputs
+}
+
+func TestCalcSegmentStats(t *testing.T) {
+	tp, inputs := trainedDiagnosticsTp()
+	tp.Compute(inputs[0], false, true)
+
+	stats := tp.CalcSegmentStats(true)
+	assert.True(t, stats.NumSegments > 0)
+	assert.True(t, stats.NumActiveSegments > 0)
+	assert.True(t, stats.NumActiveSynapses > 0)
+
+	segs, cells, syns, segsByAge, synsByPerm := 0, 0, 0, 0, 0
+	for size, count := range stats.DistSegSizes {
+		segs += count
+		syns += size * count
+	}
+	for _, count := range stats.DistNumSegsPerCell {
+		cells += count
+	}
+	for bucket, count := range stats.DistPermValues {
+		assert.True(t, bucket >= 0 && bucket <= 10)
+		synsByPerm += count
+	}
+	for _, bucket := range stats.DistAges {
+		assert.True(t, bucket.Start <= bucket.End)
+		segsByAge += bucket.Count
+	}
+
+	assert.Equal(t, stats.NumSegments, segs)
+	assert.Equal(t, stats.NumSegments, segsByAge)
+	assert.Equal(t, stats.NumSynapses, syns)
+	assert.Equal(t, stats.NumSynapses, synsByPerm)
+	assert.Equal(t, 100, cells)
+	assert.Equal(t, 20, len(stats.DistAges))
+
+	//active data is optional and active segments are not added to NumSegments
+	numSegments := stats.NumSegments
+	stats = tp.CalcSegmentStats(false)
+	assert.Equal(t, numSegments, stats.NumSegments)
+	assert.Equal(t, 0, stats.NumActiveSegments)
+	assert.Equal(t, 0, stats.NumActiveSynapses)
+}
+
+func TestPrintComputeEnd(t *testing.T) {
+	tp, inputs := trainedDiagnosticsTp()
+
+	var buf bytes.Buffer
+	tp.DiagnosticWriter = &buf
+
+	//silent at verbosity 0
+	tp.Compute(inputs[0], false, true)
+	assert.Equal(t, 0, buf.Len())
+
+	tp.params.Verbosity = 1
+	output := tp.Compute(inputs[1], false, true)
+	assert.True(t, strings.HasPrefix(buf.String(), "TP: learn: false\n"))
+	assert.Nil(t, tp.PrintComputeEnd(&buf, output, false))
+
+	buf.Reset()
+	tp.params.Verbosity = 4
+	output = tp.Compute(inputs[2], false, true)
+	assert.True(t, strings.Contains(buf.String(), "----- InfActiveState (10 on) ------"))
+	assert.True(t, strings.Contains(buf.String(), "--- PREDICTED CELLS ---"))
+
+	stats := tp.PrintComputeEnd(&buf, output, false)
+	assert.Equal(t, tp.CalcSegmentStats(true).NumSegments, stats.NumSegments)
+
+	buf.Reset()
+	tp.PrintCell(&buf, 20, 0, false)
+	tp.PrintCell(&buf, 20, 1, false)
+	assert.True(t, strings.Contains(buf.String(), "Column: 20 Cell:"))
+}
+
+func TestTemporalPoolerLogger(t *testing.T) {
+	tp, inputs := trainedDiagnosticsTp()
+
+	//verbose but without a logger nothing is written
+	tp.params.Verbosity = 3
+	tp.Compute(inputs[0], true, true)
+
+	var buf bytes.Buffer
+	tp.logger = utils.NewWriterLogger(&buf, utils.Debug)
+	tp.Reset()
+	tp.Compute(inputs[0], true, true)
+	assert.True(t, strings.HasPrefix(buf.String(), "level=debug msg=\"Reset\" component=tp iteration="))
+	assert.True(t, strings.Contains(buf.String(), "msg=\"Starting over\" component=tp iteration="))
+
+	//verbosity still controls the level of detail
+	buf.Reset()
+	tp.params.Verbosity = 0
+	tp.Compute(inputs[1], true, true)
+	assert.Equal(t, 0, buf.Len())
+}
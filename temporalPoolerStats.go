@@ -7,9 +7,7 @@ package htm
 import (
 	"fmt"
 	"github.com/cznic/mathutil"
-	"github.com/nupic-community/htm/utils"
 	"github.com/zacg/floats"
-	"github.com/zacg/go.matrix"
 	//"math"
 	//"math/rand"
 	//"sort"
@@ -33,7 +31,34 @@ type TpStats struct {
 	CurFalsePositiveScore float64
 	CurMissing            float64
 	CurExtra              float64
-	ConfHistogram         matrix.DenseMatrix
+
+	//Sum of the column normalized confidences of the correctly predicted
+	//cells over the current sequence, indexed by [column][cell]. Only
+	//collected when CollectSequenceStats is set.
+	ConfHistogram [][]float64
+	//Flattened ConfHistogram of the last completed sequence, set on reset.
+	//Sequences with similar signatures were recognized by the same cells.
+	PrevSequenceSignature []float64
+}
+
+//Returns a deep copy of the stats
+func (s *TpStats) Copy() *TpStats {
+	result := new(TpStats)
+	*result = *s
+
+	if s.ConfHistogram != nil {
+		result.ConfHistogram = make([][]float64, len(s.ConfHistogram))
+		for idx, row := range s.ConfHistogram {
+			result.ConfHistogram[idx] = make([]float64, len(row))
+			copy(result.ConfHistogram[idx], row)
+		}
+	}
+	if s.PrevSequenceSignature != nil {
+		result.PrevSequenceSignature = make([]float64, len(s.PrevSequenceSignature))
+		copy(result.PrevSequenceSignature, s.PrevSequenceSignature)
+	}
+
+	return result
 }
 
 func (s *TpStats) ToString() string {
@@ -55,7 +80,8 @@ func (s *TpStats) ToString() string {
 	result += fmt.Sprintf("CurFalsePositiveScore %v \n", s.CurFalsePositiveScore)
 	result += fmt.Sprintf("CurMissing %v \n", s.CurMissing)
 	result += fmt.Sprintf("CurExtra %v \n", s.CurExtra)
-	result += fmt.Sprintf("ConfHistogram %v \n", s.ConfHistogram.String())
+	result += fmt.Sprintf("ConfHistogram %v \n", s.ConfHistogram)
+	result += fmt.Sprintf("PrevSequenceSignature %v \n", s.PrevSequenceSignature)
 
 	return result
 }
@@ -103,9 +129,10 @@ current output
 retval conf_i the confidence score for the i'th pattern inpatternsToCheck
 This consists of 3 items as a tuple:
 (predictionScore, posPredictionScore, negPredictionScore)
-retval missing_i the bits in the i'th pattern that were missing
-in the output. This list is only returned if details is
-True.
+retval missing_i the bits in the patterns that were missing
+in the output, in ascending order without duplicates. NuPIC
+returns them in pattern order. This list is only returned if
+details is True.
 */
 func (tp *TemporalPooler) checkPrediction2(patternNZs [][]int, output *SparseBinaryMatrix,
 	colConfidence []float64, details bool) (int, int, []confidence, []int) {
@@ -114,42 +141,27 @@ func (tp *TemporalPooler) checkPrediction2(patternNZs [][]int, output *SparseBin
 	numPatterns := len(patternNZs)
 
 	// Compute the union of all the expected patterns
-	var orAll []int
+	expected := NewDenseBinaryMatrix(1, tp.params.NumberOfCols)
+	pattern := NewDenseBinaryMatrix(1, tp.params.NumberOfCols)
 	for _, row := range patternNZs {
-		for _, col := range row {
-			if !utils.ContainsInt(col, orAll) {
-				orAll = append(orAll, col)
-			}
-		}
+		pattern.ReplaceRowByIndices(0, row)
+		expected.OrInPlace(pattern)
 	}
 
-	var outputIdxs []int
-
 	// Get the list of active columns in the output
 	if output == nil {
 		if tp.CurrentOutput == nil {
 			panic("Expected tp output")
 		}
-		outputIdxs = tp.CurrentOutput.NonZeroRows()
-	} else {
-		outputIdxs = output.NonZeroRows()
+		output = tp.CurrentOutput
 	}
+	actual := NewDenseBinaryMatrix(1, tp.params.NumberOfCols)
+	actual.ReplaceRowByIndices(0, output.NonZeroRows())
 
 	// Compute the total extra and missing in the output
-	totalExtras := 0
-	totalMissing := 0
-
-	for _, val := range outputIdxs {
-		if !utils.ContainsInt(val, orAll) {
-			totalExtras++
-		}
-	}
-
-	for _, val := range orAll {
-		if !utils.ContainsInt(val, outputIdxs) {
-			totalMissing++
-		}
-	}
+	missing := expected.AndNot(actual)
+	totalExtras := actual.AndNot(expected).TotalNonZeroCount()
+	totalMissing := missing.TotalNonZeroCount()
 
 	// Get the percent confidence level per column by summing the confidence
 	// levels of the cells in the column. During training, each segment's
@@ -159,8 +171,8 @@ func (tp *TemporalPooler) checkPrediction2(patternNZs [][]int, output *SparseBin
 	// cell. Note that confidence will only be non-zero for predicted columns.
 
 	if colConfidence == nil {
-		if tp.params.Verbosity >= 5 {
-			fmt.Println("Col confidence nil, copying from tp state...")
+		if tp.debugEnabled(5) {
+			tp.debug("Col confidence nil, copying from tp state")
 		}
 		colConfidence = make([]float64, len(tp.DynamicState.ColConfidence))
 		copy(colConfidence, tp.DynamicState.ColConfidence)
@@ -213,17 +225,7 @@ func (tp *TemporalPooler) checkPrediction2(patternNZs [][]int, output *SparseBin
 
 	// Include detail? (bits in each pattern that were missing from the output)
 	if details {
-		var missingPatternBits []int
-		for _, pattern := range patternNZs {
-			for _, val := range pattern {
-				if !utils.ContainsInt(val, outputIdxs) &&
-					!utils.ContainsInt(val, missingPatternBits) {
-					missingPatternBits = append(missingPatternBits, val)
-				}
-			}
-
-		}
-		return totalExtras, totalMissing, confidences, missingPatternBits
+		return totalExtras, totalMissing, confidences, missing.GetRowIndices(0)
 	} else {
 		return totalExtras, totalMissing, confidences, nil
 	}
@@ -290,36 +292,34 @@ func (tp *TemporalPooler) updateStatsInferEnd(stats *TpStats, bottomUpNZ []int,
 	if tp.collectSequenceStats {
 		// Collect cell confidences for every cell that correctly predicted current
 		// bottom up input. Normalize confidence across each column
-		cc := tp.DynamicState.CellConfidence.Copy()
-
-		for r := 0; r < cc.Rows(); r++ {
-			for c := 0; c < cc.Cols(); c++ {
-				if !tp.DynamicState.InfActiveState.Get(r, c) {
-					cc.Set(r, c, 0)
-				}
+		if stats.ConfHistogram == nil {
+			stats.ConfHistogram = make([][]float64, tp.params.NumberOfCols)
+			for c := range stats.ConfHistogram {
+				stats.ConfHistogram[c] = make([]float64, tp.params.CellsPerColumn)
 			}
 		}
-		sconf := make([]int, cc.Rows())
-		for r := 0; r < cc.Rows(); r++ {
-			count := 0
-			for c := 0; c < cc.Cols(); c++ {
-				if cc.Get(r, c) > 0 {
-					count++
-				}
-			}
-			sconf[r] = count
+
+		cellConfidence := tp.DynamicState.CellConfidenceLast
+		if cellConfidence == nil {
+			return
 		}
 
-		for r := 0; r < cc.Rows(); r++ {
-			for c := 0; c < cc.Cols(); c++ {
-				temp := cc.Get(r, c)
-				cc.Set(r, c, temp/float64(sconf[r]))
+		for _, c := range tp.DynamicState.InfActiveState.NonZeroRows() {
+			activeCells := tp.DynamicState.InfActiveState.GetRowIndices(c)
+			sconf := 0.0
+			for _, i := range activeCells {
+				sconf += cellConfidence.Get(c, i)
+			}
+			if sconf <= 0 {
+				continue
 			}
-		}
 
-		// Update cell confidence histogram: add column-normalized confidence
-		// scores to the histogram
-		stats.ConfHistogram.Add(cc)
+			// Update cell confidence histogram: add column-normalized confidence
+			// scores to the histogram
+			for _, i := range activeCells {
+				stats.ConfHistogram[c][i] += cellConfidence.Get(c, i) / sconf
+			}
+		}
 	}
 
 }
@@ -0,0 +1,117 @@
+//
+// Segment storage for the temporal pooler
+//
+
+package htm
+
+/*
+ Preallocated segment and synapse storage used in fixed size CLA mode
+(MaxSegmentsPerCell and MaxSynapsesPerSegment set). Every cell owns
+MaxSegmentsPerCell segment slots and every slot owns a block of
+MaxSynapsesPerSegment synapses, both carved out of flat arrays up front.
+Segments are created and deleted by reslicing the cell, so memory use
+stays constant no matter how long the pooler is trained.
+*/
+type fixedSegmentStorage struct {
+	maxSegmentsPerCell    int
+	maxSynapsesPerSegment int
+	segments              []Segment
+	synapses              []Synapse
+}
+
+func newFixedSegmentStorage(numCols, cellsPerColumn, maxSegs, maxSyns int) *fixedSegmentStorage {
+	fs := new(fixedSegmentStorage)
+	fs.maxSegmentsPerCell = maxSegs
+	fs.maxSynapsesPerSegment = maxSyns
+
+	numSegments := numCols * cellsPerColumn * maxSegs
+	fs.segments = make([]Segment, numSegments)
+	fs.synapses = make([]Synapse, numSegments*maxSyns)
+	for i := range fs.segments {
+		start := i * maxSyns
+		fs.segments[i].syns = fs.synapses[start:start : start+maxSyns]
+	}
+
+	return fs
+}
+
+//Returns empty segment lists for every cell, each with a capacity of
+//maxSegmentsPerCell backed by the flat segment array
+func (fs *fixedSegmentStorage) cells(numCols, cellsPerColumn int) [][][]Segment {
+	result := make([][][]Segment, numCols)
+	for c := 0; c < numCols; c++ {
+		result[c] = make([][]Segment, cellsPerColumn)
+		for i := 0; i < cellsPerColumn; i++ {
+			start := (c*cellsPerColumn + i) * fs.maxSegmentsPerCell
+			result[c][i] = fs.segments[start:start : start+fs.maxSegmentsPerCell]
+		}
+	}
+	return result
+}
+
+//Returns true if the pooler is running with fixed size resources
+func (tp *TemporalPooler) isFixedSize() bool {
+	return tp.params.MaxSegmentsPerCell > 0
+}
+
+/*
+ Creates a new segment on the specified cell and returns a reference to
+it. In fixed size mode the segment reuses a free slot of the cell, if the
+cell is full its least recently active segment is replaced.
+*/
+func (tp *TemporalPooler) createSegment(c, i int, isSequenceSeg bool) *Segment {
+	tp.stepChanges.SegmentsCreated++
+	if !tp.isFixedSize() {
+		tp.cells[c][i] = append(tp.cells[c][i], *NewSegment(tp, isSequenceSeg))
+		return &tp.cells[c][i][len(tp.cells[c][i])-1]
+	}
+
+	if len(tp.cells[c][i]) == cap(tp.cells[c][i]) {
+		tp.removeSegment(c, i, tp.leastRecentlyUsedSegment(c, i))
+	}
+
+	cell := tp.cells[c][i][:len(tp.cells[c][i])+1]
+	tp.cells[c][i] = cell
+	seg := &cell[len(cell)-1]
+	seg.init(tp, isSequenceSeg)
+	return seg
+}
+
+/*
+ Deletes the segment at the specified index of a cell and removes any
+pending updates for it. The deleted segment's synapse storage is moved to
+the end of the cell so it can be reused by the next segment.
+*/
+func (tp *TemporalPooler) removeSegment(c, i, segIdx int) {
+	cell := tp.cells[c][i]
+	tp.cleanUpdatesList(c, i, cell[segIdx])
+	tp.stepChanges.SegmentsDestroyed++
+	tp.stepChanges.SynapsesDestroyed += len(cell[segIdx].syns)
+
+	syns := cell[segIdx].syns[:0]
+	copy(cell[segIdx:], cell[segIdx+1:])
+	cell[len(cell)-1] = Segment{syns: syns}
+	tp.cells[c][i] = cell[:len(cell)-1]
+}
+
+//Returns the index of the segment with the specified id on a cell, -1 if
+//the cell has no such segment
+func (tp *TemporalPooler) segmentIndex(c, i, segId int) int {
+	for idx, s := range tp.cells[c][i] {
+		if s.segId == segId {
+			return idx
+		}
+	}
+	return -1
+}
+
+//Returns the index of the segment on a cell that was least recently active
+func (tp *TemporalPooler) leastRecentlyUsedSegment(c, i int) int {
+	result := -1
+	for idx, s := range tp.cells[c][i] {
+		if result == -1 || s.lastActiveIteration < tp.cells[c][i][result].lastActiveIteration {
+			result = idx
+		}
+	}
+	return result
+}
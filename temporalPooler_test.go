@@ -7,7 +7,8 @@ import (
 	//"math"
 	"math/rand"
 	//"sort"
-	//"github.com/gonum/floats"
+	"github.com/gonum/floats"
+	"github.com/nupic-community/htm/utils"
 	//"github.com/zacg/ints"
 	"github.com/zacg/testify/assert"
 	"testing"
@@ -88,3 +89,396 @@ func GenerateRandSequence(size int, width int) []bool {
 
 	return input
 }
+
+func TestCheckPrediction2(t *testing.T) {
+	tps := NewTemporalPoolerParams()
+	tps.NumberOfCols = 10
+	tps.CellsPerColumn = 2
+	tp := NewTemporalPooler(*tps)
+
+	output := NewSparseBinaryMatrix(10, 2)
+	output.Set(1, 0, true)
+	output.Set(2, 1, true)
+	output.Set(7, 0, true)
+
+	colConfidence := make([]float64, 10)
+	colConfidence[1] = 0.5
+	colConfidence[7] = 0.5
+
+	extras, missing, confidences, missingBits := tp.checkPrediction2([][]int{{1, 2, 3}, {3, 4}}, output, colConfidence, true)
+	assert.Equal(t, 1, extras)
+	assert.Equal(t, 2, missing)
+	assert.Equal(t, []int{3, 4}, missingBits)
+	assert.Equal(t, 2, len(confidences))
+	assert.AlmostEqualFloat(t, 0.5, confidences[0].PositivePredictionScore)
+	assert.AlmostEqualFloat(t, 0.0, confidences[0].PredictionScore)
+	assert.AlmostEqualFloat(t, -1.0, confidences[1].PredictionScore)
+}
+
+func TestCheckPrediction2MissingBitsOrder(t *testing.T) {
+	tps := NewTemporalPoolerParams()
+	tps.NumberOfCols = 10
+	tps.CellsPerColumn = 2
+	tp := NewTemporalPooler(*tps)
+
+	output := NewSparseBinaryMatrix(10, 2)
+	output.Set(4, 1, true)
+
+	//missing bits are sorted and shared bits reported once
+	_, missing, _, missingBits := tp.checkPrediction2([][]int{{8, 1, 4}, {5, 1}}, output, make([]float64, 10), true)
+	assert.Equal(t, 3, missing)
+	assert.Equal(t, []int{1, 5, 8}, missingBits)
+}
+
+func fixedSizeTpParams() *TemporalPoolerParams {
+	tps := NewTemporalPoolerParams()
+	tps.Verbosity = 0
+	tps.NumberOfCols = 50
+	tps.CellsPerColumn = 4
+	tps.ActivationThreshold = 8
+	tps.MinThreshold = 10
+	tps.InitialPerm = 0.5
+	tps.ConnectedPerm = 0.5
+	tps.NewSynapseCount = 10
+	tps.GlobalDecay = 0
+	tps.MaxAge = 0
+	tps.MaxSegmentsPerCell = 2
+	tps.MaxSynapsesPerSegment = 12
+	return tps
+}
+
+func TestFixedSizeStorage(t *testing.T) {
+	tp := NewTemporalPooler(*fixedSizeTpParams())
+
+	for i := 0; i < 300; i++ {
+		tp.Compute(GenerateRandSequence(50, 10), true, false)
+		if i%20 == 0 {
+			tp.Reset()
+		}
+	}
+
+	numSegments := 0
+	for _, col := range tp.cells {
+		for _, cell := range col {
+			assert.Equal(t, 2, cap(cell))
+			assert.True(t, len(cell) <= 2)
+			numSegments += len(cell)
+			for _, seg := range cell {
+				assert.Equal(t, 12, cap(seg.syns))
+				assert.True(t, len(seg.syns) <= 12)
+			}
+		}
+	}
+	assert.True(t, numSegments > 0)
+}
+
+func TestFixedSizeSegmentReplacement(t *testing.T) {
+	tp := NewTemporalPooler(*fixedSizeTpParams())
+
+	first := tp.createSegment(3, 1, true)
+	first.AddSynapse(1, 1, 0.5)
+	first.lastActiveIteration = 5
+	second := tp.createSegment(3, 1, true)
+	second.lastActiveIteration = 2
+	secondId := second.segId
+
+	//cell is full, least recently active segment is replaced
+	third := tp.createSegment(3, 1, false)
+	assert.Equal(t, 2, len(tp.cells[3][1]))
+	assert.Equal(t, 5, tp.cells[3][1][0].lastActiveIteration)
+	assert.Equal(t, 1, len(tp.cells[3][1][0].syns))
+	assert.Equal(t, 0, len(third.syns))
+	assert.NotEqual(t, secondId, third.segId)
+	assert.Equal(t, 12, cap(third.syns))
+
+	//creating and removing segments does not allocate
+	allocs := testing.AllocsPerRun(100, func() {
+		seg := tp.createSegment(7, 2, false)
+		seg.AddSynapse(2, 1, 0.5)
+		tp.removeSegment(7, 2, 0)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+func TestTrimSegmentsInCell(t *testing.T) {
+	tp := NewTemporalPooler(*fixedSizeTpParams())
+
+	seg := tp.createSegment(3, 1, true)
+	seg.AddSynapse(1, 0, 0.0)
+	seg.AddSynapse(2, 0, 0.5)
+	seg.AddSynapse(4, 1, 0.0)
+	other := tp.createSegment(3, 1, false)
+	other.AddSynapse(5, 0, 0.3)
+	other.AddSynapse(6, 0, 0.2)
+	tp.stepChanges = TpStepEvent{}
+
+	//segments are passed as copies, the cell's own segment is trimmed
+	segs, syns := tp.trimSegmentsInCell(3, 1, []Segment{*seg}, 0.00001, 0)
+	assert.Equal(t, 0, segs)
+	assert.Equal(t, 2, syns)
+	assert.Equal(t, []Synapse{{2, 0, 0.5}}, tp.cells[3][1][0].syns)
+	assert.Equal(t, 12, cap(tp.cells[3][1][0].syns))
+	assert.Equal(t, 2, tp.stepChanges.SynapsesDestroyed)
+
+	//synapses of segments removed whole are counted once
+	tp.stepChanges = TpStepEvent{}
+	segs, syns = tp.trimSegmentsInCell(3, 1, []Segment{tp.cells[3][1][1]}, 0.25, 2)
+	assert.Equal(t, 1, segs)
+	assert.Equal(t, 2, syns)
+	assert.Equal(t, 1, len(tp.cells[3][1]))
+	assert.Equal(t, 1, tp.stepChanges.SegmentsDestroyed)
+	assert.Equal(t, 2, tp.stepChanges.SynapsesDestroyed)
+}
+
+func TestGlobalDecay(t *testing.T) {
+	observer := new(recordingObserver)
+	tps := NewTemporalPoolerParams()
+	tps.NumberOfCols = 50
+	tps.CellsPerColumn = 4
+	tps.GlobalDecay = 0.1
+	tps.MaxAge = 2
+	tps.Observer = observer
+	tp := NewTemporalPooler(*tps)
+
+	tp.cells[3][1] = []Segment{*NewSegment(tp, true), *NewSegment(tp, true), *NewSegment(tp, true)}
+	decayed, removed, recent := &tp.cells[3][1][0], &tp.cells[3][1][1], &tp.cells[3][1][2]
+	decayed.AddSynapse(1, 0, 0.05)
+	decayed.AddSynapse(2, 0, 0.5)
+	removed.AddSynapse(3, 0, 0.05)
+	recent.AddSynapse(4, 0, 0.05)
+	recent.lastActiveIteration = 3
+	recentId := recent.segId
+
+	//decay is applied every MaxAge iterations to segments older than MaxAge
+	for i := 0; i < 4; i++ {
+		tp.Compute(make([]bool, 50), true, false)
+	}
+
+	cell := tp.cells[3][1]
+	assert.Equal(t, 2, len(cell))
+	assert.Equal(t, 1, len(cell[0].syns))
+	assert.Equal(t, 2, cell[0].syns[0].SrcCellCol)
+	assert.AlmostEqualFloat(t, 0.4, cell[0].syns[0].Permanence)
+	assert.Equal(t, recentId, cell[1].segId)
+	assert.AlmostEqualFloat(t, 0.05, cell[1].syns[0].Permanence)
+
+	event := observer.tp[3]
+	assert.Equal(t, 1, event.SegmentsDestroyed)
+	assert.Equal(t, 2, event.SynapsesDestroyed)
+}
+
+func TestPredictColumns(t *testing.T) {
+	tps := NewTemporalPoolerParams()
+	tps.Verbosity = 0
+	tps.NumberOfCols = 50
+	tps.CellsPerColumn = 2
+	tps.ActivationThreshold = 8
+	tps.MinThreshold = 10
+	tps.InitialPerm = 0.5
+	tps.ConnectedPerm = 0.5
+	tps.NewSynapseCount = 10
+	tps.PermanenceDec = 0.0
+	tps.PermanenceInc = 0.1
+	tps.GlobalDecay = 0
+	tps.BurnIn = 1
+	tps.PamLength = 10
+	tp := NewTemporalPooler(*tps)
+
+	inputs := make([][]bool, 5)
+	for i := range inputs {
+		inputs[i] = boolRange(i*10, i*10+9, 50)
+	}
+
+	for i := 0; i < 10; i++ {
+		for p := 0; p < 5; p++ {
+			tp.Compute(inputs[p], true, false)
+		}
+		tp.Reset()
+	}
+
+	tp.Compute(inputs[0], false, true)
+	before := tp.DynamicState.Copy()
+
+	predictions := tp.PredictColumns(3, ThresholdSelector(0.01))
+	assert.Equal(t, 3, len(predictions))
+	for step, prediction := range predictions {
+		assert.Equal(t, step+1, prediction.Step)
+		assert.Equal(t, utils.OnIndices(inputs[step+1]), prediction.Columns)
+		assert.Equal(t, len(prediction.Columns), len(prediction.Confidences))
+		assert.AlmostEqualFloat(t, 1.0, floats.Sum(prediction.Confidences))
+	}
+
+	topK := tp.PredictColumns(2, TopKSelector(4))
+	assert.Equal(t, 4, len(topK[0].Columns))
+	assert.Equal(t, 4, len(topK[1].Columns))
+	for _, col := range topK[1].Columns {
+		assert.True(t, inputs[2][col])
+	}
+
+	confidences := tp.Predict(2)
+	assert.Equal(t, 2, len(confidences))
+	assert.Equal(t, tp.DynamicState.ColConfidence, confidences[0])
+
+	//dynamic state is untouched
+	assert.Equal(t, before.InfPredictedState.Entries(), tp.DynamicState.InfPredictedState.Entries())
+	assert.Equal(t, before.InfActiveState.Entries(), tp.DynamicState.InfActiveState.Entries())
+	assert.Equal(t, before.ColConfidence, tp.DynamicState.ColConfidence)
+
+	assert.Panics(t, func() { tp.Predict(0) })
+
+	//steps are validated before anything is allocated
+	for _, nSteps := range []int{0, -1} {
+		predictions := []func(){
+			func() { tp.Predict(nSteps) },
+			func() { tp.PredictColumns(nSteps, TopKSelector(4)) },
+		}
+		for _, predict := range predictions {
+			func() {
+				defer func() {
+					assert.Equal(t, "nSteps must be greater than zero", recover())
+				}()
+				predict()
+			}()
+		}
+	}
+}
+
+func TestOutputTypes(t *testing.T) {
+	tps := NewTemporalPoolerParams()
+	tps.Verbosity = 0
+	tps.NumberOfCols = 50
+	tps.CellsPerColumn = 2
+	tps.ActivationThreshold = 8
+	tps.MinThreshold = 10
+	tps.InitialPerm = 0.5
+	tps.ConnectedPerm = 0.5
+	tps.NewSynapseCount = 10
+	tps.PermanenceDec = 0.0
+	tps.PermanenceInc = 0.1
+	tps.GlobalDecay = 0
+	tps.BurnIn = 1
+	tps.PamLength = 10
+	tp := NewTemporalPooler(*tps)
+	assert.Equal(t, Normal, tp.params.OutputType)
+
+	inputs := make([][]bool, 5)
+	for i := range inputs {
+		inputs[i] = boolRange(i*10, i*10+9, 50)
+	}
+	for i := 0; i < 10; i++ {
+		for p := 0; p < 5; p++ {
+			tp.Compute(inputs[p], true, false)
+		}
+		tp.Reset()
+	}
+
+	tp.Compute(inputs[0], false, true)
+
+	tp.params.OutputType = Normal
+	output := tp.Compute(inputs[1], false, true)
+	ds := tp.DynamicState
+	assert.Equal(t, ds.InfActiveState.Or(ds.InfPredictedState).Flatten(), output)
+	assert.Equal(t, utils.OnIndices(output), tp.OutputIndices())
+
+	tp.params.OutputType = ActiveState
+	output = tp.Compute(inputs[2], false, true)
+	assert.Equal(t, tp.DynamicState.InfActiveState.Flatten(), output)
+
+	tp.params.OutputType = ActiveState1CellPerCol
+	output = tp.Compute(inputs[3], false, true)
+	activeCols := tp.DynamicState.InfActiveState.NonZeroRows()
+	for c := 0; c < tps.NumberOfCols; c++ {
+		count := 0
+		for i := 0; i < tps.CellsPerColumn; i++ {
+			if output[c*tps.CellsPerColumn+i] {
+				count++
+			}
+		}
+		if utils.ContainsInt(c, activeCols) {
+			assert.Equal(t, 1, count)
+		} else {
+			assert.Equal(t, 0, count)
+		}
+	}
+
+	//predicted active cells are the correctly predicted part of the sequence
+	tp.params.OutputType = PredictedActiveCells
+	output = tp.Compute(inputs[4], false, true)
+	expected := tp.DynamicState.InfActiveState.And(tp.prevPredictedState)
+	assert.Equal(t, expected.Flatten(), output)
+	assert.Equal(t, 10, len(tp.OutputIndices()))
+	for _, idx := range tp.OutputIndices() {
+		assert.True(t, inputs[4][idx/tps.CellsPerColumn])
+	}
+
+	//unexpected input has no predicted active cells
+	output = tp.Compute(inputs[1], false, true)
+	assert.Equal(t, 0, len(tp.OutputIndices()))
+	assert.Equal(t, 0, len(utils.OnIndices(output)))
+}
+
+func TestSequenceStats(t *testing.T) {
+	tps := NewTemporalPoolerParams()
+	tps.Verbosity = 0
+	tps.NumberOfCols = 50
+	tps.CellsPerColumn = 2
+	tps.ActivationThreshold = 8
+	tps.MinThreshold = 10
+	tps.InitialPerm = 0.5
+	tps.ConnectedPerm = 0.5
+	tps.NewSynapseCount = 10
+	tps.PermanenceDec = 0.0
+	tps.PermanenceInc = 0.1
+	tps.GlobalDecay = 0
+	tps.BurnIn = 1
+	tps.PamLength = 10
+	tps.CollectStats = true
+	tps.CollectSequenceStats = true
+	tp := NewTemporalPooler(*tps)
+
+	inputs := make([][]bool, 5)
+	for i := range inputs {
+		inputs[i] = boolRange(i*10, i*10+9, 50)
+	}
+	for i := 0; i < 10; i++ {
+		for p := 0; p < 5; p++ {
+			tp.Compute(inputs[p], true, false)
+		}
+		tp.Reset()
+	}
+
+	//infer the first part of the sequence only
+	for p := 0; p < 3; p++ {
+		tp.Compute(inputs[p], false, true)
+	}
+	stats := tp.Stats()
+	assert.Equal(t, 3, stats.NInfersSinceReset)
+	assert.Equal(t, tps.NumberOfCols, len(stats.ConfHistogram))
+
+	tp.Reset()
+	signature := tp.Stats().PrevSequenceSignature
+	assert.Equal(t, tps.NumberOfCols*tps.CellsPerColumn, len(signature))
+	sum := 0.0
+	for idx, val := range signature {
+		col := idx / tps.CellsPerColumn
+		if col >= 30 {
+			assert.Equal(t, 0.0, val)
+		}
+		sum += val
+	}
+	assert.True(t, sum > 0)
+
+	//histogram starts over for the next sequence
+	for _, col := range tp.Stats().ConfHistogram {
+		assert.Equal(t, 0.0, utils.SumSliceFloat64(col))
+	}
+
+	//stats are a copy
+	stats = tp.Stats()
+	stats.PrevSequenceSignature[0] = 42
+	assert.NotEqual(t, 42.0, tp.Stats().PrevSequenceSignature[0])
+
+	//no sequence seen since the last reset
+	tp.Reset()
+	assert.Nil(t, tp.Stats().PrevSequenceSignature)
+}
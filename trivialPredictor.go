@@ -6,8 +6,8 @@ import (
 	"github.com/nupic-community/htm/utils"
 	//"github.com/skelterjohn/go.matrix"
 	//"math"
-	//"math/rand"
-	//"sort"
+	"math/rand"
+	"sort"
 	//"github.com/gonum/floats"
 	"github.com/zacg/ints"
 )
@@ -33,6 +33,22 @@ const (
 	Lots   PredictorMethod = 5
 )
 
+func (m PredictorMethod) String() string {
+	switch m {
+	case Random:
+		return "random"
+	case Zeroth:
+		return "zeroth"
+	case Last:
+		return "last"
+	case All:
+		return "all"
+	case Lots:
+		return "lots"
+	}
+	return fmt.Sprintf("PredictorMethod(%d)", int(m))
+}
+
 type TrivialPredictorState struct {
 	ActiveState        []bool
 	ActiveStateLast    []bool
@@ -46,14 +62,27 @@ type TrivialPredictor struct {
 	NumOfCols      int
 	Methods        []PredictorMethod
 	Verbosity      int
+	Logger         utils.Logger
 	InternalStats  map[PredictorMethod]*TpStats
 	State          map[PredictorMethod]TrivialPredictorState
 	ColumnCount    []int
 	AverageDensity float64
+
+	rand *rand.Rand
 }
 
-func MakeTrivialPredictor(numberOfCols int, methods []PredictorMethod) *TrivialPredictor {
+/*
+ Creates a trivial predictor for each of the specified methods, seed is
+used for the random predictions.
+*/
+func MakeTrivialPredictor(numberOfCols int, methods []PredictorMethod, seed int) *TrivialPredictor {
 	tp := new(TrivialPredictor)
+	tp.NumOfCols = numberOfCols
+	tp.Methods = methods
+	tp.State = make(map[PredictorMethod]TrivialPredictorState, len(methods))
+	tp.InternalStats = make(map[PredictorMethod]*TpStats, len(methods))
+	tp.rand = rand.New(rand.NewSource(int64(seed)))
+	tp.Logger = utils.NopLogger{}
 
 	for _, method := range methods {
 		tps := TrivialPredictorState{}
@@ -78,9 +107,24 @@ func MakeTrivialPredictor(numberOfCols int, methods []PredictorMethod) *TrivialP
 }
 
 /*
-
+ Returns the indices of the n most frequently active columns in
+ascending order.
 */
+func (tp *TrivialPredictor) mostFrequentColumns(n int) []int {
+	counts := make([]int, len(tp.ColumnCount))
+	copy(counts, tp.ColumnCount)
+	inds := make([]int, len(counts))
+	ints.Argsort(counts, inds)
+
+	result := inds[len(inds)-n:]
+	sort.Ints(result)
+	return result
+}
 
+/*
+ Do one iteration of inference, computing the predictions of every method
+for the next time step.
+*/
 func (tp *TrivialPredictor) infer(activeColumns []int) {
 
 	numColsToPredict := int(0.5 + tp.AverageDensity*float64(tp.NumOfCols))
@@ -105,13 +149,11 @@ func (tp *TrivialPredictor) infer(activeColumns []int) {
 		switch method {
 		case Random:
 			// Randomly predict N columns
-			//predictedCols = RandomInts(numColsToPredict, tp.NumOfCols)
+			predictedCols = tp.rand.Perm(tp.NumOfCols)[:numColsToPredict]
 			break
 		case Zeroth:
 			// Always predict the top N most frequent columns
-			var inds []int
-			ints.Argsort(tp.ColumnCount, inds)
-			predictedCols = inds[len(inds)-numColsToPredict:]
+			predictedCols = tp.mostFrequentColumns(numColsToPredict)
 			break
 		case Last:
 			// Always predict the last input
@@ -130,9 +172,7 @@ func (tp *TrivialPredictor) infer(activeColumns []int) {
 		case Lots:
 			// Always predict 2 * the top N most frequent columns
 			numColsToPredict := mathutil.Min(2*numColsToPredict, tp.NumOfCols)
-			var inds []int
-			ints.Argsort(tp.ColumnCount, inds)
-			predictedCols = inds[len(inds)-numColsToPredict:]
+			predictedCols = tp.mostFrequentColumns(numColsToPredict)
 
 			break
 		default:
@@ -144,10 +184,9 @@ func (tp *TrivialPredictor) infer(activeColumns []int) {
 			tp.State[method].Confidence[val] = 1.0
 		}
 
-		if tp.Verbosity > 1 {
-			fmt.Println("Random prediction:", method)
-			fmt.Println(" numColsToPredict:", numColsToPredict)
-			fmt.Println(predictedCols)
+		if tp.Verbosity > 1 && tp.Logger.Enabled(utils.Debug) {
+			tp.Logger.Log(utils.Debug, "Trivial prediction", utils.Field("method", method),
+				utils.Field("numColsToPredict", numColsToPredict), utils.Field("columns", predictedCols))
 		}
 
 	}
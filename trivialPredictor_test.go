@@ -0,0 +1,110 @@
+package htm
+
+import (
+	"github.com/nupic-community/htm/utils"
+	"github.com/zacg/testify/assert"
+	"testing"
+)
+
+func TestTrivialPredictorMethods(t *testing.T) {
+	methods := []PredictorMethod{Random, Zeroth, Last, All, Lots}
+	tp := MakeTrivialPredictor(20, methods, 42)
+	assert.Equal(t, 20, tp.NumOfCols)
+	assert.Equal(t, methods, tp.Methods)
+
+	for i := 0; i < 20; i++ {
+		tp.learn([]int{1, 3, 5, 7})
+	}
+	tp.learn([]int{2, 4})
+
+	predicted := func(method PredictorMethod) []int {
+		return utils.OnIndices(tp.State[method].PredictedState)
+	}
+
+	assert.Equal(t, []int{2, 4}, predicted(Last))
+	assert.Equal(t, 20, len(predicted(All)))
+
+	n := int(0.5 + tp.AverageDensity*20)
+	assert.Equal(t, n, len(predicted(Random)))
+	assert.Equal(t, n, len(predicted(Zeroth)))
+	for _, col := range predicted(Zeroth) {
+		assert.True(t, utils.ContainsInt(col, []int{1, 3, 5, 7}))
+	}
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 7}, predicted(Lots))
+
+	//learning must not reorder the column counts
+	assert.Equal(t, 20, tp.ColumnCount[1])
+	assert.Equal(t, 1, tp.ColumnCount[2])
+
+	tp.reset()
+	for _, method := range methods {
+		assert.Equal(t, 0, len(predicted(method)))
+	}
+}
+
+func TestTrivialPredictorSeed(t *testing.T) {
+	a := MakeTrivialPredictor(100, []PredictorMethod{Random}, 7)
+	b := MakeTrivialPredictor(100, []PredictorMethod{Random}, 7)
+	for i := 0; i < 5; i++ {
+		a.learn([]int{i, i + 10, i + 20})
+		b.learn([]int{i, i + 10, i + 20})
+		assert.Equal(t, a.State[Random].PredictedState, b.State[Random].PredictedState)
+	}
+}
+
+func TestTrivialPredictorStats(t *testing.T) {
+	tps := NewTemporalPoolerParams()
+	tps.Verbosity = 0
+	tps.NumberOfCols = 50
+	tps.CellsPerColumn = 2
+	tps.ActivationThreshold = 8
+	tps.MinThreshold = 10
+	tps.InitialPerm = 0.5
+	tps.ConnectedPerm = 0.5
+	tps.NewSynapseCount = 10
+	tps.PermanenceDec = 0.0
+	tps.PermanenceInc = 0.1
+	tps.GlobalDecay = 0
+	tps.BurnIn = 1
+	tps.PamLength = 10
+	tps.CollectStats = true
+	tps.TrivialPredictionMethods = []PredictorMethod{Random, Zeroth, Last, All, Lots}
+	tp := NewTemporalPooler(*tps)
+
+	inputs := make([][]bool, 5)
+	for i := range inputs {
+		inputs[i] = boolRange(i*10, i*10+9, 50)
+	}
+	for i := 0; i < 5; i++ {
+		for p := 0; p < 5; p++ {
+			tp.Compute(inputs[p], true, false)
+		}
+		tp.Reset()
+	}
+	for p := 0; p < 5; p++ {
+		tp.Compute(inputs[p], false, true)
+	}
+
+	stats := tp.TrivialPredictorStats()
+	assert.Equal(t, 5, len(stats))
+	for _, method := range tps.TrivialPredictionMethods {
+		assert.Equal(t, 5, stats[method].NInfersSinceReset)
+		//totals accumulate over every sequence, 4 predictions each
+		assert.Equal(t, 24, stats[method].NPredictions)
+	}
+
+	//the last input never predicts the next pattern of the sequence
+	assert.Equal(t, 240.0, stats[Last].TotalMissing)
+	assert.Equal(t, 240.0, stats[Last].TotalExtra)
+	//predicting all columns never misses
+	assert.Equal(t, 0.0, stats[All].TotalMissing)
+
+	//stats are a copy
+	s := stats[Last]
+	s.TotalMissing = 0
+	assert.Equal(t, 240.0, tp.TrivialPredictorStats()[Last].TotalMissing)
+
+	tps.TrivialPredictionMethods = nil
+	assert.Nil(t, NewTemporalPooler(*tps).TrivialPredictorStats())
+}
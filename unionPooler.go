@@ -0,0 +1,155 @@
+package htm
+
+import (
+	"github.com/nupic-community/htm/utils"
+	"math"
+	"sort"
+)
+
+type UnionPoolerParams struct {
+	//Spatial pooler over the temporal memory cells, the input dimensions
+	//are the number of cells
+	SP SpParams
+	//Weight of the overlap with active cells in the pooling activation
+	ActiveOverlapWeight float64
+	//Weight of the overlap with correctly predicted active cells in the
+	//pooling activation
+	PredictedActiveOverlapWeight float64
+	//Maximum fraction of columns in the union SDR
+	MaxUnionActivity float64
+	//Steps for the pooling activation to decay by a factor of e, 0 disables
+	//decay
+	DecayTimeConst float64
+}
+
+//Initializes default union pooler params
+func NewUnionPoolerParams() UnionPoolerParams {
+	p := UnionPoolerParams{}
+	p.SP = NewSpParams()
+	p.SP.GlobalInhibition = true
+	p.ActiveOverlapWeight = 1.0
+	p.PredictedActiveOverlapWeight = 0.0
+	p.MaxUnionActivity = 0.20
+	p.DecayTimeConst = 20.0
+	return p
+}
+
+/*
+ The union pooler produces a slowly changing representation of a sequence.
+A spatial pooler picks the columns best matching the temporal memory cells
+each step. Winning columns add their weighted overlap to a pooling
+activation which decays over time, the union SDR is formed by the columns
+with the highest pooling activation. While a learned sequence plays out
+the same columns keep winning so the union SDR stays stable.
+*/
+type UnionPooler struct {
+	UnionPoolerParams
+	SP *SpatialPooler
+
+	poolingActivation []float64
+	decay             float64
+	maxUnionSize      int
+	unionSDR          []int
+
+	activeInput    []bool
+	predictedInput []bool
+	activeArray    []bool
+}
+
+//Creates a union pooler, params.SP.InputDimensions must be set
+func NewUnionPooler(params UnionPoolerParams) *UnionPooler {
+	if params.MaxUnionActivity <= 0 || params.MaxUnionActivity > 1 {
+		panic("MaxUnionActivity must be in (0,1]")
+	}
+	if params.DecayTimeConst < 0 {
+		panic("DecayTimeConst must be >= 0")
+	}
+
+	up := new(UnionPooler)
+	up.UnionPoolerParams = params
+	up.SP = NewSpatialPooler(params.SP)
+
+	numColumns := up.SP.NumColumns()
+	up.poolingActivation = make([]float64, numColumns)
+	up.maxUnionSize = int(float64(numColumns) * params.MaxUnionActivity)
+	up.decay = 1.0
+	if params.DecayTimeConst > 0 {
+		up.decay = math.Exp(-1.0 / params.DecayTimeConst)
+	}
+
+	up.activeInput = make([]bool, up.SP.numInputs)
+	up.predictedInput = make([]bool, up.SP.numInputs)
+	up.activeArray = make([]bool, numColumns)
+
+	return up
+}
+
+/*
+ Updates the pooling activation from the temporal memories active cells
+and the active cells that were correctly predicted. Returns the union SDR.
+*/
+func (up *UnionPooler) Compute(activeCells []int, predictedActiveCells []int, learn bool) []int {
+	utils.FillSliceBool(up.activeInput, false)
+	utils.FillSliceBool(up.predictedInput, false)
+	for _, cell := range activeCells {
+		up.activeInput[cell] = true
+	}
+	for _, cell := range predictedActiveCells {
+		up.predictedInput[cell] = true
+	}
+
+	utils.FillSliceBool(up.activeArray, false)
+	up.SP.Compute(up.activeInput, learn, up.activeArray, up.SP.InhibitColumns)
+
+	for idx := range up.poolingActivation {
+		up.poolingActivation[idx] *= up.decay
+	}
+
+	activeOverlaps := up.SP.calculateOverlap(up.activeInput)
+	predictedOverlaps := up.SP.calculateOverlap(up.predictedInput)
+	for _, col := range utils.OnIndices(up.activeArray) {
+		up.poolingActivation[col] += up.ActiveOverlapWeight*float64(activeOverlaps[col]) +
+			up.PredictedActiveOverlapWeight*float64(predictedOverlaps[col])
+	}
+
+	up.unionSDR = up.topColumns()
+	return up.unionSDR
+}
+
+//Returns the columns with the highest non zero pooling activation
+func (up *UnionPooler) topColumns() []int {
+	var candidates []int
+	for idx, val := range up.poolingActivation {
+		if val > 0 {
+			candidates = append(candidates, idx)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return up.poolingActivation[candidates[i]] > up.poolingActivation[candidates[j]]
+	})
+	if len(candidates) > up.maxUnionSize {
+		candidates = candidates[:up.maxUnionSize]
+	}
+
+	sort.Ints(candidates)
+	return candidates
+}
+
+//Returns the union SDR of the last compute
+func (up *UnionPooler) UnionSDR() []int {
+	return up.unionSDR
+}
+
+//Returns a copy of the pooling activation of every column
+func (up *UnionPooler) PoolingActivation() []float64 {
+	result := make([]float64, len(up.poolingActivation))
+	copy(result, up.poolingActivation)
+	return result
+}
+
+//Clears the pooling activation, the next sequence starts a new union
+func (up *UnionPooler) Reset() {
+	utils.FillSliceFloat64(up.poolingActivation, 0)
+	up.unionSDR = nil
+}
@@ -0,0 +1,139 @@
+package htm
+
+import (
+	"github.com/nupic-community/htm/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func unionPoolerParams(inputs int) UnionPoolerParams {
+	p := NewUnionPoolerParams()
+	p.SP.InputDimensions = []int{inputs}
+	p.SP.ColumnDimensions = []int{256}
+	p.SP.PotentialRadius = 1000
+	p.SP.NumActiveColumnsPerInhArea = 10
+	p.SP.Seed = 2
+	return p
+}
+
+//Fraction of a found in b
+func sdrOverlap(a, b []int) float64 {
+	if len(a) == 0 {
+		return 0
+	}
+	count := 0
+	for _, val := range a {
+		if utils.ContainsInt(val, b) {
+			count++
+		}
+	}
+	return float64(count) / float64(len(a))
+}
+
+//Input with a block of 10 bits set for each of 8 symbols
+func unionInput(symbol int) []bool {
+	return boolRange(symbol*10, symbol*10+9, 80)
+}
+
+func TestUnionPoolerActivation(t *testing.T) {
+	params := unionPoolerParams(100)
+	params.SP.ColumnDimensions = []int{50}
+	params.SP.NumActiveColumnsPerInhArea = 5
+	params.DecayTimeConst = 10
+	params.PredictedActiveOverlapWeight = 2
+	up := NewUnionPooler(params)
+
+	union := up.Compute([]int{1, 2, 3, 4, 5, 6, 7, 8, 9}, nil, true)
+	//union size is limited by MaxUnionActivity
+	assert.True(t, len(union) > 0 && len(union) <= 10)
+	assert.Equal(t, union, up.UnionSDR())
+	first := up.PoolingActivation()
+	for idx, val := range first {
+		assert.Equal(t, val > 0, utils.ContainsInt(idx, union))
+	}
+
+	//activation decays when nothing is active
+	up.Compute(nil, nil, false)
+	for idx, val := range up.PoolingActivation() {
+		assert.InDelta(t, first[idx]*0.904837418, val, 1e-6)
+	}
+
+	//predicted active cells add extra activation
+	up.Reset()
+	up.Compute([]int{1, 2, 3, 4, 5, 6, 7, 8, 9}, nil, false)
+	active := up.PoolingActivation()
+	up.Reset()
+	assert.Nil(t, up.UnionSDR())
+	up.Compute([]int{1, 2, 3, 4, 5, 6, 7, 8, 9}, []int{1, 2, 3, 4, 5, 6, 7, 8, 9}, false)
+	predicted := up.PoolingActivation()
+	for idx := range active {
+		assert.InDelta(t, active[idx]*3, predicted[idx], 1e-9)
+	}
+}
+
+func TestUnionPoolerStableOverSequence(t *testing.T) {
+	level := hierarchyLevel(80)
+	region := NewRegion(level.SP, level.TM, nil)
+	region.EnableUnionPooler(unionPoolerParams(region.NumCells()))
+
+	sequences := [][]int{{0, 1, 2, 3}, {4, 5, 6, 7}}
+	for rep := 0; rep < 40; rep++ {
+		for _, seq := range sequences {
+			region.Reset()
+			for rep2 := 0; rep2 < 3; rep2++ {
+				for _, symbol := range seq {
+					region.Compute(unionInput(symbol), true)
+				}
+			}
+		}
+	}
+
+	unions := make([][][]int, len(sequences))
+	for idx, seq := range sequences {
+		region.Reset()
+		for rep2 := 0; rep2 < 3; rep2++ {
+			for _, symbol := range seq {
+				region.Compute(unionInput(symbol), false)
+				unions[idx] = append(unions[idx], region.OutputCells(UnionOutput))
+			}
+		}
+	}
+
+	//once the sequence is recognized the union barely changes
+	for _, seqUnions := range unions {
+		for step := 5; step < len(seqUnions); step++ {
+			assert.True(t, sdrOverlap(seqUnions[step-1], seqUnions[step]) >= 0.8)
+		}
+	}
+
+	//different sequences have different unions
+	last := len(unions[0]) - 1
+	assert.True(t, sdrOverlap(unions[0][last], unions[1][last]) < 0.5)
+}
+
+func TestUnionPoolerHierarchy(t *testing.T) {
+	bottom := hierarchyLevel(80)
+	union := unionPoolerParams(0)
+	bottom.Union = &union
+	bottom.Output = UnionOutput
+	h := NewHierarchy([]HierarchyLevelParams{bottom, hierarchyLevel(0)})
+
+	assert.Equal(t, 256, h.Levels[1].SP.numInputs)
+	for i := 0; i < 8; i++ {
+		h.Compute(unionInput(i%4), true)
+	}
+	assert.True(t, len(h.Levels[0].OutputCells(UnionOutput)) > 0)
+	assert.Equal(t, 8, len(h.Levels[1].ActiveColumns()))
+
+	bottom.Union = nil
+	assert.Panics(t, func() { NewHierarchy([]HierarchyLevelParams{bottom, hierarchyLevel(0)}) })
+}
+
+func TestUnionPoolerInvalidParams(t *testing.T) {
+	params := unionPoolerParams(10)
+	params.MaxUnionActivity = 0
+	assert.Panics(t, func() { NewUnionPooler(params) })
+	params = unionPoolerParams(10)
+	params.DecayTimeConst = -1
+	assert.Panics(t, func() { NewUnionPooler(params) })
+}
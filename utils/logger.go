@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+type LogLevel int
+
+const (
+	//Detailed algorithm tracing, gated further by the components verbosity
+	Debug LogLevel = 0
+	Info  LogLevel = 1
+	//Recoverable problems such as clipped input or questionable parameters
+	Warn  LogLevel = 2
+	Error LogLevel = 3
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case Debug:
+		return "debug"
+	case Info:
+		return "info"
+	case Warn:
+		return "warn"
+	case Error:
+		return "error"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+//Key value pair attached to a log message
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+//Returns a log field
+func Field(key string, value interface{}) LogField {
+	return LogField{key, value}
+}
+
+/*
+ Destination of all diagnostic output. Components call Enabled before
+building expensive messages, so an implementation returning false for a
+level will never be asked to log it.
+*/
+type Logger interface {
+	Enabled(level LogLevel) bool
+	Log(level LogLevel, msg string, fields ...LogField)
+}
+
+//Logger that discards everything, the default for all components
+type NopLogger struct{}
+
+func (NopLogger) Enabled(level LogLevel) bool {
+	return false
+}
+
+func (NopLogger) Log(level LogLevel, msg string, fields ...LogField) {
+}
+
+//Returns l, or a NopLogger if l is nil
+func LoggerOrNop(l Logger) Logger {
+	if l == nil {
+		return NopLogger{}
+	}
+	return l
+}
+
+/*
+ Logger writing one line per message in key=value form, e.g.
+	level=debug msg="New segment" component=tp iteration=12 column=3
+Messages below MinLevel are dropped. Safe for concurrent use.
+*/
+type WriterLogger struct {
+	MinLevel LogLevel
+	w        io.Writer
+	mu       sync.Mutex
+}
+
+func NewWriterLogger(w io.Writer, minLevel LogLevel) *WriterLogger {
+	return &WriterLogger{MinLevel: minLevel, w: w}
+}
+
+func (wl *WriterLogger) Enabled(level LogLevel) bool {
+	return level >= wl.MinLevel
+}
+
+func (wl *WriterLogger) Log(level LogLevel, msg string, fields ...LogField) {
+	if !wl.Enabled(level) {
+		return
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("level=%v msg=%q", level, msg))
+	for _, f := range fields {
+		buffer.WriteString(fmt.Sprintf(" %v=%v", f.Key, f.Value))
+	}
+	buffer.WriteByte('\n')
+
+	wl.mu.Lock()
+	wl.w.Write(buffer.Bytes())
+	wl.mu.Unlock()
+}
+
+//Logger adding fixed fields to every message of the wrapped logger
+type fieldLogger struct {
+	logger Logger
+	fields []LogField
+}
+
+//Returns a logger that appends fields to every message logged through it
+func WithFields(l Logger, fields ...LogField) Logger {
+	l = LoggerOrNop(l)
+	if len(fields) == 0 {
+		return l
+	}
+	return &fieldLogger{l, fields}
+}
+
+func (fl *fieldLogger) Enabled(level LogLevel) bool {
+	return fl.logger.Enabled(level)
+}
+
+func (fl *fieldLogger) Log(level LogLevel, msg string, fields ...LogField) {
+	all := make([]LogField, 0, len(fl.fields)+len(fields))
+	all = append(all, fl.fields...)
+	all = append(all, fields...)
+	fl.logger.Log(level, msg, all...)
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWriterLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, Info)
+
+	assert.False(t, l.Enabled(Debug))
+	assert.True(t, l.Enabled(Warn))
+
+	l.Log(Debug, "dropped")
+	assert.Equal(t, 0, buf.Len())
+
+	l.Log(Warn, "clipped input", Field("input", 5), Field("name", "x"))
+	assert.Equal(t, "level=warn msg=\"clipped input\" input=5 name=x\n", buf.String())
+}
+
+func TestWithFields(t *testing.T) {
+	var buf bytes.Buffer
+	l := WithFields(NewWriterLogger(&buf, Debug), Field("component", "tp"))
+	l.Log(Debug, "reset", Field("iteration", 3))
+	assert.Equal(t, "level=debug msg=\"reset\" component=tp iteration=3\n", buf.String())
+
+	//nil loggers are silent
+	l = WithFields(nil, Field("component", "tp"))
+	assert.False(t, l.Enabled(Error))
+	l.Log(Error, "discarded")
+	assert.Equal(t, NopLogger{}, LoggerOrNop(nil))
+}
@@ -6,6 +6,7 @@ import (
 	"math"
 	"math/big"
 	"math/rand"
+	"sort"
 	"time"
 )
 
@@ -240,6 +241,69 @@ func MaxSliceFloat64(values []float64) float64 {
 	return max
 }
 
+/*
+Returns the indices of the k largest values, in ascending index order. Uses
+an in place quickselect over an index slice, so the cost is linear on average
+rather than the n log n of a full sort. Values are compared at full precision,
+ties are broken in favour of the higher index.
+*/
+func TopKFloat64(values []float64, k int) []int {
+	if k > len(values) {
+		k = len(values)
+	}
+	if k <= 0 {
+		return []int{}
+	}
+
+	idx := make([]int, len(values))
+	FillSliceWithIdxInt(idx)
+
+	//orders larger values (and higher indices among equals) first
+	before := func(a, b int) bool {
+		if values[a] != values[b] {
+			return values[a] > values[b]
+		}
+		return a > b
+	}
+
+	lo, hi := 0, len(idx)-1
+	for lo < hi {
+		//median of three pivot, moved to the end of the range
+		mid := lo + (hi-lo)/2
+		if before(idx[mid], idx[lo]) {
+			idx[mid], idx[lo] = idx[lo], idx[mid]
+		}
+		if before(idx[hi], idx[lo]) {
+			idx[hi], idx[lo] = idx[lo], idx[hi]
+		}
+		if before(idx[mid], idx[hi]) {
+			idx[mid], idx[hi] = idx[hi], idx[mid]
+		}
+		pivot := idx[hi]
+
+		store := lo
+		for i := lo; i < hi; i++ {
+			if before(idx[i], pivot) {
+				idx[i], idx[store] = idx[store], idx[i]
+				store++
+			}
+		}
+		idx[store], idx[hi] = idx[hi], idx[store]
+
+		if store == k-1 {
+			break
+		} else if store < k-1 {
+			lo = store + 1
+		} else {
+			hi = store - 1
+		}
+	}
+
+	result := idx[:k]
+	sort.Ints(result)
+	return result
+}
+
 //Returns product of set of integers
 func ProdInt(vals []int) int {
 	sum := 1
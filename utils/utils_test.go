@@ -48,3 +48,16 @@ func TestProdInt(t *testing.T) {
 	assert.Equal(t, expected, actual)
 
 }
+
+func TestTopKFloat64(t *testing.T) {
+	vals := []float64{0.5, 3, 1.25, 3, 2, 1.5}
+
+	assert.Equal(t, []int{1, 3}, TopKFloat64(vals, 2))
+	assert.Equal(t, []int{1, 3, 4}, TopKFloat64(vals, 3))
+	assert.Equal(t, []int{3}, TopKFloat64(vals, 1))
+	assert.Equal(t, []int{}, TopKFloat64(vals, 0))
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5}, TopKFloat64(vals, 10))
+
+	//input is left untouched
+	assert.Equal(t, []float64{0.5, 3, 1.25, 3, 2, 1.5}, vals)
+}